@@ -0,0 +1,165 @@
+package align
+
+import (
+	"sort"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+// Row maps a page in the pivot language to its titles in other languages.
+type Row struct {
+	// PageID is the ID of the page in the pivot language.
+	PageID int32
+
+	// Titles maps languages to titles. The pivot language is included.
+	// Languages without a matching page are omitted.
+	Titles map[string]string
+}
+
+type key struct {
+	lang  string
+	title string
+}
+
+type edge struct {
+	from key
+	to   key
+}
+
+// Aligner aligns pages across language editions using their
+// interlanguage links.
+//
+// Pages are grouped so that two pages end up in the same group if there
+// is a chain of interlanguage links between them, in any direction.
+// Links to redirects are resolved to the redirect target.
+type Aligner struct {
+	iw        map[string]*wikitext.Interwiki
+	ids       map[key]int32
+	redirects map[key]string
+	edges     []edge
+}
+
+// NewAligner returns an empty aligner.
+func NewAligner() *Aligner {
+	return &Aligner{
+		iw:        make(map[string]*wikitext.Interwiki),
+		ids:       make(map[key]int32),
+		redirects: make(map[key]string),
+	}
+}
+
+// SetInterwiki sets the interwiki configuration used to parse links from
+// the pages of a language. By default, wikitext.DefaultInterwiki is used.
+func (a *Aligner) SetInterwiki(lang string, iw *wikitext.Interwiki) {
+	a.iw[lang] = iw
+}
+
+// Add adds a page from the language edition lang.
+func (a *Aligner) Add(lang string, p *wikipedia.Page) {
+	k := key{lang, p.Title}
+	if p.RedirectTitle != "" {
+		a.redirects[k] = p.RedirectTitle
+		return
+	}
+	a.ids[k] = p.Id
+	if len(p.Revisions) == 0 {
+		return
+	}
+
+	iw, ok := a.iw[lang]
+	if !ok {
+		iw = wikitext.DefaultInterwiki()
+		a.iw[lang] = iw
+	}
+	text := p.Revisions[len(p.Revisions)-1].Text
+	for _, link := range wikitext.ParseLanguageLinks(text, iw) {
+		a.edges = append(a.edges, edge{k, key{link.Language, link.Title}})
+	}
+}
+
+// Rows returns one row per page in the pivot language, ordered by page ID.
+//
+// If a group of aligned pages contains multiple pages in the same
+// language, the page linked directly from the pivot page is used, or
+// otherwise the page with the lowest ID.
+func (a *Aligner) Rows(pivot string) []Row {
+	parent := make(map[key]key)
+	var find func(k key) key
+	find = func(k key) key {
+		p, ok := parent[k]
+		if !ok || p == k {
+			return k
+		}
+		root := find(p)
+		parent[k] = root
+		return root
+	}
+
+	direct := make(map[key]map[string]string)
+	for _, e := range a.edges {
+		to := a.resolve(e.to)
+		if _, exists := a.ids[to]; !exists {
+			continue
+		}
+		parent[find(e.from)] = find(to)
+		if e.from.lang == pivot {
+			if direct[e.from] == nil {
+				direct[e.from] = make(map[string]string)
+			}
+			direct[e.from][to.lang] = to.title
+		}
+	}
+
+	// Pick one page per language and group
+	best := make(map[key]map[string]key)
+	for k, id := range a.ids {
+		root := find(k)
+		if best[root] == nil {
+			best[root] = make(map[string]key)
+		}
+		cur, exists := best[root][k.lang]
+		if !exists || id < a.ids[cur] {
+			best[root][k.lang] = k
+		}
+	}
+
+	var res []Row
+	for k, id := range a.ids {
+		if k.lang != pivot {
+			continue
+		}
+		row := Row{
+			PageID: id,
+			Titles: map[string]string{pivot: k.title},
+		}
+		for lang, other := range best[find(k)] {
+			if lang != pivot {
+				row.Titles[lang] = other.title
+			}
+		}
+		for lang, title := range direct[k] {
+			if lang != pivot {
+				row.Titles[lang] = title
+			}
+		}
+		res = append(res, row)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].PageID < res[j].PageID
+	})
+	return res
+}
+
+// resolve follows redirects from k. Redirect loops are cut after a fixed
+// number of steps.
+func (a *Aligner) resolve(k key) key {
+	for i := 0; i < 5; i++ {
+		target, ok := a.redirects[k]
+		if !ok {
+			return k
+		}
+		k = key{k.lang, target}
+	}
+	return k
+}
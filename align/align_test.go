@@ -0,0 +1,41 @@
+package align_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/align"
+)
+
+func page(id int32, title string, text string) *wikipedia.Page {
+	return &wikipedia.Page{
+		Id:        id,
+		Title:     title,
+		Revisions: []*wikipedia.Revision{{Text: text}},
+	}
+}
+
+func Test_Aligner_Rows(t *testing.T) {
+	a := align.NewAligner()
+	a.Add("en", page(1, "Stockholm", "[[de:Stockholm]] [[sv:Stockholm]]"))
+	a.Add("en", page(2, "Gothenburg", ""))
+	a.Add("en", page(3, "Sweden", "[[de:Schweden]] [[Category:Sweden]]"))
+	a.Add("de", page(10, "Stockholm", "[[en:Stockholm]]"))
+	a.Add("de", page(11, "Göteborg", "[[sv:Göteborg]]"))
+	a.Add("de", page(12, "Schweden", "<!-- [[sv:Norge]] -->"))
+	a.Add("sv", page(20, "Stockholm", ""))
+	a.Add("sv", page(21, "Göteborg", "[[en:Göteborg]]"))
+	a.Add("sv", page(22, "Sverige", "[[de:Schweden]]"))
+	a.Add("en", &wikipedia.Page{Id: 4, Title: "Göteborg", RedirectTitle: "Gothenburg"})
+
+	got := a.Rows("en")
+	want := []align.Row{
+		{PageID: 1, Titles: map[string]string{"en": "Stockholm", "de": "Stockholm", "sv": "Stockholm"}},
+		{PageID: 2, Titles: map[string]string{"en": "Gothenburg", "de": "Göteborg", "sv": "Göteborg"}},
+		{PageID: 3, Titles: map[string]string{"en": "Sweden", "de": "Schweden", "sv": "Sverige"}},
+	}
+	if !cmp.Equal(want, got) {
+		t.Errorf("invalid rows\n%v", cmp.Diff(want, got))
+	}
+}
@@ -0,0 +1,222 @@
+package bdg
+
+import (
+	"io"
+	"math"
+
+	"github.com/dgraph-io/badger"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/byteconv"
+)
+
+// Pages are keyed by their big-endian encoded ID. IDs are positive, so the
+// first byte of a page key is always below 0x80. Keys of the backlink
+// index start with a byte above it, which lets the index be stored in the
+// same database as the pages.
+const (
+	// backlinkPrefix is followed by the target title, a zero byte and the
+	// ID of the linking page. The value is the title of the linking page.
+	backlinkPrefix byte = 0xb0
+
+	// countPrefix is followed by the target title. The value is the number
+	// of pages linking to the target.
+	countPrefix byte = 0xb1
+)
+
+// Backlink is a page linking to another page.
+type Backlink struct {
+	PageID int32
+	Title  string
+}
+
+// WriteBacklinks reads all pages from r and writes an index of their
+// incoming links to the badger database at the provided path, keyed by
+// the title of the linked page. An existing backlink index in the
+// database is replaced, while pages stored in it are kept.
+func WriteBacklinks(path string, r wikipedia.LinkedPageReader) error {
+	db, err := badger.Open(badger.DefaultOptions(path))
+	if err != nil {
+		return err
+	}
+	if err := writeBacklinks(db, r); err != nil {
+		db.Close()
+		return err
+	}
+	return db.Close()
+}
+
+func writeBacklinks(db *badger.DB, r wikipedia.LinkedPageReader) error {
+	for _, prefix := range []byte{backlinkPrefix, countPrefix} {
+		if err := db.DropPrefix([]byte{prefix}); err != nil {
+			return err
+		}
+	}
+
+	wb := db.NewWriteBatch()
+	defer wb.Cancel()
+
+	counts := make(map[string]int32)
+	for {
+		p, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		seen := make(map[string]bool, len(p.Links))
+		for _, link := range p.Links {
+			if seen[link.TargetTitle] {
+				continue
+			}
+			seen[link.TargetTitle] = true
+			counts[link.TargetTitle]++
+			key := backlinkKey(link.TargetTitle, p.PageId)
+			if err := wb.Set(key, []byte(p.PageTitle)); err != nil {
+				return err
+			}
+		}
+	}
+	for title, n := range counts {
+		if err := wb.Set(countKey(title), byteconv.Int32ToBytes(n)); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
+// BacklinkIndex looks up the incoming links of pages in a backlink index
+// written by WriteBacklinks.
+type BacklinkIndex struct {
+	db *badger.DB
+}
+
+// OpenBacklinkIndex opens the backlink index of the badger database at the
+// provided path.
+func OpenBacklinkIndex(path string) (*BacklinkIndex, error) {
+	db, err := badger.Open(badger.DefaultOptions(path))
+	if err != nil {
+		return nil, err
+	}
+	return &BacklinkIndex{db: db}, nil
+}
+
+// Backlinks returns up to limit pages linking to the page with the
+// provided title, ordered by page ID. Only pages with an ID greater than
+// after are returned, so the next page of results is retrieved by passing
+// the ID of the last returned page. Use zero to start from the beginning.
+func (idx *BacklinkIndex) Backlinks(title string, after int32, limit int) ([]Backlink, error) {
+	if after == math.MaxInt32 || limit <= 0 {
+		return nil, nil
+	}
+	prefix := backlinkKey(title, 0)
+	prefix = prefix[:len(prefix)-4]
+
+	var res []Backlink
+	err := idx.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Seek(backlinkKey(title, after+1)); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			key := item.Key()
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			res = append(res, Backlink{
+				PageID: byteconv.BytesToInt32(key[len(key)-4:]),
+				Title:  string(value),
+			})
+			if len(res) == limit {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Count returns the number of pages linking to the page with the provided
+// title.
+func (idx *BacklinkIndex) Count(title string) (int32, error) {
+	var n int32
+	err := idx.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(countKey(title))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return nil
+			}
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			n = byteconv.BytesToInt32(val)
+			return nil
+		})
+	})
+	return n, err
+}
+
+// LinkCounts returns the number of incoming links of all linked pages.
+func (idx *BacklinkIndex) LinkCounts() (wikipedia.LinkCounts, error) {
+	counts := make(wikipedia.LinkCounts)
+	err := idx.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		prefix := []byte{countPrefix}
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			title := string(item.Key()[1:])
+			if err := item.Value(func(val []byte) error {
+				counts[title] = byteconv.BytesToInt32(val)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// AllBacklinks returns the IDs of the pages linking to all linked pages,
+// e.g. for wikipedia.MilneWitten.
+func (idx *BacklinkIndex) AllBacklinks() (wikipedia.Backlinks, error) {
+	backlinks := make(wikipedia.Backlinks)
+	err := idx.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{})
+		defer it.Close()
+		prefix := []byte{backlinkPrefix}
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			key := it.Item().Key()
+			title := string(key[1 : len(key)-5])
+			backlinks[title] = append(backlinks[title], byteconv.BytesToInt32(key[len(key)-4:]))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return backlinks, nil
+}
+
+func (idx *BacklinkIndex) Close() error {
+	return idx.db.Close()
+}
+
+func backlinkKey(title string, id int32) []byte {
+	key := make([]byte, 0, len(title)+6)
+	key = append(key, backlinkPrefix)
+	key = append(key, title...)
+	key = append(key, 0)
+	return append(key, byteconv.Int32ToBytes(id)...)
+}
+
+func countKey(title string) []byte {
+	return append([]byte{countPrefix}, title...)
+}
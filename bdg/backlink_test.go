@@ -0,0 +1,148 @@
+package bdg_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/bdg"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+func Test_BacklinkIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backlinks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &linktest.Reader{
+		linktest.Page(3, "c", "a", "a", "ab"),
+		linktest.Page(1, "a", "ab"),
+		linktest.Page(2, "b", "a"),
+		linktest.Page(4, "d", "a"),
+	}
+	if err := bdg.WriteBacklinks(dir, r); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := bdg.OpenBacklinkIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	for _, tc := range []struct {
+		title string
+		after int32
+		limit int
+		want  []bdg.Backlink
+	}{
+		{"a", 0, 10, []bdg.Backlink{{2, "b"}, {3, "c"}, {4, "d"}}},
+		{"a", 0, 2, []bdg.Backlink{{2, "b"}, {3, "c"}}},
+		{"a", 3, 2, []bdg.Backlink{{4, "d"}}},
+		{"a", 4, 2, nil},
+		{"ab", 0, 10, []bdg.Backlink{{1, "a"}, {3, "c"}}},
+		{"missing", 0, 10, nil},
+	} {
+		got, err := idx.Backlinks(tc.title, tc.after, tc.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cmp.Equal(tc.want, got) {
+			t.Errorf("invalid backlinks of %q after %v with limit %v\n%v", tc.title, tc.after, tc.limit, cmp.Diff(tc.want, got))
+		}
+	}
+
+	n, err := idx.Count("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("invalid count, expected: 3, got: %v", n)
+	}
+	counts, err := idx.LinkCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(wikipedia.LinkCounts{"a": 3, "ab": 2}, counts) {
+		t.Errorf("invalid link counts\n%v", cmp.Diff(wikipedia.LinkCounts{"a": 3, "ab": 2}, counts))
+	}
+	backlinks, err := idx.AllBacklinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := wikipedia.Backlinks{"a": {2, 3, 4}, "ab": {1, 3}}
+	if !cmp.Equal(want, backlinks) {
+		t.Errorf("invalid backlinks\n%v", cmp.Diff(want, backlinks))
+	}
+}
+
+func Test_PagesAndBacklinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backlinks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writePages := func(pages ...*wikipedia.Page) {
+		w, err := bdg.NewPageWriter(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range pages {
+			if err := w.Write(p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	readTitles := func(ids ...int32) []string {
+		store, err := bdg.OpenPageStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		var res []string
+		for _, id := range ids {
+			p, err := store.Get(id)
+			if err != nil {
+				res = append(res, "")
+				continue
+			}
+			res = append(res, p.Title)
+		}
+		return res
+	}
+
+	// Writing backlinks keeps the pages
+	writePages(&wikipedia.Page{Id: 9, Title: "old"})
+	if err := bdg.WriteBacklinks(dir, &linktest.Reader{linktest.Page(1, "a", "b"), linktest.Page(2, "b", "a")}); err != nil {
+		t.Fatal(err)
+	}
+	if want, got := []string{"old"}, readTitles(9); !cmp.Equal(want, got) {
+		t.Errorf("invalid pages after writing backlinks\n%v", cmp.Diff(want, got))
+	}
+
+	// Writing pages replaces the pages, and keeps the backlinks
+	writePages(&wikipedia.Page{Id: 1, Title: "a"}, &wikipedia.Page{Id: 2, Title: "b"})
+	if want, got := []string{"a", "b", ""}, readTitles(1, 2, 9); !cmp.Equal(want, got) {
+		t.Errorf("invalid pages after writing pages\n%v", cmp.Diff(want, got))
+	}
+	idx, err := bdg.OpenBacklinkIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+	counts, err := idx.LinkCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(wikipedia.LinkCounts{"a": 1, "b": 1}, counts) {
+		t.Errorf("invalid link counts\n%v", cmp.Diff(wikipedia.LinkCounts{"a": 1, "b": 1}, counts))
+	}
+}
@@ -1,72 +1,169 @@
 package bdg
 
 import (
-	"context"
-	"sync"
-
 	"github.com/dgraph-io/badger"
 	"github.com/sebnyberg/wikipedia"
 	"github.com/sebnyberg/wikipedia/byteconv"
-	"golang.org/x/sync/errgroup"
 	"google.golang.org/protobuf/proto"
 )
 
-type pageEntry struct {
-	key   []byte
-	value []byte
-}
+// pageTitlePrefix is followed by the title of a page. The value is the ID
+// of the page.
+const pageTitlePrefix byte = 0xa0
 
 type pageWriter struct {
-	db    *badger.DB
-	pageC chan []wikipedia.Page
-	g     *errgroup.Group
-	ctx   context.Context
-	i     int
-	mtx   sync.RWMutex
+	db *badger.DB
+	wb *badger.WriteBatch
 }
 
-func NewPageWriter(outpath string) (wikipedia.PageBlockWriter, error) {
-	// Open the Badger database located in the /tmp/badger directory.
-	// It will be created if it doesn't exist.
+// NewPageWriter returns a writer that stores pages in the badger database
+// at the provided path, keyed by their ID, along with an index of their
+// titles. Pages already stored in the
+// database are deleted, while indexes stored next to them, such as the
+// backlink index, are kept.
+func NewPageWriter(outpath string) (wikipedia.PageWriter, error) {
+	// The database is created if it doesn't exist
 	db, err := badger.Open(badger.DefaultOptions(outpath))
 	if err != nil {
 		return nil, err
 	}
-	db.DropAll()
-	g, ctx := errgroup.WithContext(context.Background())
-
-	w := &pageWriter{
-		db:    db,
-		pageC: make(chan []wikipedia.Page, 1000),
-		g:     g,
-		ctx:   ctx,
+	if err := dropPages(db); err != nil {
+		db.Close()
+		return nil, err
 	}
+	return &pageWriter{
+		db: db,
+		wb: db.NewWriteBatch(),
+	}, nil
+}
 
-	return w, nil
+// dropPages deletes all pages of a database and their titles. Page keys
+// are big-endian encoded positive IDs, so their first byte is below 0x80.
+func dropPages(db *badger.DB) error {
+	for b := 0; b < 0x80; b++ {
+		if err := db.DropPrefix([]byte{byte(b)}); err != nil {
+			return err
+		}
+	}
+	return db.DropPrefix([]byte{pageTitlePrefix})
 }
 
 func (w *pageWriter) Close() error {
-	close(w.pageC)
-	if err := w.g.Wait(); err != nil {
+	if err := w.wb.Flush(); err != nil {
+		w.db.Close()
 		return err
 	}
 	return w.db.Close()
 }
 
-func (w *pageWriter) Write(pageblock []wikipedia.Page) error {
-	w.g.Go(func() error {
-		return w.db.Update(func(txn *badger.Txn) error {
-			for _, p := range pageblock {
-				b, err := proto.Marshal(&p)
-				if err != nil {
-					return err
-				}
-				if err := txn.Set(byteconv.Int32ToBytes(p.Id), b); err != nil {
+func (w *pageWriter) Write(p *wikipedia.Page) error {
+	b, err := proto.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if err := w.wb.Set(byteconv.Int32ToBytes(p.Id), b); err != nil {
+		return err
+	}
+	return w.wb.Set(pageTitleKey(p.Title), byteconv.Int32ToBytes(p.Id))
+}
+
+// PageStore reads and writes pages in a badger database written by a
+// page writer.
+type PageStore struct {
+	db *badger.DB
+}
+
+// OpenPageStore opens the badger database at the provided path.
+func OpenPageStore(path string) (*PageStore, error) {
+	db, err := badger.Open(badger.DefaultOptions(path))
+	if err != nil {
+		return nil, err
+	}
+	return &PageStore{db: db}, nil
+}
+
+// Get returns the page with the provided ID. If there is no such page,
+// badger.ErrKeyNotFound is returned.
+func (s *PageStore) Get(id int32) (wikipedia.Page, error) {
+	var p wikipedia.Page
+	err := s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(byteconv.Int32ToBytes(id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return proto.Unmarshal(val, &p)
+		})
+	})
+	if err != nil {
+		return wikipedia.Page{}, err
+	}
+	return wikipedia.Page{
+		Id:            p.Id,
+		Title:         p.Title,
+		Namespace:     p.Namespace,
+		RedirectTitle: p.RedirectTitle,
+		Revisions:     p.Revisions,
+	}, nil
+}
+
+// PageID returns the ID of the page with the provided title. If there is
+// no such page, false is returned.
+func (s *PageStore) PageID(title string) (id int32, ok bool, err error) {
+	err = s.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(pageTitleKey(title))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			id, ok = byteconv.BytesToInt32(val), true
+			return nil
+		})
+	})
+	return id, ok, err
+}
+
+// Set stores a page with the provided ID. If the page replaces a page with
+// another title, the old title is removed from the index of titles.
+func (s *PageStore) Set(id int32, p *wikipedia.Page) error {
+	b, err := proto.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(txn *badger.Txn) error {
+		key := byteconv.Int32ToBytes(id)
+		item, err := txn.Get(key)
+		switch err {
+		case nil:
+			var old wikipedia.Page
+			if err := item.Value(func(val []byte) error {
+				return proto.Unmarshal(val, &old)
+			}); err != nil {
+				return err
+			}
+			if old.Title != p.Title {
+				if err := txn.Delete(pageTitleKey(old.Title)); err != nil {
 					return err
 				}
 			}
-			return nil
-		})
+		case badger.ErrKeyNotFound:
+		default:
+			return err
+		}
+		if err := txn.Set(key, b); err != nil {
+			return err
+		}
+		return txn.Set(pageTitleKey(p.Title), key)
 	})
-	return nil
+}
+
+func (s *PageStore) Close() error {
+	return s.db.Close()
+}
+
+func pageTitleKey(title string) []byte {
+	return append([]byte{pageTitlePrefix}, title...)
 }
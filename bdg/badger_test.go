@@ -0,0 +1,57 @@
+package bdg_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/bdg"
+)
+
+func Test_PageStoreTitles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := bdg.NewPageWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*wikipedia.Page{{Id: 1, Title: "a"}, {Id: 2, Title: "b"}} {
+		if err := w.Write(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := bdg.OpenPageStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.Set(1, &wikipedia.Page{Id: 1, Title: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var titles wikipedia.TitleLookup = store
+	got := make(map[string]int32)
+	for _, title := range []string{"a", "b", "c", "d"} {
+		id, ok, err := titles.PageID(title)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			got[title] = id
+		}
+	}
+	want := map[string]int32{"b": 2, "c": 1}
+	if !cmp.Equal(want, got) {
+		t.Errorf("invalid page IDs\n%v", cmp.Diff(want, got))
+	}
+}
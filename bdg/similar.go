@@ -0,0 +1,263 @@
+package bdg
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+
+	"github.com/dgraph-io/badger"
+	"github.com/sebnyberg/wikipedia/byteconv"
+	"github.com/sebnyberg/wikipedia/minhash"
+)
+
+// Keys of the similarity index, which is stored next to pages and the
+// backlink index.
+const (
+	// similarMetaKey holds the number of bands and rows of the index.
+	similarMetaKey byte = 0xc0
+
+	// similarPagePrefix is followed by a page ID. The value is the
+	// signature of the page followed by its title.
+	similarPagePrefix byte = 0xc1
+
+	// similarTitlePrefix is followed by a title. The value is the page ID.
+	similarTitlePrefix byte = 0xc2
+
+	// similarBandPrefix is followed by a band, the hash of the band of a
+	// signature and the page ID. The value is empty.
+	similarBandPrefix byte = 0xc3
+)
+
+// SimilarPage is a page found in a similarity index.
+type SimilarPage struct {
+	PageID int32
+	Title  string
+
+	// Similarity is the estimated Jaccard index of the features of the
+	// page and the queried page.
+	Similarity float64
+}
+
+// SimilarityWriter writes the MinHash signatures of pages to a similarity
+// index, like minhash.Index but stored in a badger database so that
+// queries do not need to load the signatures of all pages.
+type SimilarityWriter struct {
+	db          *badger.DB
+	wb          *badger.WriteBatch
+	bands, rows int
+}
+
+// NewSimilarityWriter returns a writer of a similarity index for
+// signatures of bands*rows values, in the badger database at the provided
+// path. An existing similarity index in the database is replaced, while
+// pages and other indexes stored in it are kept.
+func NewSimilarityWriter(path string, bands, rows int) (*SimilarityWriter, error) {
+	if bands < 1 || rows < 1 || bands > 1<<16 {
+		return nil, errors.New("invalid number of bands and rows")
+	}
+	db, err := badger.Open(badger.DefaultOptions(path))
+	if err != nil {
+		return nil, err
+	}
+	for _, prefix := range []byte{similarMetaKey, similarPagePrefix, similarTitlePrefix, similarBandPrefix} {
+		if err := db.DropPrefix([]byte{prefix}); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	w := &SimilarityWriter{db: db, wb: db.NewWriteBatch(), bands: bands, rows: rows}
+	meta := make([]byte, 8)
+	binary.BigEndian.PutUint32(meta, uint32(bands))
+	binary.BigEndian.PutUint32(meta[4:], uint32(rows))
+	if err := w.wb.Set([]byte{similarMetaKey}, meta); err != nil {
+		w.wb.Cancel()
+		db.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write adds the signature of a page to the index. Pages without a
+// signature, i.e. without features, are ignored. Each page should be
+// written once.
+func (w *SimilarityWriter) Write(id int32, title string, sig minhash.Signature) error {
+	if sig == nil {
+		return nil
+	}
+	if len(sig) != w.bands*w.rows {
+		return errors.New("signature length does not match the bands and rows of the index")
+	}
+	value := make([]byte, 4*len(sig), 4*len(sig)+len(title))
+	for i, v := range sig {
+		binary.BigEndian.PutUint32(value[4*i:], v)
+	}
+	value = append(value, title...)
+	if err := w.wb.Set(similarPageKey(id), value); err != nil {
+		return err
+	}
+	if err := w.wb.Set(similarTitleKey(title), byteconv.Int32ToBytes(id)); err != nil {
+		return err
+	}
+	for band := 0; band < w.bands; band++ {
+		if err := w.wb.Set(similarBandKey(band, sig.BandHash(band, w.rows), id), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes the index and closes the database.
+func (w *SimilarityWriter) Close() error {
+	if err := w.wb.Flush(); err != nil {
+		w.db.Close()
+		return err
+	}
+	return w.db.Close()
+}
+
+// SimilarityIndex finds similar pages in a similarity index written by a
+// SimilarityWriter.
+type SimilarityIndex struct {
+	db          *badger.DB
+	bands, rows int
+}
+
+// OpenSimilarityIndex opens the similarity index of the badger database at
+// the provided path.
+func OpenSimilarityIndex(path string) (*SimilarityIndex, error) {
+	db, err := badger.Open(badger.DefaultOptions(path))
+	if err != nil {
+		return nil, err
+	}
+	idx := &SimilarityIndex{db: db}
+	err = db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte{similarMetaKey})
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return errors.New("the database has no similarity index")
+			}
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			idx.bands = int(binary.BigEndian.Uint32(val))
+			idx.rows = int(binary.BigEndian.Uint32(val[4:]))
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Similar returns up to k pages which are similar to the page with the
+// provided title, excluding the page itself, ordered by descending
+// similarity. If the page is not in the index, ok is false.
+func (idx *SimilarityIndex) Similar(title string, k int) (res []SimilarPage, ok bool, err error) {
+	err = idx.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(similarTitleKey(title))
+		if err != nil {
+			if err == badger.ErrKeyNotFound {
+				return nil
+			}
+			return err
+		}
+		idBytes, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		id := byteconv.BytesToInt32(idBytes)
+		sig, _, err := idx.page(txn, id)
+		if err != nil {
+			return err
+		}
+		ok = true
+		if k <= 0 {
+			return nil
+		}
+
+		seen := map[int32]bool{id: true}
+		it := txn.NewIterator(badger.IteratorOptions{})
+		defer it.Close()
+		for band := 0; band < idx.bands; band++ {
+			prefix := similarBandKey(band, sig.BandHash(band, idx.rows), 0)
+			prefix = prefix[:len(prefix)-4]
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				key := it.Item().Key()
+				candidate := byteconv.BytesToInt32(key[len(key)-4:])
+				if seen[candidate] {
+					continue
+				}
+				seen[candidate] = true
+				other, title, err := idx.page(txn, candidate)
+				if err != nil {
+					return err
+				}
+				res = append(res, SimilarPage{
+					PageID:     candidate,
+					Title:      title,
+					Similarity: sig.Similarity(other),
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Similarity == res[j].Similarity {
+			return res[i].PageID < res[j].PageID
+		}
+		return res[i].Similarity > res[j].Similarity
+	})
+	if k > 0 && len(res) > k {
+		res = res[:k]
+	}
+	return res, ok, nil
+}
+
+// page returns the signature and title of a page in the index.
+func (idx *SimilarityIndex) page(txn *badger.Txn, id int32) (minhash.Signature, string, error) {
+	item, err := txn.Get(similarPageKey(id))
+	if err != nil {
+		return nil, "", err
+	}
+	var sig minhash.Signature
+	var title string
+	err = item.Value(func(val []byte) error {
+		n := idx.bands * idx.rows
+		if len(val) < 4*n {
+			return errors.New("invalid signature in similarity index")
+		}
+		sig = make(minhash.Signature, n)
+		for i := range sig {
+			sig[i] = binary.BigEndian.Uint32(val[4*i:])
+		}
+		title = string(val[4*n:])
+		return nil
+	})
+	return sig, title, err
+}
+
+func (idx *SimilarityIndex) Close() error {
+	return idx.db.Close()
+}
+
+func similarPageKey(id int32) []byte {
+	return append([]byte{similarPagePrefix}, byteconv.Int32ToBytes(id)...)
+}
+
+func similarTitleKey(title string) []byte {
+	return append([]byte{similarTitlePrefix}, title...)
+}
+
+func similarBandKey(band int, hash uint64, id int32) []byte {
+	key := make([]byte, 15)
+	key[0] = similarBandPrefix
+	binary.BigEndian.PutUint16(key[1:], uint16(band))
+	binary.BigEndian.PutUint64(key[3:], hash)
+	copy(key[11:], byteconv.Int32ToBytes(id))
+	return key
+}
@@ -0,0 +1,85 @@
+package bdg_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/bdg"
+	"github.com/sebnyberg/wikipedia/minhash"
+)
+
+func Test_SimilarityIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "similar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Pages share features with the pages next to them
+	const bands, rows = 16, 2
+	h := minhash.NewHasher(bands*rows, 1)
+	mem, err := minhash.NewIndex(bands, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := bdg.NewSimilarityWriter(dir, bands, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := int32(1); id <= 50; id++ {
+		var features []string
+		for i := 2 * id; i < 2*id+20; i++ {
+			features = append(features, strconv.Itoa(int(i)))
+		}
+		sig := h.Signature(features)
+		if err := mem.Add(id, sig); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(id, "p"+strconv.Itoa(int(id)), sig); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := bdg.OpenSimilarityIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	// The stored index finds the same pages as an index in memory
+	for _, id := range []int32{1, 25, 50} {
+		title := "p" + strconv.Itoa(int(id))
+		got, ok, err := idx.Similar(title, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Fatalf("page %v not found", title)
+		}
+		var want []bdg.SimilarPage
+		for _, m := range mem.Similar(id, 5) {
+			want = append(want, bdg.SimilarPage{PageID: m.ID, Title: "p" + strconv.Itoa(int(m.ID)), Similarity: m.Similarity})
+		}
+		if len(want) == 0 {
+			t.Fatalf("no similar pages for %v", title)
+		}
+		if !cmp.Equal(want, got) {
+			t.Errorf("invalid similar pages of %q\n%v", title, cmp.Diff(want, got))
+		}
+	}
+
+	if _, ok, err := idx.Similar("missing", 5); err != nil || ok {
+		t.Errorf("invalid result for a missing title, expected: false, <nil>, got: %v, %v", ok, err)
+	}
+	for _, k := range []int{0, -1} {
+		if got, ok, err := idx.Similar("p1", k); err != nil || !ok || len(got) != 0 {
+			t.Errorf("invalid result for k = %v: %v, %v, %v", k, got, ok, err)
+		}
+	}
+}
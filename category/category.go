@@ -0,0 +1,241 @@
+package category
+
+import (
+	"io"
+	"sort"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+// CategoryNamespace is the namespace of category pages.
+const CategoryNamespace = 14
+
+// Member is a page that belongs to a category.
+type Member struct {
+	PageID  int32
+	Title   string
+	SortKey string
+}
+
+// Node is a category found when traversing the graph.
+type Node struct {
+	Name string
+
+	// Depth is the number of edges between the category and the
+	// category where the traversal started.
+	Depth int
+}
+
+// Graph is a graph of categories. Edges go from a category to its
+// subcategories. Non-category pages are stored as members of categories.
+type Graph struct {
+	parents  map[string][]string
+	children map[string][]string
+	members  map[string][]Member
+}
+
+// NewGraph returns an empty category graph.
+func NewGraph() *Graph {
+	return &Graph{
+		parents:  make(map[string][]string),
+		children: make(map[string][]string),
+		members:  make(map[string][]Member),
+	}
+}
+
+// Build reads all pages from r and returns their category graph.
+func Build(r wikipedia.PageReader) (*Graph, error) {
+	g := NewGraph()
+	for {
+		p, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				return g, nil
+			}
+			return nil, err
+		}
+		g.Add(p)
+	}
+}
+
+// Add adds the category memberships of the latest revision of a page to
+// the graph. Pages in the category namespace become subcategories of the
+// categories they belong to, other pages become members. Edges which are
+// already in the graph, e.g. from a page which is added twice, are not
+// added again.
+func (g *Graph) Add(p *wikipedia.Page) {
+	if len(p.Revisions) == 0 || p.RedirectTitle != "" {
+		return
+	}
+	links := wikitext.ParseCategories(p.Revisions[len(p.Revisions)-1].Text)
+	if p.Namespace != CategoryNamespace {
+		for _, link := range links {
+			g.members[link.Name] = append(g.members[link.Name], Member{
+				PageID:  p.Id,
+				Title:   p.Title,
+				SortKey: link.SortKey,
+			})
+		}
+		return
+	}
+
+	name, ok := wikitext.CategoryName(p.Title)
+	if !ok {
+		return
+	}
+	for _, link := range links {
+		if link.Name == name || contains(g.parents[name], link.Name) {
+			continue
+		}
+		g.parents[name] = append(g.parents[name], link.Name)
+		g.children[link.Name] = append(g.children[link.Name], name)
+	}
+}
+
+// Parents returns the direct parent categories of a category.
+func (g *Graph) Parents(name string) []string {
+	return g.parents[name]
+}
+
+// Subcategories returns the direct subcategories of a category.
+func (g *Graph) Subcategories(name string) []string {
+	return g.children[name]
+}
+
+// Members returns the pages which belong directly to a category.
+func (g *Graph) Members(name string) []Member {
+	return g.members[name]
+}
+
+// Descendants returns all subcategories of a category within maxDepth
+// edges, ordered by depth and name. If maxDepth is negative, there is no
+// depth limit.
+//
+// The category graph is not guaranteed to be acyclic. Each category is
+// visited once, at the shortest distance from the starting category, so
+// cycles do not cause the traversal to loop.
+func (g *Graph) Descendants(name string, maxDepth int) []Node {
+	return traverse(g.children, name, maxDepth)
+}
+
+// Ancestors returns all parent categories of a category within maxDepth
+// edges, ordered by depth and name. If maxDepth is negative, there is no
+// depth limit. Cycles are handled in the same way as for Descendants.
+func (g *Graph) Ancestors(name string, maxDepth int) []Node {
+	return traverse(g.parents, name, maxDepth)
+}
+
+// Pages returns the pages belonging to a category or to any of its
+// descendants within maxDepth edges. Pages belonging to multiple
+// categories are returned once.
+func (g *Graph) Pages(name string, maxDepth int) []Member {
+	seen := make(map[int32]bool)
+	var res []Member
+	add := func(cat string) {
+		for _, m := range g.members[cat] {
+			if !seen[m.PageID] {
+				seen[m.PageID] = true
+				res = append(res, m)
+			}
+		}
+	}
+	add(name)
+	for _, n := range g.Descendants(name, maxDepth) {
+		add(n.Name)
+	}
+	return res
+}
+
+// Cycles returns the strongly connected components of the category
+// graph which contain more than one category, i.e. sets of categories
+// that are each other's ancestors.
+func (g *Graph) Cycles() [][]string {
+	var names []string
+	for name := range g.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Tarjan's algorithm
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var res [][]string
+	var visit func(v string)
+	visit = func(v string) {
+		index[v] = len(index)
+		lowlink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		for _, w := range g.children[v] {
+			if _, ok := index[w]; !ok {
+				visit(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] && index[w] < lowlink[v] {
+				lowlink[v] = index[w]
+			}
+		}
+		if lowlink[v] != index[v] {
+			return
+		}
+		var component []string
+		for {
+			w := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[w] = false
+			component = append(component, w)
+			if w == v {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Strings(component)
+			res = append(res, component)
+		}
+	}
+	for _, name := range names {
+		if _, ok := index[name]; !ok {
+			visit(name)
+		}
+	}
+	return res
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// traverse performs a breadth-first search from start along the provided
+// edges.
+func traverse(edges map[string][]string, start string, maxDepth int) []Node {
+	visited := map[string]bool{start: true}
+	frontier := []string{start}
+	var res []Node
+	for depth := 1; len(frontier) > 0 && (maxDepth < 0 || depth <= maxDepth); depth++ {
+		var next []string
+		for _, name := range frontier {
+			for _, adj := range edges[name] {
+				if visited[adj] {
+					continue
+				}
+				visited[adj] = true
+				next = append(next, adj)
+			}
+		}
+		sort.Strings(next)
+		for _, name := range next {
+			res = append(res, Node{Name: name, Depth: depth})
+		}
+		frontier = next
+	}
+	return res
+}
@@ -0,0 +1,104 @@
+package category_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/category"
+)
+
+func page(id int32, title string, ns uint32, text string) *wikipedia.Page {
+	return &wikipedia.Page{
+		Id:        id,
+		Title:     title,
+		Namespace: ns,
+		Revisions: []*wikipedia.Revision{{Text: text}},
+	}
+}
+
+func testGraph() *category.Graph {
+	g := category.NewGraph()
+	for _, p := range []*wikipedia.Page{
+		page(1, "Category:Europe", 14, "[[Category:Continents]]"),
+		page(2, "Category:Sweden", 14, "[[Category:Europe|Sweden]] [[Category:Countries]]"),
+		page(3, "Category:Stockholm", 14, "[[Category:Sweden]]"),
+		// Stockholm and Capitals form a cycle
+		page(4, "Category:Capitals", 14, "[[Category:Stockholm]] [[Category:Countries]]"),
+		page(5, "Category:Countries", 14, "[[Category:Capitals]]"),
+		page(6, "Stockholm", 0, "[[Category:Stockholm|*]] [[:Category:Sweden]]"),
+		page(7, "Gothenburg", 0, "[[Category:Sweden]] [[Category:Cities_in_Sweden]]"),
+	} {
+		g.Add(p)
+	}
+	return g
+}
+
+func Test_Graph_Descendants(t *testing.T) {
+	g := testGraph()
+	for _, tc := range []struct {
+		name     string
+		category string
+		maxDepth int
+		want     []category.Node
+	}{
+		{"missing category", "Asia", -1, nil},
+		{"depth one", "Europe", 1, []category.Node{{"Sweden", 1}}},
+		{"unlimited", "Europe", -1, []category.Node{
+			{"Sweden", 1}, {"Stockholm", 2}, {"Capitals", 3}, {"Countries", 4},
+		}},
+		{"cycle", "Capitals", -1, []category.Node{
+			{"Countries", 1}, {"Sweden", 2}, {"Stockholm", 3},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := g.Descendants(tc.category, tc.maxDepth)
+			if !cmp.Equal(tc.want, got, cmpopts.EquateEmpty()) {
+				t.Errorf("invalid descendants\n%v", cmp.Diff(tc.want, got, cmpopts.EquateEmpty()))
+			}
+		})
+	}
+}
+
+func Test_Graph_Ancestors(t *testing.T) {
+	g := testGraph()
+	got := g.Ancestors("Stockholm", 2)
+	want := []category.Node{{"Sweden", 1}, {"Countries", 2}, {"Europe", 2}}
+	if !cmp.Equal(want, got) {
+		t.Errorf("invalid ancestors\n%v", cmp.Diff(want, got))
+	}
+}
+
+func Test_Graph_DuplicateEdges(t *testing.T) {
+	g := category.NewGraph()
+	g.Add(page(1, "Category:Sweden", 14, "[[Category:Europe]] [[Category:Europe|Sweden]]"))
+	g.Add(page(1, "Category:Sweden", 14, "[[Category:Europe]]"))
+	if want, got := []string{"Europe"}, g.Parents("Sweden"); !cmp.Equal(want, got) {
+		t.Errorf("invalid parents\n%v", cmp.Diff(want, got))
+	}
+	if want, got := []string{"Sweden"}, g.Subcategories("Europe"); !cmp.Equal(want, got) {
+		t.Errorf("invalid subcategories\n%v", cmp.Diff(want, got))
+	}
+}
+
+func Test_Graph_Pages(t *testing.T) {
+	g := testGraph()
+	got := g.Pages("Sweden", -1)
+	want := []category.Member{
+		{PageID: 7, Title: "Gothenburg"},
+		{PageID: 6, Title: "Stockholm", SortKey: "*"},
+	}
+	if !cmp.Equal(want, got) {
+		t.Errorf("invalid pages\n%v", cmp.Diff(want, got))
+	}
+}
+
+func Test_Graph_Cycles(t *testing.T) {
+	g := testGraph()
+	got := g.Cycles()
+	want := [][]string{{"Capitals", "Countries", "Stockholm", "Sweden"}}
+	if !cmp.Equal(want, got) {
+		t.Errorf("invalid cycles\n%v", cmp.Diff(want, got))
+	}
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia/align"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/urfave/cli/v2"
+)
+
+func Align() *cli.Command {
+	return &cli.Command{
+		Name:        "align",
+		Description: "align pages across language editions using interlanguage links",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:     "pagefile",
+				Usage:    "input `LANG=FILE` to parse pages from, in proto format. Can be repeated.",
+				Aliases:  []string{"f"},
+				Required: true,
+			},
+			&cli.StringSliceFlag{
+				Name:  "interwiki",
+				Usage: "`LANG=FILE` with the siteinfo API response of a language, used to parse interlanguage links",
+			},
+			&cli.StringSliceFlag{
+				Name:  "siteinfo",
+				Usage: "`LANG=FILE` with the XML dump of a language, in bzip2 or plain format, whose namespaces are used to parse interlanguage links",
+			},
+			&cli.StringFlag{
+				Name:  "pivot",
+				Usage: "pivot `LANG` whose page IDs are used in the output. Defaults to the first pagefile.",
+			},
+			&cli.StringFlag{
+				Name:     "outpath",
+				Usage:    "output `FILE` to write the TSV mapping table to",
+				Aliases:  []string{"o"},
+				Required: true,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return alignAction(c)
+		},
+	}
+}
+
+func alignAction(c *cli.Context) error {
+	var langs, paths []string
+	for _, arg := range c.StringSlice("pagefile") {
+		lang, path, err := splitLangArg(arg)
+		if err != nil {
+			return err
+		}
+		langs = append(langs, lang)
+		paths = append(paths, path)
+	}
+	pivot := c.String("pivot")
+	if len(pivot) == 0 {
+		pivot = langs[0]
+	}
+	validPivot := false
+	for _, lang := range langs {
+		validPivot = validPivot || lang == pivot
+	}
+	if !validPivot {
+		return fmt.Errorf("invalid pivot '%v', must be one of the pagefile languages", pivot)
+	}
+
+	a := align.NewAligner()
+	interwikis := make(map[string]*wikitext.Interwiki)
+	for _, arg := range c.StringSlice("interwiki") {
+		lang, path, err := splitLangArg(arg)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		iw, err := wikitext.ReadInterwiki(f)
+		check(f.Close())
+		if err != nil {
+			return fmt.Errorf("failed to read interwiki for %v, err: %w", lang, err)
+		}
+		interwikis[lang] = iw
+	}
+	for _, arg := range c.StringSlice("siteinfo") {
+		lang, path, err := splitLangArg(arg)
+		if err != nil {
+			return err
+		}
+		si, err := readSiteInfo(path)
+		if err != nil {
+			return fmt.Errorf("failed to read siteinfo for %v, err: %w", lang, err)
+		}
+		iw, ok := interwikis[lang]
+		if !ok {
+			iw = wikitext.DefaultInterwiki()
+			interwikis[lang] = iw
+		}
+		for _, ns := range si.Namespaces {
+			iw.AddNamespace(ns.Name)
+		}
+	}
+	for lang, iw := range interwikis {
+		a.SetInterwiki(lang, iw)
+	}
+
+	for i, lang := range langs {
+		if err := addPages(a, lang, paths[i]); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(c.String("outpath"), os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "page_id\t%v\n", strings.Join(langs, "\t"))
+	for _, row := range a.Rows(pivot) {
+		fmt.Fprint(w, row.PageID)
+		for _, lang := range langs {
+			fmt.Fprintf(w, "\t%v", row.Titles[lang])
+		}
+		fmt.Fprintln(w)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func addPages(a *align.Aligner, lang string, path string) error {
+	r, err := wikiproto.NewProtoBlockReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to create proto reader, err: %w", err)
+	}
+	defer func() {
+		check(r.Close())
+	}()
+	for {
+		p, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		a.Add(lang, p)
+	}
+}
+
+// readSiteInfo reads the siteinfo header of an XML dump. Files ending with
+// .bz2 are decompressed. Only the start of the dump is read.
+func readSiteInfo(path string) (*wikitext.SiteInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".bz2") {
+		r = bzip2.NewReader(f)
+	}
+	return wikitext.ReadSiteInfo(r)
+}
+
+// splitLangArg splits an argument on the format LANG=VALUE.
+func splitLangArg(arg string) (string, string, error) {
+	parts := strings.SplitN(arg, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid argument '%v', expected LANG=VALUE", arg)
+	}
+	return parts[0], parts[1], nil
+}
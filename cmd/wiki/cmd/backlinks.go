@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sebnyberg/wikipedia/bdg"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+	"github.com/urfave/cli/v2"
+)
+
+func Backlinks() *cli.Command {
+	dbFlag := &cli.StringFlag{
+		Name:     "db",
+		Usage:    "badger database `DIR` holding the backlink index",
+		Aliases:  []string{"d"},
+		Required: true,
+	}
+	return &cli.Command{
+		Name:        "backlinks",
+		Description: "index and query incoming links",
+		Subcommands: []*cli.Command{
+			{
+				Name:        "index",
+				Description: "index the incoming links of a proto LinkedPage dataset",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Usage:    "input `FILE` to read linked pages from, in proto format",
+						Aliases:  []string{"f"},
+						Required: true,
+					},
+					dbFlag,
+				},
+				Action: func(c *cli.Context) error {
+					return backlinksIndexAction(c)
+				},
+			},
+			{
+				Name:        "list",
+				Description: "list the pages linking to a page",
+				Flags: []cli.Flag{
+					dbFlag,
+					&cli.StringFlag{
+						Name:     "title",
+						Usage:    "`TITLE` of the linked page",
+						Aliases:  []string{"t"},
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:  "after",
+						Usage: "only list pages with an ID greater than `ID`",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "list at most `N` pages",
+						Value: 100,
+					},
+					&cli.BoolFlag{
+						Name:  "count",
+						Usage: "print the number of linking pages instead",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return backlinksListAction(c)
+				},
+			},
+		},
+	}
+}
+
+func backlinksIndexAction(c *cli.Context) error {
+	r, err := wikiproto.NewLinkedPageReader(c.String("file"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(r.Close())
+	}()
+	return bdg.WriteBacklinks(c.String("db"), r)
+}
+
+func backlinksListAction(c *cli.Context) error {
+	if c.Int("limit") <= 0 {
+		return errors.New("limit must be positive")
+	}
+	idx, err := bdg.OpenBacklinkIndex(c.String("db"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(idx.Close())
+	}()
+
+	if c.Bool("count") {
+		n, err := idx.Count(c.String("title"))
+		if err != nil {
+			return err
+		}
+		fmt.Println(n)
+		return nil
+	}
+	links, err := idx.Backlinks(c.String("title"), int32(c.Int("after")), c.Int("limit"))
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		fmt.Printf("%v\t%v\n", link.PageID, link.Title)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia/category"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/urfave/cli/v2"
+)
+
+func Categories() *cli.Command {
+	return &cli.Command{
+		Name:        "categories",
+		Description: "query the category graph of a proto page dataset",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "pagefile",
+				Usage:    "input `FILE` to parse pages from, in proto format",
+				Aliases:  []string{"f"},
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:    "category",
+				Usage:   "category `NAME`, with or without the 'Category:' prefix",
+				Aliases: []string{"c"},
+			},
+			&cli.StringFlag{
+				Name:  "query",
+				Usage: "`QUERY` to run, can be 'descendants', 'ancestors', 'pages' or 'cycles'. Cycles are printed one per line, with tab-separated categories.",
+				Value: "descendants",
+			},
+			&cli.IntFlag{
+				Name:  "depth",
+				Usage: "maximum `DEPTH` of the traversal, negative for no limit",
+				Value: -1,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return categoriesAction(c)
+		},
+	}
+}
+
+func categoriesAction(c *cli.Context) error {
+	query := c.String("query")
+	switch query {
+	case "descendants", "ancestors", "pages", "cycles":
+	default:
+		return fmt.Errorf("invalid query '%v'", query)
+	}
+	// Category names are normalized in the same way as category links,
+	// with or without the namespace prefix
+	name := wikitext.NormalizeTitle(c.String("category"))
+	if n, ok := wikitext.CategoryName(name); ok {
+		name = n
+	}
+	if len(name) == 0 && query != "cycles" {
+		return errors.New("category is required")
+	}
+
+	reader, err := wikiproto.NewProtoBlockReader(c.String("pagefile"))
+	if err != nil {
+		return fmt.Errorf("failed to create proto reader, err: %w", err)
+	}
+	defer func() {
+		check(reader.Close())
+	}()
+
+	g, err := category.Build(reader)
+	if err != nil {
+		return err
+	}
+
+	depth := c.Int("depth")
+	switch query {
+	case "descendants":
+		for _, n := range g.Descendants(name, depth) {
+			fmt.Printf("%v\t%v\n", n.Depth, n.Name)
+		}
+	case "ancestors":
+		for _, n := range g.Ancestors(name, depth) {
+			fmt.Printf("%v\t%v\n", n.Depth, n.Name)
+		}
+	case "pages":
+		for _, m := range g.Pages(name, depth) {
+			fmt.Printf("%v\t%v\n", m.PageID, m.Title)
+		}
+	case "cycles":
+		for _, cycle := range g.Cycles() {
+			// Categories of a cycle are unordered, so they are not
+			// printed as a path
+			fmt.Println(strings.Join(cycle, "\t"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/urfave/cli/v2"
+)
+
+func Citations() *cli.Command {
+	return &cli.Command{
+		Name:        "citations",
+		Description: "extract references and citations from a proto page dataset",
+		Flags:       extractFlags(),
+		Action: func(c *cli.Context) error {
+			return extractAction(c, wikitext.NewCitationWriter)
+		},
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/urfave/cli/v2"
+)
+
+func ExternalLinks() *cli.Command {
+	return &cli.Command{
+		Name:        "extlinks",
+		Description: "extract and aggregate external links",
+		Subcommands: []*cli.Command{
+			{
+				Name:        "extract",
+				Description: "extract external links from a proto page dataset",
+				Flags:       extractFlags(),
+				Action: func(c *cli.Context) error {
+					return extractAction(c, wikitext.NewExternalLinkWriter)
+				},
+			},
+			{
+				Name:        "domains",
+				Description: "count the number of links per domain",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Usage:    "input `FILE` to read external links from",
+						Aliases:  []string{"f"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "infmt",
+						Usage: "input `FORMAT`, can be either 'proto' or 'jsonl'",
+						Value: "proto",
+					},
+					&cli.IntFlag{
+						Name:  "top",
+						Usage: "only print the `N` most frequent domains, zero for all",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return extlinksDomainsAction(c)
+				},
+			},
+		},
+	}
+}
+
+func extlinksDomainsAction(c *cli.Context) error {
+	format, err := wikitext.ParseFormat(c.String("infmt"))
+	if err != nil {
+		return err
+	}
+	r, err := newMessageReader(c.String("file"), format)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(r.Close())
+	}()
+
+	counts := make(map[string]int)
+	for {
+		var link wikipedia.ExternalLink
+		if err := r.Read(&link); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		if domain := wikitext.Domain(link.Url); domain != "" {
+			counts[domain]++
+		}
+	}
+
+	domains := make([]string, 0, len(counts))
+	for domain := range counts {
+		domains = append(domains, domain)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if counts[domains[i]] == counts[domains[j]] {
+			return domains[i] < domains[j]
+		}
+		return counts[domains[i]] > counts[domains[j]]
+	})
+	if top := c.Int("top"); top > 0 && top < len(domains) {
+		domains = domains[:top]
+	}
+	for _, domain := range domains {
+		fmt.Printf("%v\t%v\n", counts[domain], domain)
+	}
+	return nil
+}
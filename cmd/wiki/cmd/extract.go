@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sebnyberg/wikipedia"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/urfave/cli/v2"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// extractFlags are the flags used by commands which extract records from
+// a proto page dataset.
+func extractFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "pagefile",
+			Usage:    "input `FILE` to parse pages from, in proto format",
+			Aliases:  []string{"f"},
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     "outpath",
+			Usage:    "output `FILE` to write records to",
+			Aliases:  []string{"o"},
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "outfmt",
+			Usage: "output `FORMAT`, can be either 'proto' or 'jsonl'",
+			Value: "proto",
+		},
+	}
+}
+
+// extractAction transfers pages from the pagefile to the writer returned
+// by newWriter, using the output format from the outfmt flag.
+func extractAction(
+	c *cli.Context,
+	newWriter func(path string, format wikitext.Format) (wikipedia.PageWriter, error),
+) error {
+	format, err := wikitext.ParseFormat(c.String("outfmt"))
+	if err != nil {
+		return err
+	}
+	return transferAction(c, func(path string) (wikipedia.PageWriter, error) {
+		return newWriter(path, format)
+	})
+}
+
+// transferAction transfers pages from the pagefile to the writer returned
+// by newWriter.
+func transferAction(
+	c *cli.Context,
+	newWriter func(path string) (wikipedia.PageWriter, error),
+) error {
+	reader, err := wikiproto.NewProtoBlockReader(c.String("pagefile"))
+	if err != nil {
+		return fmt.Errorf("failed to create proto reader, err: %w", err)
+	}
+	writer, err := newWriter(c.String("outpath"))
+	if err != nil {
+		return fmt.Errorf("failed to create writer, err: %w", err)
+	}
+
+	defer func() {
+		check(reader.Close())
+		check(writer.Close())
+	}()
+
+	return wikipedia.Transfer(reader, writer)
+}
+
+// messageReader reads records written by an extract command.
+type messageReader interface {
+	Read(m pb.Message) error
+	Close() error
+}
+
+// newMessageReader returns a reader of the records in the provided file,
+// which was written in the provided output format.
+func newMessageReader(path string, format wikitext.Format) (messageReader, error) {
+	if format == wikitext.FormatJSONLines {
+		return wikiproto.NewJSONLinesReader(path)
+	}
+	return wikiproto.NewMessageReader(path)
+}
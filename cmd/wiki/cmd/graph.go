@@ -0,0 +1,684 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/graph"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+	"github.com/urfave/cli/v2"
+)
+
+func Graph() *cli.Command {
+	return &cli.Command{
+		Name:        "graph",
+		Description: "build and analyse the link graph",
+		Subcommands: []*cli.Command{
+			{
+				Name:        "build",
+				Description: "build a compact link graph file from a proto LinkedPage dataset",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Usage:    "input `FILE` to read linked pages from, in proto format",
+						Aliases:  []string{"f"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "outpath",
+						Usage:    "output graph `FILE`",
+						Aliases:  []string{"o"},
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return graphBuildAction(c)
+				},
+			},
+			{
+				Name:        "pagerank",
+				Description: "compute the PageRank of each page",
+				Flags: append([]cli.Flag{
+					graphFlag(),
+					&cli.Float64Flag{
+						Name:  "damping",
+						Usage: "`PROBABILITY` of following a link rather than jumping to a random page",
+						Value: 0.85,
+					},
+					&cli.Float64Flag{
+						Name:  "tolerance",
+						Usage: "stop when the L1 norm of the change in scores is below `TOL`",
+						Value: 1e-6,
+					},
+					&cli.IntFlag{
+						Name:  "max-iter",
+						Usage: "maximum `N` of iterations",
+						Value: 100,
+					},
+					&cli.IntFlag{
+						Name:  "workers",
+						Usage: "`N` of goroutines, zero for one per CPU",
+					},
+				}, scoreFlags()...),
+				Action: func(c *cli.Context) error {
+					return graphPageRankAction(c)
+				},
+			},
+			{
+				Name: "hits",
+				Description: "compute the HITS hub and authority scores of each page, on the whole graph " +
+					"or on the neighborhood of seed pages. Writes the ID, title, hub and authority score of each page.",
+				Flags: append([]cli.Flag{
+					graphFlag(),
+					&cli.StringSliceFlag{
+						Name:  "seed",
+						Usage: "`TITLE` of a seed page, scores are computed on the seeds, the pages they link to and the pages linking to them",
+					},
+					&cli.IntFlag{
+						Name:  "max-in",
+						Usage: "maximum `N` of pages linking to each seed to include, negative for all",
+						Value: 50,
+					},
+					&cli.StringFlag{
+						Name:  "rank",
+						Usage: "`SCORE` to order pages by, can be 'hub' or 'authority'",
+						Value: "authority",
+					},
+					&cli.Float64Flag{
+						Name:  "tolerance",
+						Usage: "stop when the L1 norm of the change in scores is below `TOL`",
+						Value: 1e-6,
+					},
+					&cli.IntFlag{
+						Name:  "max-iter",
+						Usage: "maximum `N` of iterations",
+						Value: 100,
+					},
+					&cli.IntFlag{
+						Name:  "workers",
+						Usage: "`N` of goroutines, zero for one per CPU",
+					},
+				}, scoreFlags()...),
+				Action: func(c *cli.Context) error {
+					return graphHITSAction(c)
+				},
+			},
+			{
+				Name:        "communities",
+				Description: "detect communities of pages, and summarize each community",
+				Flags: []cli.Flag{
+					graphFlag(),
+					&cli.StringFlag{
+						Name:  "algorithm",
+						Usage: "community detection `ALGORITHM`, can be 'louvain' or 'labelprop'",
+						Value: "louvain",
+					},
+					&cli.Float64Flag{
+						Name:  "resolution",
+						Usage: "Louvain `RESOLUTION`, larger values give smaller communities",
+						Value: 1,
+					},
+					&cli.IntFlag{
+						Name:  "max-iter",
+						Usage: "maximum `N` of label propagation iterations",
+						Value: 20,
+					},
+					&cli.StringFlag{
+						Name:  "rank",
+						Usage: "`MEASURE` to rank the pages of each community by, can be 'degree' or 'pagerank'",
+						Value: "degree",
+					},
+					&cli.IntFlag{
+						Name:  "top",
+						Usage: "list the `N` highest ranked pages of each community",
+						Value: 10,
+					},
+					&cli.StringFlag{
+						Name:    "outpath",
+						Usage:   "output `FILE` to write the community of each page to",
+						Aliases: []string{"o"},
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return graphCommunitiesAction(c)
+				},
+			},
+			{
+				Name:        "walks",
+				Description: "write node2vec random walks over the link graph to sharded text files",
+				Flags: []cli.Flag{
+					graphFlag(),
+					&cli.StringFlag{
+						Name:     "outdir",
+						Usage:    "output `DIR` to write walk files to",
+						Aliases:  []string{"o"},
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:  "shards",
+						Usage: "`N` of output files, which are written in parallel",
+						Value: 1,
+					},
+					&cli.IntFlag{
+						Name:  "length",
+						Usage: "maximum `N` of pages in a walk",
+						Value: 80,
+					},
+					&cli.IntFlag{
+						Name:  "walks",
+						Usage: "`N` of walks started from each page",
+						Value: 10,
+					},
+					&cli.Float64Flag{
+						Name:  "p",
+						Usage: "return parameter `P`, lower values keep walks local",
+						Value: 1,
+					},
+					&cli.Float64Flag{
+						Name:  "q",
+						Usage: "in-out parameter `Q`, lower values make walks explore",
+						Value: 1,
+					},
+					&cli.Int64Flag{
+						Name:  "seed",
+						Usage: "random `SEED`, the same seed gives the same walks",
+						Value: 1,
+					},
+					&cli.BoolFlag{
+						Name:  "titles",
+						Usage: "write titles rather than page IDs",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return graphWalksAction(c)
+				},
+			},
+			{
+				Name: "export",
+				Description: "export the link graph for other tools. Formats are 'tsv' (edge list), " +
+					"'graphml', 'gexf' (Gephi) and 'neo4j' (node and relationship CSV files for " +
+					"neo4j-admin import, written to the output directory)",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "graph",
+						Usage:   "input graph `FILE`, as written by 'graph build'",
+						Aliases: []string{"g"},
+					},
+					&cli.StringFlag{
+						Name:    "file",
+						Usage:   "input `FILE` to read linked pages from, in proto format, if no graph is provided",
+						Aliases: []string{"f"},
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "output `FORMAT`, can be 'tsv', 'graphml', 'gexf' or 'neo4j'",
+						Value: "tsv",
+					},
+					&cli.StringFlag{
+						Name:     "outpath",
+						Usage:    "output `FILE`, or directory for neo4j",
+						Aliases:  []string{"o"},
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "pagerank",
+						Usage: "compute the PageRank of each page and add it as an attribute",
+					},
+					&cli.BoolFlag{
+						Name:  "titles",
+						Usage: "write titles rather than page IDs to the tsv edge list",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return graphExportAction(c)
+				},
+			},
+			{
+				Name:        "diff",
+				Description: "compare the links of two linked page datasets, and write the changes of each page",
+				ArgsUsage:   "BEFORE AFTER",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name: "graphs",
+						Usage: "read graph files written by 'graph build' rather than linked page datasets. " +
+							"Graphs do not store links to missing pages, so creating or deleting a page " +
+							"changes the links of the pages linking to it.",
+					},
+					&cli.StringFlag{
+						Name:    "outpath",
+						Usage:   "output `FILE` to write the changes of each page to, in proto format",
+						Aliases: []string{"o"},
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return graphDiffAction(c)
+				},
+			},
+			graphHealth(),
+		},
+	}
+}
+
+func graphBuildAction(c *cli.Context) error {
+	r, err := wikiproto.NewLinkedPageReader(c.String("file"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(r.Close())
+	}()
+
+	g, err := graph.Build(r)
+	if err != nil {
+		return err
+	}
+	if err := g.WriteFile(c.String("outpath")); err != nil {
+		return err
+	}
+	fmt.Printf("nodes: %v, edges: %v\n", g.NumNodes(), g.NumEdges())
+	return nil
+}
+
+func graphWalksAction(c *cli.Context) error {
+	if c.Float64("p") <= 0 || c.Float64("q") <= 0 {
+		return errors.New("p and q must be positive")
+	}
+	if c.Int("length") < 1 || c.Int("walks") < 1 {
+		return errors.New("length and walks must be positive")
+	}
+	g, err := graph.Open(c.String("graph"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(g.Close())
+	}()
+
+	return graph.WriteWalks(g, c.String("outdir"), c.Int("shards"), c.Bool("titles"), graph.WalkOptions{
+		Length:       c.Int("length"),
+		WalksPerNode: c.Int("walks"),
+		P:            c.Float64("p"),
+		Q:            c.Float64("q"),
+		Seed:         c.Int64("seed"),
+	})
+}
+
+func graphExportAction(c *cli.Context) error {
+	g, err := loadGraph(c)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(g.Close())
+	}()
+
+	var pagerank []float64
+	if c.Bool("pagerank") {
+		pagerank, _ = graph.PageRank(g, graph.PageRankOptions{})
+	}
+
+	path := c.String("outpath")
+	if c.String("format") == "neo4j" {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return err
+		}
+		return writeFiles(func(ws ...io.Writer) error {
+			return graph.WriteNeo4j(ws[0], ws[1], g, pagerank)
+		}, filepath.Join(path, "nodes.csv"), filepath.Join(path, "relationships.csv"))
+	}
+	var write func(w io.Writer) error
+	switch c.String("format") {
+	case "tsv":
+		write = func(w io.Writer) error { return graph.WriteEdgeList(w, g, c.Bool("titles")) }
+	case "graphml":
+		write = func(w io.Writer) error { return graph.WriteGraphML(w, g, pagerank) }
+	case "gexf":
+		write = func(w io.Writer) error { return graph.WriteGEXF(w, g, pagerank) }
+	default:
+		return fmt.Errorf("invalid format '%v'", c.String("format"))
+	}
+	return writeFiles(func(ws ...io.Writer) error { return write(ws[0]) }, path)
+}
+
+func graphDiffAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("expected the paths of two dumps")
+	}
+	write := func(*wikipedia.LinkDiff) error { return nil }
+	if path := c.String("outpath"); path != "" {
+		w, err := wikiproto.NewMessageWriter(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			check(w.Close())
+		}()
+		write = func(d *wikipedia.LinkDiff) error { return w.Write(d) }
+	}
+
+	var stats graph.DiffStats
+	var err error
+	if c.Bool("graphs") {
+		stats, err = diffGraphs(c.Args().Get(0), c.Args().Get(1), write)
+	} else {
+		stats, err = diffLinks(c.Args().Get(0), c.Args().Get(1), write)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("pages: %v -> %v, links: %v -> %v\n",
+		stats.PagesBefore, stats.PagesAfter, stats.LinksBefore, stats.LinksAfter)
+	fmt.Printf("pages added: %v, deleted: %v, renamed: %v, with changed links: %v\n",
+		stats.AddedPages, stats.DeletedPages, stats.RenamedPages, stats.ChangedPages)
+	fmt.Printf("links added: %v, removed: %v, churn: %.2f%%\n",
+		stats.AddedLinks, stats.RemovedLinks, 100*stats.Churn())
+	return nil
+}
+
+func diffLinks(before, after string, fn func(*wikipedia.LinkDiff) error) (graph.DiffStats, error) {
+	rb, err := wikiproto.NewLinkedPageReader(before)
+	if err != nil {
+		return graph.DiffStats{}, err
+	}
+	defer func() {
+		check(rb.Close())
+	}()
+	ra, err := wikiproto.NewLinkedPageReader(after)
+	if err != nil {
+		return graph.DiffStats{}, err
+	}
+	defer func() {
+		check(ra.Close())
+	}()
+	return graph.DiffLinks(rb, ra, fn)
+}
+
+func diffGraphs(before, after string, fn func(*wikipedia.LinkDiff) error) (graph.DiffStats, error) {
+	gb, err := graph.Open(before)
+	if err != nil {
+		return graph.DiffStats{}, err
+	}
+	defer func() {
+		check(gb.Close())
+	}()
+	ga, err := graph.Open(after)
+	if err != nil {
+		return graph.DiffStats{}, err
+	}
+	defer func() {
+		check(ga.Close())
+	}()
+	return graph.Diff(gb, ga, fn)
+}
+
+// loadGraph opens the graph file, or builds the graph of the linked page
+// dataset if no graph file is provided.
+func loadGraph(c *cli.Context) (*graph.Graph, error) {
+	if path := c.String("graph"); path != "" {
+		return graph.Open(path)
+	}
+	if c.String("file") == "" {
+		return nil, errors.New("either graph or file is required")
+	}
+	return buildGraph(c.String("file"))
+}
+
+// buildGraph builds the graph of a linked page dataset.
+func buildGraph(path string) (*graph.Graph, error) {
+	r, err := wikiproto.NewLinkedPageReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		check(r.Close())
+	}()
+	return graph.Build(r)
+}
+
+// writeFiles creates new files, and passes buffered writers of the files
+// to write.
+func writeFiles(write func(ws ...io.Writer) error, paths ...string) error {
+	files := make([]*os.File, 0, len(paths))
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	ws := make([]io.Writer, len(paths))
+	bufs := make([]*bufio.Writer, len(paths))
+	for i, path := range paths {
+		f, err := os.OpenFile(path, os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		bufs[i] = bufio.NewWriter(f)
+		ws[i] = bufs[i]
+	}
+	if err := write(ws...); err != nil {
+		return err
+	}
+	for i, f := range files {
+		if err := bufs[i].Flush(); err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	files = nil
+	return nil
+}
+
+func graphPageRankAction(c *cli.Context) error {
+	g, err := graph.Open(c.String("graph"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(g.Close())
+	}()
+
+	scores, iter := graph.PageRank(g, graph.PageRankOptions{
+		Damping:       c.Float64("damping"),
+		Tolerance:     c.Float64("tolerance"),
+		MaxIterations: c.Int("max-iter"),
+		Workers:       c.Int("workers"),
+	})
+	fmt.Fprintf(os.Stderr, "iterations: %v\n", iter)
+	return writeScores(c, g, scores)
+}
+
+func graphHITSAction(c *cli.Context) error {
+	if rank := c.String("rank"); rank != "hub" && rank != "authority" {
+		return fmt.Errorf("invalid rank '%v'", rank)
+	}
+	g, err := graph.Open(c.String("graph"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(g.Close())
+	}()
+
+	var nodes []int32
+	if titles := c.StringSlice("seed"); len(titles) > 0 {
+		seeds := make([]int32, len(titles))
+		for i, title := range titles {
+			n, ok := g.NodeByTitle(title)
+			if !ok {
+				return fmt.Errorf("page not found: %v", title)
+			}
+			seeds[i] = n
+		}
+		nodes = graph.Neighborhood(g, seeds, c.Int("max-in"))
+		fmt.Fprintf(os.Stderr, "pages: %v\n", len(nodes))
+	}
+
+	hubs, authorities, iter := graph.HITS(g, graph.HITSOptions{
+		Tolerance:     c.Float64("tolerance"),
+		MaxIterations: c.Int("max-iter"),
+		Workers:       c.Int("workers"),
+		Nodes:         nodes,
+	})
+	fmt.Fprintf(os.Stderr, "iterations: %v\n", iter)
+	rank := authorities
+	if c.String("rank") == "hub" {
+		rank = hubs
+	}
+	return writeNodeScores(c, g, nodes, rank, hubs, authorities)
+}
+
+func graphCommunitiesAction(c *cli.Context) error {
+	g, err := graph.Open(c.String("graph"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(g.Close())
+	}()
+
+	var communities []int32
+	switch c.String("algorithm") {
+	case "louvain":
+		communities = graph.Louvain(g, c.Float64("resolution"))
+	case "labelprop":
+		communities = graph.LabelPropagation(g, c.Int("max-iter"))
+	default:
+		return fmt.Errorf("invalid algorithm '%v'", c.String("algorithm"))
+	}
+	var scores []float64
+	switch c.String("rank") {
+	case "degree":
+	case "pagerank":
+		scores, _ = graph.PageRank(g, graph.PageRankOptions{})
+	default:
+		return fmt.Errorf("invalid rank measure '%v'", c.String("rank"))
+	}
+
+	if path := c.String("outpath"); path != "" {
+		f, err := os.OpenFile(path, os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		buf := bufio.NewWriter(f)
+		for n, community := range communities {
+			fmt.Fprintf(buf, "%v\t%v\t%v\n", g.PageID(int32(n)), g.Title(int32(n)), community)
+		}
+		if err := buf.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "communities: %v, modularity: %.4f\n",
+		maxCommunity(communities)+1, graph.Modularity(g, communities))
+	for _, cluster := range graph.Summarize(g, communities, scores, c.Int("top")) {
+		titles := make([]string, len(cluster.Top))
+		for i, n := range cluster.Top {
+			titles[i] = g.Title(n)
+		}
+		fmt.Printf("%v\t%v\t%v\n", cluster.ID, cluster.Size, strings.Join(titles, "; "))
+	}
+	return nil
+}
+
+// maxCommunity returns the largest community number, or -1 if there are
+// no nodes.
+func maxCommunity(communities []int32) int32 {
+	res := int32(-1)
+	for _, c := range communities {
+		if c > res {
+			res = c
+		}
+	}
+	return res
+}
+
+// graphFlag is the flag of commands reading a graph file written by
+// 'graph build'.
+func graphFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:     "graph",
+		Usage:    "input graph `FILE`, as written by 'graph build'",
+		Aliases:  []string{"g"},
+		Required: true,
+	}
+}
+
+// scoreFlags are the flags of commands writing scores with writeScores.
+func scoreFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "outpath",
+			Usage:   "output `FILE`, or stdout if empty",
+			Aliases: []string{"o"},
+		},
+		&cli.IntFlag{
+			Name:  "top",
+			Usage: "only write the `N` highest scores, zero for all",
+		},
+	}
+}
+
+// writeScores writes the ID, title and score of each node as tab-separated
+// lines, ordered by descending score.
+func writeScores(c *cli.Context, g *graph.Graph, scores []float64) error {
+	return writeNodeScores(c, g, nil, scores, scores)
+}
+
+// writeNodeScores writes the ID, title and scores of nodes as tab-separated
+// lines, ordered by descending rank. If nodes is nil, all nodes are
+// written.
+func writeNodeScores(c *cli.Context, g *graph.Graph, nodes []int32, rank []float64, scores ...[]float64) error {
+	if nodes == nil {
+		nodes = make([]int32, g.NumNodes())
+		for i := range nodes {
+			nodes[i] = int32(i)
+		}
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return rank[nodes[i]] > rank[nodes[j]]
+	})
+	if top := c.Int("top"); top > 0 && top < len(nodes) {
+		nodes = nodes[:top]
+	}
+
+	var w io.Writer = os.Stdout
+	if path := c.String("outpath"); path != "" {
+		f, err := os.OpenFile(path, os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			check(f.Close())
+		}()
+		w = f
+	}
+	buf := bufio.NewWriter(w)
+	for _, n := range nodes {
+		if _, err := fmt.Fprintf(buf, "%v\t%v", g.PageID(n), g.Title(n)); err != nil {
+			return err
+		}
+		for _, s := range scores {
+			if _, err := fmt.Fprintf(buf, "\t%v", s[n]); err != nil {
+				return err
+			}
+		}
+		if err := buf.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
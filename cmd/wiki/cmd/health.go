@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sebnyberg/wikipedia/graph"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+	"github.com/urfave/cli/v2"
+)
+
+func graphHealth() *cli.Command {
+	return &cli.Command{
+		Name: "health",
+		Description: "report maintenance lists of the link graph. Reports are 'summary', " +
+			"'weak' and 'strong' (connected components), 'orphans' (pages without incoming links), " +
+			"'deadends' (pages without outgoing links), 'redlinks' (links to missing pages) " +
+			"and 'degrees' (degree distributions)",
+		Flags: []cli.Flag{
+			graphFlag(),
+			&cli.StringFlag{
+				Name:  "report",
+				Usage: "`REPORT` to write",
+				Value: "summary",
+			},
+			&cli.StringFlag{
+				Name:    "file",
+				Usage:   "input `FILE` to read linked pages from, in proto format. Required for red links.",
+				Aliases: []string{"f"},
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "output `FORMAT`, can be either 'csv' or 'json'",
+				Value: "csv",
+			},
+			&cli.StringFlag{
+				Name:    "outpath",
+				Usage:   "output `FILE`, or stdout if empty",
+				Aliases: []string{"o"},
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return graphHealthAction(c)
+		},
+	}
+}
+
+// table is a report with named columns.
+type table struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+func (t *table) add(values ...interface{}) {
+	t.rows = append(t.rows, values)
+}
+
+// healthReports contains the names of the reports of the health command.
+var healthReports = map[string]bool{
+	"summary": true, "weak": true, "strong": true, "orphans": true,
+	"deadends": true, "redlinks": true, "degrees": true,
+}
+
+func graphHealthAction(c *cli.Context) error {
+	// Options and the output file are checked before the graph is read
+	var writeTable func(io.Writer, *table) error
+	switch c.String("format") {
+	case "csv":
+		writeTable = writeCSVTable
+	case "json":
+		writeTable = writeJSONTable
+	default:
+		return fmt.Errorf("invalid format '%v'", c.String("format"))
+	}
+	report := c.String("report")
+	if !healthReports[report] {
+		return fmt.Errorf("invalid report '%v'", report)
+	}
+	if report == "redlinks" && c.String("file") == "" {
+		return errors.New("file is required for red links")
+	}
+	var w io.Writer = os.Stdout
+	if path := c.String("outpath"); path != "" {
+		f, err := os.OpenFile(path, os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			check(f.Close())
+		}()
+		w = f
+	}
+
+	g, err := graph.Open(c.String("graph"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(g.Close())
+	}()
+
+	var t *table
+	switch report {
+	case "summary":
+		t = healthSummary(g)
+	case "weak":
+		t = componentsTable(g, graph.WeakComponents(g))
+	case "strong":
+		t = componentsTable(g, graph.StrongComponents(g))
+	case "orphans":
+		t = pagesTable(g, graph.Orphans(g))
+	case "deadends":
+		t = pagesTable(g, graph.DeadEnds(g))
+	case "redlinks":
+		r, err := wikiproto.NewLinkedPageReader(c.String("file"))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			check(r.Close())
+		}()
+		redLinks, err := graph.FindRedLinks(r, g)
+		if err != nil {
+			return err
+		}
+		t = &table{columns: []string{"title", "links"}}
+		for _, link := range redLinks {
+			t.add(link.Title, link.Count)
+		}
+	case "degrees":
+		in, out := graph.DegreeDistribution(g)
+		t = &table{columns: []string{"degree", "in", "out"}}
+		for d := 0; d < len(in) || d < len(out); d++ {
+			var nin, nout int
+			if d < len(in) {
+				nin = in[d]
+			}
+			if d < len(out) {
+				nout = out[d]
+			}
+			if nin > 0 || nout > 0 {
+				t.add(d, nin, nout)
+			}
+		}
+	}
+
+	buf := bufio.NewWriter(w)
+	if err := writeTable(buf, t); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+func healthSummary(g *graph.Graph) *table {
+	t := &table{columns: []string{"metric", "value"}}
+	t.add("pages", g.NumNodes())
+	t.add("links", g.NumEdges())
+	for _, c := range []struct {
+		name       string
+		components []int32
+	}{
+		{"weak", graph.WeakComponents(g)},
+		{"strong", graph.StrongComponents(g)},
+	} {
+		var largest int
+		for _, comp := range c.components {
+			if comp == 0 {
+				largest++
+			}
+		}
+		t.add(c.name+"_components", maxCommunity(c.components)+1)
+		t.add("largest_"+c.name+"_component", largest)
+	}
+	t.add("orphans", len(graph.Orphans(g)))
+	t.add("dead_ends", len(graph.DeadEnds(g)))
+	return t
+}
+
+// componentsTable lists the components of a graph with their size and
+// the page with the lowest ID in each component.
+func componentsTable(g *graph.Graph, components []int32) *table {
+	sizes := make([]int, maxCommunity(components)+1)
+	first := make([]int32, len(sizes))
+	for i := range first {
+		first[i] = -1
+	}
+	for n, c := range components {
+		sizes[c]++
+		if first[c] == -1 {
+			first[c] = int32(n)
+		}
+	}
+	t := &table{columns: []string{"component", "size", "page_id", "title"}}
+	for c, size := range sizes {
+		t.add(c, size, g.PageID(first[c]), g.Title(first[c]))
+	}
+	return t
+}
+
+func pagesTable(g *graph.Graph, nodes []int32) *table {
+	t := &table{columns: []string{"page_id", "title"}}
+	for _, n := range nodes {
+		t.add(g.PageID(n), g.Title(n))
+	}
+	return t
+}
+
+func writeCSVTable(w io.Writer, t *table) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.columns); err != nil {
+		return err
+	}
+	record := make([]string, len(t.columns))
+	for _, row := range t.rows {
+		for i, v := range row {
+			record[i] = fmt.Sprint(v)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeJSONTable writes a table as a JSON array of objects, with keys in
+// the order of the columns.
+func writeJSONTable(w io.Writer, t *table) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+	for i, row := range t.rows {
+		sep := ",\n"
+		if i == 0 {
+			sep = "\n"
+		}
+		if _, err := io.WriteString(w, sep+"{"); err != nil {
+			return err
+		}
+		for j, v := range row {
+			key, err := json.Marshal(t.columns[j])
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if j > 0 {
+				key = append([]byte(","), key...)
+			}
+			if _, err := fmt.Fprintf(w, "%s:%s", key, value); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "}"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\n]\n")
+	return err
+}
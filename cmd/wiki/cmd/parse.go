@@ -8,6 +8,7 @@ import (
 	"github.com/pkg/profile"
 	"github.com/sebnyberg/wikipedia"
 	"github.com/sebnyberg/wikipedia/bdg"
+	"github.com/sebnyberg/wikipedia/wikitext"
 	"github.com/urfave/cli/v2"
 )
 
@@ -41,6 +42,26 @@ func Parse() *cli.Command {
 				Name:  "outpath",
 				Usage: "output `PATH`. For proto, use a file, for badger, use a directory",
 			},
+			&cli.StringFlag{
+				Name:  "emit",
+				Usage: "`RECORDS` to emit, can be either 'pages' or 'sections'. Sections are written in 'proto' or 'jsonl' format.",
+				Value: "pages",
+			},
+			&cli.StringFlag{
+				Name:  "text",
+				Usage: "revision text `FORMAT`, can be either 'wiki' or 'plain'",
+				Value: "wiki",
+			},
+			&cli.BoolFlag{
+				Name:  "lists",
+				Usage: "keep list items when converting to plain text",
+				Value: true,
+			},
+			&cli.BoolFlag{
+				Name:  "titles",
+				Usage: "keep section titles when converting to plain text",
+				Value: true,
+			},
 		},
 		Action: func(c *cli.Context) error {
 			return parseAction(c)
@@ -82,14 +103,39 @@ func parseAction(c *cli.Context) error {
 		return errors.New("outpath is required")
 	}
 
+	var plain *wikitext.PlainTextOptions
+	switch c.String("text") {
+	case "wiki":
+	case "plain":
+		plain = &wikitext.PlainTextOptions{
+			Lists:         c.Bool("lists"),
+			SectionTitles: c.Bool("titles"),
+		}
+	default:
+		return errors.New("text must be either 'wiki' or 'plain'")
+	}
+
+	emit := c.String("emit")
 	var writer wikipedia.PageBlockWriter
-	switch outfmt {
-	case "badger":
+	switch {
+	case emit == "sections":
+		var format wikitext.Format
+		format, err = wikitext.ParseFormat(outfmt)
+		if err != nil {
+			return err
+		}
+		writer, err = wikitext.NewSectionWriter(outpath, format, plain)
+		if err != nil {
+			return fmt.Errorf("failed to create section writer, err: %w", err)
+		}
+	case emit != "pages":
+		return errors.New("emit must be either 'pages' or 'sections'")
+	case outfmt == "badger":
 		writer, err = bdg.NewPageWriter(outpath)
 		if err != nil {
 			return fmt.Errorf("failed to create badger writer, err: %w", err)
 		}
-	case "proto":
+	case outfmt == "proto":
 		writer, err = wikipedia.NewProtoBlockWriter(outpath)
 		if err != nil {
 			return fmt.Errorf("failed to create proto writer, err: %w", err)
@@ -97,6 +143,11 @@ func parseAction(c *cli.Context) error {
 	default:
 		fmt.Println("output must be of type 'badger' or 'proto'")
 	}
+
+	// The section writer converts sections to plain text by itself
+	if plain != nil && emit == "pages" {
+		writer = wikitext.NewPlainTextWriter(writer, *plain)
+	}
 	defer profile.Start(profile.CPUProfile, profile.ProfilePath(".")).Stop()
 
 	defer func() {
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia/graph"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/urfave/cli/v2"
+)
+
+func Path() *cli.Command {
+	return &cli.Command{
+		Name:        "path",
+		ArgsUsage:   "FROM TO",
+		Description: "find the shortest link paths between two pages",
+		Flags: []cli.Flag{
+			graphFlag(),
+			&cli.BoolFlag{
+				Name:  "all",
+				Usage: "print all shortest paths rather than one",
+			},
+			&cli.IntFlag{
+				Name:  "max",
+				Usage: "print at most `N` paths when printing all paths, zero for all",
+				Value: 100,
+			},
+			&cli.StringSliceFlag{
+				Name:  "namespace",
+				Usage: "only pass through pages in `NAMESPACE`, e.g. 'Category', or 'Main' for articles. Can be repeated.",
+			},
+			&cli.StringFlag{
+				Name:  "redirects",
+				Usage: "proto page dataset `FILE` to read redirects from, which paths may not pass through",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			return pathAction(c)
+		},
+	}
+}
+
+func pathAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("expected the titles of two pages")
+	}
+	g, err := graph.Open(c.String("graph"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(g.Close())
+	}()
+
+	var ends [2]int32
+	for i, title := range c.Args().Slice() {
+		n, ok := g.NodeByTitle(title)
+		if !ok {
+			return fmt.Errorf("page not found: %v", title)
+		}
+		ends[i] = n
+	}
+
+	var filters []func(n int32) bool
+	if namespaces := c.StringSlice("namespace"); len(namespaces) > 0 {
+		allowed := make(map[string]bool)
+		for _, ns := range namespaces {
+			if strings.EqualFold(ns, "main") {
+				ns = ""
+			}
+			allowed[strings.ToLower(ns)] = true
+		}
+		filters = append(filters, func(n int32) bool {
+			ns, _ := wikitext.SplitNamespace(g.Title(n))
+			return allowed[strings.ToLower(ns)]
+		})
+	}
+	if path := c.String("redirects"); path != "" {
+		redirects, err := readRedirects(path)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, func(n int32) bool {
+			return !redirects[g.Title(n)]
+		})
+	}
+
+	opts := graph.PathOptions{MaxPaths: 1}
+	if c.Bool("all") {
+		opts.MaxPaths = c.Int("max")
+	}
+	if len(filters) > 0 {
+		opts.Allow = func(n int32) bool {
+			for _, allow := range filters {
+				if !allow(n) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	paths := graph.ShortestPaths(g, ends[0], ends[1], opts)
+	if len(paths) == 0 {
+		return errors.New("no path found")
+	}
+	for _, path := range paths {
+		titles := make([]string, len(path))
+		for i, n := range path {
+			titles[i] = g.Title(n)
+		}
+		fmt.Println(strings.Join(titles, " -> "))
+	}
+	return nil
+}
+
+// readRedirects returns the titles of the redirects in a proto page
+// dataset.
+func readRedirects(path string) (map[string]bool, error) {
+	r, err := wikiproto.NewProtoBlockReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		check(r.Close())
+	}()
+
+	redirects := make(map[string]bool)
+	for {
+		p, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				return redirects, nil
+			}
+			return nil, err
+		}
+		if p.RedirectTitle != "" {
+			redirects[p.Title] = true
+		}
+	}
+}
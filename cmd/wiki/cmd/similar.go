@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sebnyberg/wikipedia/bdg"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+	"github.com/sebnyberg/wikipedia/minhash"
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/urfave/cli/v2"
+)
+
+func Similar() *cli.Command {
+	dbFlag := &cli.StringFlag{
+		Name:     "db",
+		Usage:    "badger database `DIR` holding the similarity index",
+		Aliases:  []string{"d"},
+		Required: true,
+	}
+	return &cli.Command{
+		Name:        "similar",
+		Description: "index and find similar pages, by MinHash over links or text",
+		Subcommands: []*cli.Command{
+			{
+				Name:        "index",
+				Description: "index the MinHash signatures of the pages of a dataset",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Usage:   "input `FILE` to read linked pages from, in proto format",
+						Aliases: []string{"f"},
+					},
+					&cli.StringFlag{
+						Name:  "pagefile",
+						Usage: "input `FILE` to read pages from, in proto format. Pages are compared by the shingles of their plain text rather than by links.",
+					},
+					dbFlag,
+					&cli.IntFlag{
+						Name:  "bands",
+						Usage: "`N` of LSH bands. More bands find more similar pages, but are slower.",
+						Value: 32,
+					},
+					&cli.IntFlag{
+						Name:  "rows",
+						Usage: "`N` of signature values per LSH band. More rows are faster, but find fewer pages.",
+						Value: 4,
+					},
+					&cli.IntFlag{
+						Name:  "shingle",
+						Usage: "`N` of words per text shingle",
+						Value: 3,
+					},
+					&cli.Int64Flag{
+						Name:  "seed",
+						Usage: "`SEED` of the MinHash functions",
+						Value: 1,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return similarIndexAction(c)
+				},
+			},
+			{
+				Name:        "query",
+				Description: "find the pages most similar to a page",
+				Flags: []cli.Flag{
+					dbFlag,
+					&cli.StringFlag{
+						Name:     "title",
+						Usage:    "`TITLE` of the page to find similar pages for",
+						Aliases:  []string{"t"},
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:  "top",
+						Usage: "print the `N` most similar pages",
+						Value: 10,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return similarQueryAction(c)
+				},
+			},
+		},
+	}
+}
+
+func similarIndexAction(c *cli.Context) error {
+	if c.String("file") != "" && c.String("pagefile") != "" {
+		return errors.New("only one of file and pagefile may be set")
+	}
+	if c.String("file") == "" && c.String("pagefile") == "" {
+		return errors.New("either file or pagefile is required")
+	}
+	h := minhash.NewHasher(c.Int("bands")*c.Int("rows"), c.Int64("seed"))
+	w, err := bdg.NewSimilarityWriter(c.String("db"), c.Int("bands"), c.Int("rows"))
+	if err != nil {
+		return err
+	}
+	if err := writeSignatures(c, h, w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func writeSignatures(c *cli.Context, h *minhash.Hasher, w *bdg.SimilarityWriter) error {
+	if c.String("file") != "" {
+		r, err := wikiproto.NewLinkedPageReader(c.String("file"))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			check(r.Close())
+		}()
+		for {
+			p, err := r.Next()
+			if err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+			if err := w.Write(p.PageId, p.PageTitle, h.Signature(minhash.LinkFeatures(p))); err != nil {
+				return err
+			}
+		}
+	}
+
+	r, err := wikiproto.NewProtoBlockReader(c.String("pagefile"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(r.Close())
+	}()
+	for {
+		p, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if p.RedirectTitle != "" || len(p.Revisions) == 0 {
+			continue
+		}
+		text := wikitext.PlainText(p.Revisions[len(p.Revisions)-1].Text, wikitext.PlainTextOptions{Lists: true})
+		if err := w.Write(p.Id, p.Title, h.Signature(minhash.Shingles(text, c.Int("shingle")))); err != nil {
+			return err
+		}
+	}
+}
+
+func similarQueryAction(c *cli.Context) error {
+	if c.Int("top") <= 0 {
+		return errors.New("top must be positive")
+	}
+	idx, err := bdg.OpenSimilarityIndex(c.String("db"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(idx.Close())
+	}()
+
+	pages, ok, err := idx.Similar(c.String("title"), c.Int("top"))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("page not found: %v", c.String("title"))
+	}
+	for _, p := range pages {
+		fmt.Printf("%v\t%v\t%.4f\n", p.PageID, p.Title, p.Similarity)
+	}
+	return nil
+}
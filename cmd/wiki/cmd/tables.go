@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/urfave/cli/v2"
+)
+
+func Tables() *cli.Command {
+	flags := extractFlags()
+	flags[2] = &cli.StringFlag{
+		Name:  "outfmt",
+		Usage: "output `FORMAT`, can be 'proto', 'jsonl' or 'csv'. For csv, outpath is a directory with one file per table.",
+		Value: "proto",
+	}
+	return &cli.Command{
+		Name:        "tables",
+		Description: "extract wikitables from a proto page dataset",
+		Flags:       flags,
+		Action: func(c *cli.Context) error {
+			if c.String("outfmt") == "csv" {
+				return transferAction(c, wikitext.NewTableCSVWriter)
+			}
+			return extractAction(c, wikitext.NewTableWriter)
+		},
+	}
+}
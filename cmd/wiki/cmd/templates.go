@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sebnyberg/wikipedia"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/urfave/cli/v2"
+)
+
+func Templates() *cli.Command {
+	return &cli.Command{
+		Name:        "templates",
+		Description: "extract and query template invocations",
+		Subcommands: []*cli.Command{
+			{
+				Name:        "extract",
+				Description: "extract template invocations from a proto page dataset",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "pagefile",
+						Usage:    "input `FILE` to parse pages from, in proto format",
+						Aliases:  []string{"f"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "outpath",
+						Usage:    "output `FILE` to write templates to",
+						Aliases:  []string{"o"},
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return transferAction(c, wikitext.NewTemplateWriter)
+				},
+			},
+			{
+				Name:        "query",
+				Description: "list pages using a template",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Usage:    "input `FILE` to read templates from",
+						Aliases:  []string{"f"},
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "name",
+						Usage:    "template `NAME`, e.g. 'Infobox settlement'",
+						Aliases:  []string{"n"},
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return templatesQueryAction(c)
+				},
+			},
+		},
+	}
+}
+
+func templatesQueryAction(c *cli.Context) error {
+	name := wikitext.NormalizeTemplateName(c.String("name"))
+	if len(name) == 0 {
+		return errors.New("name is required")
+	}
+
+	r, err := wikiproto.NewMessageReader(c.String("file"))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		check(r.Close())
+	}()
+
+	var prevID int32 = -1
+	for {
+		var t wikipedia.Template
+		if err := r.Read(&t); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		// Templates are written page by page, so duplicate uses within
+		// the same page are adjacent
+		if t.Name != name || t.PageId == prevID {
+			continue
+		}
+		prevID = t.PageId
+		fmt.Printf("%v\t%v\n", t.PageId, t.PageTitle)
+	}
+}
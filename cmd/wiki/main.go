@@ -33,6 +33,17 @@ func NewApp() *cli.App {
 		Usage:    "wiki commands",
 		Commands: []*cli.Command{
 			cmd.Parse(),
+			cmd.Templates(),
+			cmd.Infoboxes(),
+			cmd.Categories(),
+			cmd.ExternalLinks(),
+			cmd.Align(),
+			cmd.Citations(),
+			cmd.Tables(),
+			cmd.Backlinks(),
+			cmd.Graph(),
+			cmd.Path(),
+			cmd.Similar(),
 		},
 	}
 
@@ -1,6 +1,6 @@
 module github.com/sebnyberg/wikipedia
 
-go 1.14
+go 1.23
 
 require (
 	github.com/DataDog/zstd v1.4.1
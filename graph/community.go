@@ -0,0 +1,341 @@
+package graph
+
+import "sort"
+
+// LabelPropagation detects communities in the undirected version of the
+// graph by label propagation, and returns the community of each node.
+// Communities are numbered from zero in order of descending size.
+//
+// Each node starts in a community of its own, and repeatedly joins the
+// community with the largest weight among its neighbours, until no node
+// changes community or maxIterations is reached. Ties are broken in favour
+// of the current community, and then of the community with the smallest
+// number, so the result is deterministic.
+func LabelPropagation(g *Graph, maxIterations int) []int32 {
+	w := newWeightedGraph(g)
+	labels := make([]int32, w.numNodes())
+	for i := range labels {
+		labels[i] = int32(i)
+	}
+	weights := make([]float64, len(labels))
+	var touched []int32
+	for iter := 0; iter < maxIterations; iter++ {
+		changed := false
+		for v := range labels {
+			touched = touched[:0]
+			for i := w.offsets[v]; i < w.offsets[v+1]; i++ {
+				l := labels[w.adj[i]]
+				if weights[l] == 0 {
+					touched = append(touched, l)
+				}
+				weights[l] += w.weights[i]
+			}
+			cur := labels[v]
+			best := cur
+			for _, l := range touched {
+				switch {
+				case weights[l] > weights[best]:
+					best = l
+				case weights[l] == weights[best] && best != cur && (l == cur || l < best):
+					best = l
+				}
+			}
+			for _, l := range touched {
+				weights[l] = 0
+			}
+			if best != cur {
+				labels[v] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return renumber(labels)
+}
+
+// Louvain detects communities in the undirected version of the graph by
+// the Louvain method, and returns the community of each node. Communities
+// are numbered from zero in order of descending size.
+//
+// Nodes are moved between communities as long as it increases the
+// modularity of the partition. Communities are then merged into single
+// nodes, and the process is repeated on the merged graph until no node is
+// moved. The resolution scales the penalty of large communities, where
+// one gives standard modularity and larger values give smaller
+// communities.
+func Louvain(g *Graph, resolution float64) []int32 {
+	w := newWeightedGraph(g)
+	communities := make([]int32, w.numNodes())
+	for i := range communities {
+		communities[i] = int32(i)
+	}
+	for {
+		level, moved := w.moveNodes(resolution)
+		if !moved {
+			break
+		}
+		level = renumber(level)
+		for i, c := range communities {
+			communities[i] = level[c]
+		}
+		w = w.aggregate(level)
+	}
+	return renumber(communities)
+}
+
+// Modularity returns the modularity of a partition of the nodes into
+// communities, which is the fraction of edge weight within communities
+// minus the fraction expected if edges were placed at random.
+func Modularity(g *Graph, communities []int32) float64 {
+	w := newWeightedGraph(g)
+	if w.total == 0 {
+		return 0
+	}
+	inner := make(map[int32]float64)
+	degrees := make(map[int32]float64)
+	for v, c := range communities {
+		for i := w.offsets[v]; i < w.offsets[v+1]; i++ {
+			degrees[c] += w.weights[i]
+			if communities[w.adj[i]] == c {
+				inner[c] += w.weights[i]
+			}
+		}
+	}
+	var q float64
+	for c, d := range degrees {
+		q += inner[c]/w.total - (d/w.total)*(d/w.total)
+	}
+	return q
+}
+
+// Cluster summarizes a community.
+type Cluster struct {
+	ID   int32
+	Size int
+
+	// Top contains the nodes of the community with the highest scores, in
+	// descending order.
+	Top []int32
+}
+
+// Summarize returns a summary of each community, ordered by community.
+// Nodes are ranked by the provided scores, e.g. PageRank, or by their
+// number of incoming and outgoing links if scores is nil. At most top
+// nodes are listed per community.
+func Summarize(g *Graph, communities []int32, scores []float64, top int) []Cluster {
+	score := func(n int32) float64 {
+		if scores == nil {
+			return float64(g.InDegree(n) + g.OutDegree(n))
+		}
+		return scores[n]
+	}
+	var res []Cluster
+	for n, c := range communities {
+		for int(c) >= len(res) {
+			res = append(res, Cluster{ID: int32(len(res))})
+		}
+		cl := &res[c]
+		cl.Size++
+		// Insert the node into the top list, kept sorted by score
+		i := sort.Search(len(cl.Top), func(i int) bool {
+			return score(cl.Top[i]) < score(int32(n))
+		})
+		if i < top {
+			if len(cl.Top) < top {
+				cl.Top = append(cl.Top, 0)
+			}
+			copy(cl.Top[i+1:], cl.Top[i:])
+			cl.Top[i] = int32(n)
+		}
+	}
+	return res
+}
+
+// renumber maps labels to community numbers from zero, in order of
+// descending community size. Ties are ordered by the first node of each
+// community.
+func renumber(labels []int32) []int32 {
+	sizes := make(map[int32]int)
+	var order []int32
+	for _, l := range labels {
+		if sizes[l] == 0 {
+			order = append(order, l)
+		}
+		sizes[l]++
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return sizes[order[i]] > sizes[order[j]]
+	})
+	ids := make(map[int32]int32, len(order))
+	for i, l := range order {
+		ids[l] = int32(i)
+	}
+	res := make([]int32, len(labels))
+	for i, l := range labels {
+		res[i] = ids[l]
+	}
+	return res
+}
+
+// weightedGraph is an undirected graph with weighted edges in CSR form.
+// Each edge is stored in the adjacency lists of both of its nodes, and
+// loops of merged nodes are stored once with the total weight of the
+// edges merged into them.
+type weightedGraph struct {
+	offsets []int64
+	adj     []int32
+	weights []float64
+
+	// total is the sum of all weights, i.e. twice the total weight of the
+	// edges.
+	total float64
+}
+
+func (w *weightedGraph) numNodes() int {
+	return len(w.offsets) - 1
+}
+
+// newWeightedGraph returns the undirected version of a graph, where the
+// weight of an edge is the number of links between its nodes, i.e. two for
+// pages linking to each other. Links from a page to itself are ignored.
+func newWeightedGraph(g *Graph) *weightedGraph {
+	n := g.NumNodes()
+	w := &weightedGraph{offsets: make([]int64, n+1)}
+	for v := int32(0); int(v) < n; v++ {
+		// Merge the sorted lists of outgoing and incoming links
+		out, in := g.Out(v), g.In(v)
+		for len(out) > 0 || len(in) > 0 {
+			var u int32
+			var weight float64
+			switch {
+			case len(in) == 0 || (len(out) > 0 && out[0] < in[0]):
+				u, weight, out = out[0], 1, out[1:]
+			case len(out) == 0 || in[0] < out[0]:
+				u, weight, in = in[0], 1, in[1:]
+			default:
+				u, weight, out, in = out[0], 2, out[1:], in[1:]
+			}
+			if u == v {
+				continue
+			}
+			w.adj = append(w.adj, u)
+			w.weights = append(w.weights, weight)
+			w.total += weight
+		}
+		w.offsets[v+1] = int64(len(w.adj))
+	}
+	return w
+}
+
+const maxLouvainPasses = 100
+
+// moveNodes runs the local moving phase of the Louvain method, and
+// returns the community of each node and whether any node was moved.
+// Nodes are only moved if modularity strictly increases, but the number of
+// passes over the nodes is limited in case of rounding errors.
+func (w *weightedGraph) moveNodes(resolution float64) ([]int32, bool) {
+	n := w.numNodes()
+	communities := make([]int32, n)
+	degrees := make([]float64, n)
+	totals := make([]float64, n)
+	for v := range communities {
+		communities[v] = int32(v)
+		for i := w.offsets[v]; i < w.offsets[v+1]; i++ {
+			degrees[v] += w.weights[i]
+		}
+		totals[v] = degrees[v]
+	}
+	if w.total == 0 {
+		return communities, false
+	}
+
+	links := make([]float64, n)
+	var touched []int32
+	moved := false
+	for pass := 0; pass < maxLouvainPasses; pass++ {
+		changed := false
+		for v := 0; v < n; v++ {
+			// Sum the weights to each neighbouring community
+			touched = touched[:0]
+			for i := w.offsets[v]; i < w.offsets[v+1]; i++ {
+				u := w.adj[i]
+				if int(u) == v {
+					continue
+				}
+				c := communities[u]
+				if links[c] == 0 {
+					touched = append(touched, c)
+				}
+				links[c] += w.weights[i]
+			}
+
+			// Remove the node from its community, and add it to the
+			// community with the largest gain in modularity
+			cur := communities[v]
+			totals[cur] -= degrees[v]
+			gain := func(c int32) float64 {
+				return links[c] - resolution*totals[c]*degrees[v]/w.total
+			}
+			best, bestGain := cur, gain(cur)
+			for _, c := range touched {
+				if g := gain(c); g > bestGain {
+					best, bestGain = c, g
+				}
+			}
+			totals[best] += degrees[v]
+			for _, c := range touched {
+				links[c] = 0
+			}
+			if best != cur {
+				communities[v] = best
+				changed = true
+				moved = true
+			}
+		}
+		if !changed {
+			return communities, moved
+		}
+	}
+	return communities, moved
+}
+
+// aggregate returns the graph where the nodes of each community are
+// merged into a single node, numbered by the community.
+func (w *weightedGraph) aggregate(communities []int32) *weightedGraph {
+	var k int32
+	for _, c := range communities {
+		if c+1 > k {
+			k = c + 1
+		}
+	}
+	members := make([][]int32, k)
+	for v, c := range communities {
+		members[c] = append(members[c], int32(v))
+	}
+
+	res := &weightedGraph{offsets: make([]int64, k+1), total: w.total}
+	weights := make([]float64, k)
+	var touched []int32
+	for c := int32(0); c < k; c++ {
+		touched = touched[:0]
+		for _, v := range members[c] {
+			for i := w.offsets[v]; i < w.offsets[v+1]; i++ {
+				d := communities[w.adj[i]]
+				if weights[d] == 0 {
+					touched = append(touched, d)
+				}
+				weights[d] += w.weights[i]
+			}
+		}
+		sort.Slice(touched, func(i, j int) bool { return touched[i] < touched[j] })
+		for _, d := range touched {
+			res.adj = append(res.adj, d)
+			res.weights = append(res.weights, weights[d])
+			weights[d] = 0
+		}
+		res.offsets[c+1] = int64(len(res.adj))
+	}
+	return res
+}
@@ -0,0 +1,107 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/graph"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+// cliquesGraph returns a graph of two cliques of four pages, joined by a
+// single link, and a page without links.
+func cliquesGraph(t *testing.T) *graph.Graph {
+	return buildGraph(t,
+		linktest.Page(1, "A1", "A2", "A3", "A4"),
+		linktest.Page(2, "A2", "A1", "A3", "A4"),
+		linktest.Page(3, "A3", "A1", "A2", "A4"),
+		linktest.Page(4, "A4", "A1", "A2", "A3", "B1"),
+		linktest.Page(5, "B1", "B2", "B3", "B4"),
+		linktest.Page(6, "B2", "B1", "B3", "B4"),
+		linktest.Page(7, "B3", "B1", "B2", "B4"),
+		linktest.Page(8, "B4", "B1", "B2", "B3"),
+		linktest.Page(9, "C"),
+	)
+}
+
+func Test_Communities(t *testing.T) {
+	g := cliquesGraph(t)
+	want := []int32{0, 0, 0, 0, 1, 1, 1, 1, 2}
+	for _, tc := range []struct {
+		name string
+		got  []int32
+	}{
+		{"label propagation", graph.LabelPropagation(g, 20)},
+		{"louvain", graph.Louvain(g, 1)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if !cmp.Equal(want, tc.got) {
+				t.Errorf("invalid communities\n%v", cmp.Diff(want, tc.got))
+			}
+		})
+	}
+
+	single := make([]int32, g.NumNodes())
+	if q, qSingle := graph.Modularity(g, want), graph.Modularity(g, single); q <= qSingle || q <= 0.3 {
+		t.Errorf("invalid modularity, expected: more than 0.3 and %v for a single community, got: %v", qSingle, q)
+	}
+	if q := graph.Modularity(buildGraph(t, linktest.Page(1, "A")), []int32{0}); q != 0 {
+		t.Errorf("invalid modularity of a graph without links, expected: 0, got: %v", q)
+	}
+}
+
+func Test_Summarize(t *testing.T) {
+	g := cliquesGraph(t)
+	communities := []int32{0, 0, 0, 0, 1, 1, 1, 1, 2}
+	for _, tc := range []struct {
+		name   string
+		scores []float64
+		want   []graph.Cluster
+	}{
+		{"degree", nil, []graph.Cluster{
+			{ID: 0, Size: 4, Top: []int32{3, 0}},
+			{ID: 1, Size: 4, Top: []int32{4, 5}},
+			{ID: 2, Size: 1, Top: []int32{8}},
+		}},
+		{"scores", []float64{1, 2, 3, 4, 5, 6, 7, 8, 9}, []graph.Cluster{
+			{ID: 0, Size: 4, Top: []int32{3, 2}},
+			{ID: 1, Size: 4, Top: []int32{7, 6}},
+			{ID: 2, Size: 1, Top: []int32{8}},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := graph.Summarize(g, communities, tc.scores, 2)
+			if !cmp.Equal(tc.want, got) {
+				t.Errorf("invalid summaries\n%v", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
+
+func Test_LouvainResolution(t *testing.T) {
+	// A ring of pages where each page links to the next one
+	var pages []*wikipedia.LinkedPage
+	titles := []string{"A", "B", "C", "D", "E", "F"}
+	for i, title := range titles {
+		pages = append(pages, linktest.Page(int32(i+1), title, titles[(i+1)%len(titles)]))
+	}
+	g := buildGraph(t, pages...)
+	coarse, fine := graph.Louvain(g, 0.01), graph.Louvain(g, 10)
+	if n := maxCommunity(coarse) + 1; n != 1 {
+		t.Errorf("invalid number of communities at low resolution, expected: 1, got: %v", n)
+	}
+	if n := int(maxCommunity(fine)) + 1; n != len(titles) {
+		t.Errorf("invalid number of communities at high resolution, expected: %v, got: %v", len(titles), n)
+	}
+}
+
+func maxCommunity(communities []int32) int32 {
+	var res int32
+	for _, c := range communities {
+		if c > res {
+			res = c
+		}
+	}
+	return res
+}
@@ -0,0 +1,201 @@
+package graph
+
+import (
+	"io"
+	"sort"
+
+	"github.com/sebnyberg/wikipedia"
+)
+
+// WeakComponents returns the weakly connected component of each node,
+// i.e. the nodes connected by links in any direction. Components are
+// numbered from zero in order of descending size.
+func WeakComponents(g *Graph) []int32 {
+	n := g.NumNodes()
+	components := make([]int32, n)
+	for i := range components {
+		components[i] = -1
+	}
+	var queue []int32
+	var c int32
+	for start := int32(0); int(start) < n; start++ {
+		if components[start] != -1 {
+			continue
+		}
+		components[start] = c
+		queue = append(queue[:0], start)
+		for len(queue) > 0 {
+			v := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			for _, next := range [][]int32{g.Out(v), g.In(v)} {
+				for _, u := range next {
+					if components[u] == -1 {
+						components[u] = c
+						queue = append(queue, u)
+					}
+				}
+			}
+		}
+		c++
+	}
+	return renumber(components)
+}
+
+// StrongComponents returns the strongly connected component of each node,
+// i.e. the nodes which can be reached from each other by following links.
+// Components are numbered from zero in order of descending size.
+func StrongComponents(g *Graph) []int32 {
+	// Tarjan's algorithm, with an explicit stack since the depth of the
+	// search may exceed the size of a goroutine stack
+	n := g.NumNodes()
+	index := make([]int32, n)
+	lowlink := make([]int32, n)
+	onStack := make([]bool, n)
+	components := make([]int32, n)
+	for i := range index {
+		index[i] = -1
+	}
+	type frame struct {
+		v    int32
+		edge int
+	}
+	var calls []frame
+	var stack []int32
+	var next, c int32
+	for start := int32(0); int(start) < n; start++ {
+		if index[start] != -1 {
+			continue
+		}
+		calls = append(calls[:0], frame{v: start})
+		index[start], lowlink[start] = next, next
+		next++
+		stack = append(stack, start)
+		onStack[start] = true
+		for len(calls) > 0 {
+			f := &calls[len(calls)-1]
+			out := g.Out(f.v)
+			if f.edge < len(out) {
+				u := out[f.edge]
+				f.edge++
+				switch {
+				case index[u] == -1:
+					index[u], lowlink[u] = next, next
+					next++
+					stack = append(stack, u)
+					onStack[u] = true
+					calls = append(calls, frame{v: u})
+				case onStack[u] && index[u] < lowlink[f.v]:
+					lowlink[f.v] = index[u]
+				}
+				continue
+			}
+
+			// All links of v are visited
+			v := f.v
+			calls = calls[:len(calls)-1]
+			if len(calls) > 0 {
+				if parent := calls[len(calls)-1].v; lowlink[v] < lowlink[parent] {
+					lowlink[parent] = lowlink[v]
+				}
+			}
+			if lowlink[v] == index[v] {
+				for {
+					u := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[u] = false
+					components[u] = c
+					if u == v {
+						break
+					}
+				}
+				c++
+			}
+		}
+	}
+	return renumber(components)
+}
+
+// Orphans returns the nodes without incoming links.
+func Orphans(g *Graph) []int32 {
+	var res []int32
+	for n := int32(0); int(n) < g.NumNodes(); n++ {
+		if g.InDegree(n) == 0 {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// DeadEnds returns the nodes without outgoing links.
+func DeadEnds(g *Graph) []int32 {
+	var res []int32
+	for n := int32(0); int(n) < g.NumNodes(); n++ {
+		if g.OutDegree(n) == 0 {
+			res = append(res, n)
+		}
+	}
+	return res
+}
+
+// DegreeDistribution returns the number of nodes with each number of
+// incoming and outgoing links, indexed by degree.
+func DegreeDistribution(g *Graph) (in, out []int) {
+	for n := int32(0); int(n) < g.NumNodes(); n++ {
+		in = addCount(in, g.InDegree(n))
+		out = addCount(out, g.OutDegree(n))
+	}
+	return in, out
+}
+
+func addCount(counts []int, i int) []int {
+	for i >= len(counts) {
+		counts = append(counts, 0)
+	}
+	counts[i]++
+	return counts
+}
+
+// RedLink is a title which is linked to, but which is not a page of the
+// graph.
+type RedLink struct {
+	Title string
+
+	// Count is the number of pages linking to the title.
+	Count int
+}
+
+// FindRedLinks reads all pages from r and returns the titles they link to
+// which are not pages of g, ordered by descending count.
+func FindRedLinks(r wikipedia.LinkedPageReader, g *Graph) ([]RedLink, error) {
+	counts := make(map[string]int)
+	for {
+		p, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		seen := make(map[string]bool, len(p.Links))
+		for _, link := range p.Links {
+			if seen[link.TargetTitle] {
+				continue
+			}
+			seen[link.TargetTitle] = true
+			if _, ok := g.NodeByTitle(link.TargetTitle); !ok {
+				counts[link.TargetTitle]++
+			}
+		}
+	}
+	res := make([]RedLink, 0, len(counts))
+	for title, n := range counts {
+		res = append(res, RedLink{Title: title, Count: n})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count == res[j].Count {
+			return res[i].Title < res[j].Title
+		}
+		return res[i].Count > res[j].Count
+	})
+	return res, nil
+}
@@ -0,0 +1,78 @@
+package graph_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/graph"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+func Test_Health(t *testing.T) {
+	pages := []*wikipedia.LinkedPage{
+		linktest.Page(1, "A", "B", "Missing"),
+		linktest.Page(2, "B", "C", "Missing", "Other", "Missing"),
+		linktest.Page(3, "C", "A", "D"),
+		linktest.Page(4, "D"),
+		linktest.Page(5, "E", "F"),
+		linktest.Page(6, "F"),
+		linktest.Page(7, "G"),
+	}
+	g := buildGraph(t, pages...)
+
+	for _, tc := range []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"weak components", graph.WeakComponents(g), []int32{0, 0, 0, 0, 1, 1, 2}},
+		{"strong components", graph.StrongComponents(g), []int32{0, 0, 0, 1, 2, 3, 4}},
+		{"orphans", graph.Orphans(g), []int32{4, 6}},
+		{"dead ends", graph.DeadEnds(g), []int32{3, 5, 6}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if !cmp.Equal(tc.want, tc.got) {
+				t.Errorf("invalid nodes\n%v", cmp.Diff(tc.want, tc.got))
+			}
+		})
+	}
+
+	in, out := graph.DegreeDistribution(g)
+	if !cmp.Equal([]int{2, 5}, in) {
+		t.Errorf("invalid in-degree distribution\n%v", cmp.Diff([]int{2, 5}, in))
+	}
+	if !cmp.Equal([]int{3, 3, 1}, out) {
+		t.Errorf("invalid out-degree distribution\n%v", cmp.Diff([]int{3, 3, 1}, out))
+	}
+
+	r := linktest.Reader(pages)
+	redLinks, err := graph.FindRedLinks(&r, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []graph.RedLink{{Title: "Missing", Count: 2}, {Title: "Other", Count: 1}}
+	if !cmp.Equal(want, redLinks) {
+		t.Errorf("invalid red links\n%v", cmp.Diff(want, redLinks))
+	}
+}
+
+func Test_StrongComponentsDeep(t *testing.T) {
+	// A long cycle makes the search as deep as the graph
+	const n = 100000
+	titles := make([]string, n)
+	for i := range titles {
+		titles[i] = strconv.Itoa(i)
+	}
+	var pages []*wikipedia.LinkedPage
+	for i := range titles {
+		pages = append(pages, linktest.Page(int32(i+1), titles[i], titles[(i+1)%n]))
+	}
+	g := buildGraph(t, pages...)
+	for i, c := range graph.StrongComponents(g) {
+		if c != 0 {
+			t.Fatalf("invalid component of node %v, expected: 0, got: %v", i, c)
+		}
+	}
+}
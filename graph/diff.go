@@ -0,0 +1,295 @@
+package graph
+
+import (
+	"io"
+	"sort"
+
+	"github.com/sebnyberg/wikipedia"
+)
+
+// DiffStats summarizes the changes between two dumps.
+type DiffStats struct {
+	PagesBefore, PagesAfter int
+	LinksBefore, LinksAfter int
+
+	AddedPages   int
+	DeletedPages int
+	RenamedPages int
+
+	// ChangedPages is the number of pages in both dumps whose links
+	// changed.
+	ChangedPages int
+
+	// AddedLinks and RemovedLinks include the links of added and deleted
+	// pages.
+	AddedLinks   int
+	RemovedLinks int
+}
+
+// Churn returns the number of added and removed links relative to the
+// number of links before.
+func (s DiffStats) Churn() float64 {
+	if s.LinksBefore == 0 {
+		return 0
+	}
+	return float64(s.AddedLinks+s.RemovedLinks) / float64(s.LinksBefore)
+}
+
+// DiffLinks reads the linked pages of two dumps, and calls fn with the
+// changes of each added, deleted, renamed or changed page. Pages are
+// matched by page ID, and links by their target title, including links to
+// missing pages. Changes are reported in the order that pages are read
+// from after, followed by the deleted pages in order of page ID. If a
+// page ID occurs more than once in a dump, only its first page is used.
+//
+// The pages before are held in memory, with each distinct title stored
+// once.
+func DiffLinks(before, after wikipedia.LinkedPageReader, fn func(*wikipedia.LinkDiff) error) (DiffStats, error) {
+	var stats DiffStats
+	ids := make(map[string]int32)
+	var titles []string
+	intern := func(title string) int32 {
+		id, ok := ids[title]
+		if !ok {
+			id = int32(len(titles))
+			ids[title] = id
+			titles = append(titles, title)
+		}
+		return id
+	}
+	titlesOf := func(links []int32) []string {
+		var res []string
+		for _, id := range links {
+			res = append(res, titles[id])
+		}
+		return res
+	}
+
+	type page struct {
+		title int32
+		// links contains the sorted IDs of distinct targets
+		links []int32
+	}
+	pages := make(map[int32]page)
+	for {
+		p, err := before.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return stats, err
+		}
+		if _, ok := pages[p.PageId]; ok {
+			continue
+		}
+		links := make([]int32, 0, len(p.Links))
+		for _, link := range p.Links {
+			links = append(links, intern(link.TargetTitle))
+		}
+		links = sortUnique(links)
+		pages[p.PageId] = page{title: intern(p.PageTitle), links: links}
+		stats.PagesBefore++
+		stats.LinksBefore += len(links)
+	}
+
+	var known []int32
+	seenAfter := make(map[int32]bool)
+	for {
+		p, err := after.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return stats, err
+		}
+		if seenAfter[p.PageId] {
+			continue
+		}
+		seenAfter[p.PageId] = true
+		stats.PagesAfter++
+		d := &wikipedia.LinkDiff{PageId: p.PageId, PageTitle: p.PageTitle}
+
+		// Targets which are not titles before are always added
+		known = known[:0]
+		seen := make(map[string]bool, len(p.Links))
+		for _, link := range p.Links {
+			if seen[link.TargetTitle] {
+				continue
+			}
+			seen[link.TargetTitle] = true
+			stats.LinksAfter++
+			if id, ok := ids[link.TargetTitle]; ok {
+				known = append(known, id)
+			} else {
+				d.AddedLinks = append(d.AddedLinks, link.TargetTitle)
+			}
+		}
+
+		old, ok := pages[p.PageId]
+		if !ok {
+			d.Change = wikipedia.LinkDiff_ADDED
+			d.AddedLinks = append(d.AddedLinks, titlesOf(known)...)
+			stats.AddedPages++
+		} else {
+			delete(pages, p.PageId)
+			if titles[old.title] != p.PageTitle {
+				d.PreviousTitle = titles[old.title]
+				stats.RenamedPages++
+			}
+			sort.Slice(known, func(i, j int) bool { return known[i] < known[j] })
+			removed, added := difference(old.links, known)
+			d.AddedLinks = append(d.AddedLinks, titlesOf(added)...)
+			d.RemovedLinks = titlesOf(removed)
+		}
+		if err := report(&stats, d, fn); err != nil {
+			return stats, err
+		}
+	}
+
+	deleted := make([]int32, 0, len(pages))
+	for id := range pages {
+		deleted = append(deleted, id)
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i] < deleted[j] })
+	for _, id := range deleted {
+		p := pages[id]
+		d := &wikipedia.LinkDiff{
+			PageId:       id,
+			PageTitle:    titles[p.title],
+			Change:       wikipedia.LinkDiff_DELETED,
+			RemovedLinks: titlesOf(p.links),
+		}
+		stats.DeletedPages++
+		if err := report(&stats, d, fn); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Diff compares the graphs of two dumps like DiffLinks, with changes
+// reported in order of page ID.
+//
+// The result is an approximation: graphs do not store links to missing
+// pages, so creating a page adds a link on each page that already linked
+// to its title, and deleting a page removes them, although the text of
+// those pages did not change. Use DiffLinks for exact changes.
+func Diff(before, after *Graph, fn func(*wikipedia.LinkDiff) error) (DiffStats, error) {
+	stats := DiffStats{
+		PagesBefore: before.NumNodes(),
+		PagesAfter:  after.NumNodes(),
+		LinksBefore: before.NumEdges(),
+		LinksAfter:  after.NumEdges(),
+	}
+
+	// The node of each page before in the graph after, by title
+	nodes := make([]int32, before.NumNodes())
+	for u := range nodes {
+		nodes[u], _ = after.NodeByTitle(before.title(int32(u)))
+	}
+
+	var u, v int32
+	var out []int32
+	for int(u) < before.NumNodes() || int(v) < after.NumNodes() {
+		d := &wikipedia.LinkDiff{}
+		switch {
+		case int(v) == after.NumNodes() || int(u) < before.NumNodes() && before.PageID(u) < after.PageID(v):
+			d.PageId, d.PageTitle = before.PageID(u), before.Title(u)
+			d.Change = wikipedia.LinkDiff_DELETED
+			d.RemovedLinks = nodeTitles(before, before.Out(u))
+			stats.DeletedPages++
+			u++
+		case int(u) == before.NumNodes() || after.PageID(v) < before.PageID(u):
+			d.PageId, d.PageTitle = after.PageID(v), after.Title(v)
+			d.Change = wikipedia.LinkDiff_ADDED
+			d.AddedLinks = nodeTitles(after, after.Out(v))
+			stats.AddedPages++
+			v++
+		default:
+			d.PageId, d.PageTitle = after.PageID(v), after.Title(v)
+			if before.title(u) != after.title(v) {
+				d.PreviousTitle = before.Title(u)
+				stats.RenamedPages++
+			}
+			out = out[:0]
+			for _, w := range before.Out(u) {
+				if nodes[w] == -1 {
+					d.RemovedLinks = append(d.RemovedLinks, before.Title(w))
+				} else {
+					out = append(out, nodes[w])
+				}
+			}
+			sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+			removed, added := difference(out, after.Out(v))
+			d.RemovedLinks = append(d.RemovedLinks, nodeTitles(after, removed)...)
+			d.AddedLinks = nodeTitles(after, added)
+			u++
+			v++
+		}
+		if err := report(&stats, d, fn); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// report sorts the changed links of a page and adds them to the stats,
+// and passes the changes to fn unless the page did not change.
+func report(stats *DiffStats, d *wikipedia.LinkDiff, fn func(*wikipedia.LinkDiff) error) error {
+	sort.Strings(d.AddedLinks)
+	sort.Strings(d.RemovedLinks)
+	stats.AddedLinks += len(d.AddedLinks)
+	stats.RemovedLinks += len(d.RemovedLinks)
+	if d.Change == wikipedia.LinkDiff_MODIFIED {
+		if len(d.AddedLinks) > 0 || len(d.RemovedLinks) > 0 {
+			stats.ChangedPages++
+		} else if d.PreviousTitle == "" {
+			return nil
+		}
+	}
+	return fn(d)
+}
+
+// sortUnique sorts a list of IDs and removes duplicates.
+func sortUnique(ids []int32) []int32 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	res := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+// difference returns the elements of sorted lists which are only in a,
+// and only in b.
+func difference(a, b []int32) (onlyA, onlyB []int32) {
+	var i, j int
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && a[i] < b[j]:
+			onlyA = append(onlyA, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			onlyB = append(onlyB, b[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return onlyA, onlyB
+}
+
+// nodeTitles returns the titles of nodes.
+func nodeTitles(g *Graph, nodes []int32) []string {
+	if len(nodes) == 0 {
+		return nil
+	}
+	res := make([]string, len(nodes))
+	for i, n := range nodes {
+		res[i] = g.Title(n)
+	}
+	return res
+}
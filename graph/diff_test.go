@@ -0,0 +1,119 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/graph"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+func Test_Diff(t *testing.T) {
+	before := buildGraph(t,
+		linktest.Page(1, "A", "B", "C"),
+		linktest.Page(2, "B", "A"),
+		linktest.Page(3, "C", "A"),
+		linktest.Page(4, "D", "A"),
+	)
+	after := buildGraph(t,
+		linktest.Page(1, "A", "B2", "E"),
+		linktest.Page(2, "B2", "A"),
+		linktest.Page(3, "C", "A"),
+		linktest.Page(5, "E", "A"),
+	)
+
+	var got []*wikipedia.LinkDiff
+	stats, err := graph.Diff(before, after, func(d *wikipedia.LinkDiff) error {
+		got = append(got, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*wikipedia.LinkDiff{
+		{PageId: 1, PageTitle: "A", AddedLinks: []string{"B2", "E"}, RemovedLinks: []string{"B", "C"}},
+		{PageId: 2, PageTitle: "B2", PreviousTitle: "B"},
+		{PageId: 4, PageTitle: "D", Change: wikipedia.LinkDiff_DELETED, RemovedLinks: []string{"A"}},
+		{PageId: 5, PageTitle: "E", Change: wikipedia.LinkDiff_ADDED, AddedLinks: []string{"A"}},
+	}
+	if !cmp.Equal(want, got, cmpopts.IgnoreUnexported(wikipedia.LinkDiff{})) {
+		t.Errorf("invalid diffs\n%v", cmp.Diff(want, got, cmpopts.IgnoreUnexported(wikipedia.LinkDiff{})))
+	}
+
+	wantStats := graph.DiffStats{
+		PagesBefore:  4,
+		PagesAfter:   4,
+		LinksBefore:  5,
+		LinksAfter:   5,
+		AddedPages:   1,
+		DeletedPages: 1,
+		RenamedPages: 1,
+		ChangedPages: 1,
+		AddedLinks:   3,
+		RemovedLinks: 3,
+	}
+	if !cmp.Equal(wantStats, stats) {
+		t.Errorf("invalid stats\n%v", cmp.Diff(wantStats, stats))
+	}
+	if churn := stats.Churn(); churn != 1.2 {
+		t.Errorf("invalid churn, expected: 1.2, got: %v", churn)
+	}
+}
+
+func Test_DiffLinks(t *testing.T) {
+	before := linktest.Reader{
+		linktest.Page(1, "A", "B", "C", "Missing", "B"),
+		linktest.Page(2, "B", "A"),
+		linktest.Page(3, "C", "A", "Gone"),
+		linktest.Page(4, "Gone", "A"),
+		linktest.Page(2, "B2", "C"),
+	}
+	after := linktest.Reader{
+		linktest.Page(1, "A", "B2", "E", "Missing"),
+		linktest.Page(2, "B2", "A"),
+		linktest.Page(3, "C", "A", "Gone"),
+		linktest.Page(5, "E", "A"),
+		linktest.Page(7, "Missing"),
+		// Duplicate pages are ignored
+		linktest.Page(5, "E2", "B"),
+	}
+
+	var got []*wikipedia.LinkDiff
+	stats, err := graph.DiffLinks(&before, &after, func(d *wikipedia.LinkDiff) error {
+		got = append(got, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Creating Missing and deleting Gone does not change the pages
+	// linking to them
+	want := []*wikipedia.LinkDiff{
+		{PageId: 1, PageTitle: "A", AddedLinks: []string{"B2", "E"}, RemovedLinks: []string{"B", "C"}},
+		{PageId: 2, PageTitle: "B2", PreviousTitle: "B"},
+		{PageId: 5, PageTitle: "E", Change: wikipedia.LinkDiff_ADDED, AddedLinks: []string{"A"}},
+		{PageId: 7, PageTitle: "Missing", Change: wikipedia.LinkDiff_ADDED},
+		{PageId: 4, PageTitle: "Gone", Change: wikipedia.LinkDiff_DELETED, RemovedLinks: []string{"A"}},
+	}
+	if !cmp.Equal(want, got, cmpopts.IgnoreUnexported(wikipedia.LinkDiff{})) {
+		t.Errorf("invalid diffs\n%v", cmp.Diff(want, got, cmpopts.IgnoreUnexported(wikipedia.LinkDiff{})))
+	}
+
+	wantStats := graph.DiffStats{
+		PagesBefore:  4,
+		PagesAfter:   5,
+		LinksBefore:  7,
+		LinksAfter:   7,
+		AddedPages:   2,
+		DeletedPages: 1,
+		RenamedPages: 1,
+		ChangedPages: 1,
+		AddedLinks:   3,
+		RemovedLinks: 3,
+	}
+	if !cmp.Equal(wantStats, stats) {
+		t.Errorf("invalid stats\n%v", cmp.Diff(wantStats, stats))
+	}
+}
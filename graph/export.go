@@ -0,0 +1,203 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+// Exports identify nodes by page ID, and describe each node by its title
+// and namespace. The PageRank of each node is added when pagerank is not
+// nil. Namespaces are canonical names such as "Category", and empty for
+// the main namespace.
+
+// WriteEdgeList writes the links of a graph as tab-separated lines of
+// source and target page IDs, or of titles if titles is true.
+func WriteEdgeList(w io.Writer, g *Graph, titles bool) error {
+	buf := bufio.NewWriter(w)
+	var line []byte
+	for v := int32(0); int(v) < g.NumNodes(); v++ {
+		for _, u := range g.Out(v) {
+			line = line[:0]
+			if titles {
+				line = append(append(append(line, g.title(v)...), '\t'), g.title(u)...)
+			} else {
+				line = strconv.AppendInt(line, int64(g.PageID(v)), 10)
+				line = append(line, '\t')
+				line = strconv.AppendInt(line, int64(g.PageID(u)), 10)
+			}
+			if _, err := buf.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+	}
+	return buf.Flush()
+}
+
+// WriteGraphML writes a graph in the GraphML format.
+func WriteGraphML(w io.Writer, g *Graph, pagerank []float64) error {
+	x := &xmlWriter{w: bufio.NewWriter(w)}
+	x.raw(xml.Header)
+	x.raw(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	x.raw(`  <key id="title" for="node" attr.name="title" attr.type="string"/>` + "\n")
+	x.raw(`  <key id="namespace" for="node" attr.name="namespace" attr.type="string"/>` + "\n")
+	if pagerank != nil {
+		x.raw(`  <key id="pagerank" for="node" attr.name="pagerank" attr.type="double"/>` + "\n")
+	}
+	x.raw(`  <graph id="G" edgedefault="directed">` + "\n")
+	for v := int32(0); int(v) < g.NumNodes(); v++ {
+		ns, _ := wikitext.SplitNamespace(g.title(v))
+		x.raw(`    <node id="`).int(g.PageID(v)).raw(`">`)
+		x.raw(`<data key="title">`).text(g.title(v)).raw(`</data>`)
+		x.raw(`<data key="namespace">`).text(ns).raw(`</data>`)
+		if pagerank != nil {
+			x.raw(`<data key="pagerank">`).float(pagerank[v]).raw(`</data>`)
+		}
+		x.raw("</node>\n")
+	}
+	for v := int32(0); int(v) < g.NumNodes(); v++ {
+		for _, u := range g.Out(v) {
+			x.raw(`    <edge source="`).int(g.PageID(v)).raw(`" target="`).int(g.PageID(u)).raw("\"/>\n")
+		}
+	}
+	x.raw("  </graph>\n</graphml>\n")
+	return x.flush()
+}
+
+// WriteGEXF writes a graph in the GEXF 1.2 format read by Gephi. Titles
+// are written as node labels.
+func WriteGEXF(w io.Writer, g *Graph, pagerank []float64) error {
+	x := &xmlWriter{w: bufio.NewWriter(w)}
+	x.raw(xml.Header)
+	x.raw(`<gexf xmlns="http://gexf.net/1.2" version="1.2">` + "\n")
+	x.raw(`  <graph mode="static" defaultedgetype="directed">` + "\n")
+	x.raw(`    <attributes class="node">` + "\n")
+	x.raw(`      <attribute id="namespace" title="namespace" type="string"/>` + "\n")
+	if pagerank != nil {
+		x.raw(`      <attribute id="pagerank" title="pagerank" type="double"/>` + "\n")
+	}
+	x.raw("    </attributes>\n    <nodes>\n")
+	for v := int32(0); int(v) < g.NumNodes(); v++ {
+		ns, _ := wikitext.SplitNamespace(g.title(v))
+		x.raw(`      <node id="`).int(g.PageID(v)).raw(`" label="`).text(g.title(v)).raw(`"><attvalues>`)
+		x.raw(`<attvalue for="namespace" value="`).text(ns).raw(`"/>`)
+		if pagerank != nil {
+			x.raw(`<attvalue for="pagerank" value="`).float(pagerank[v]).raw(`"/>`)
+		}
+		x.raw("</attvalues></node>\n")
+	}
+	x.raw("    </nodes>\n    <edges>\n")
+	var id int64
+	for v := int32(0); int(v) < g.NumNodes(); v++ {
+		for _, u := range g.Out(v) {
+			x.raw(`      <edge id="`).int64(id).raw(`" source="`).int(g.PageID(v)).raw(`" target="`).int(g.PageID(u)).raw("\"/>\n")
+			id++
+		}
+	}
+	x.raw("    </edges>\n  </graph>\n</gexf>\n")
+	return x.flush()
+}
+
+// WriteNeo4j writes a graph as the node and relationship CSV files of
+// neo4j-admin import. Nodes have the label Page, and links are
+// relationships of the type LINKS_TO.
+func WriteNeo4j(nodes, relationships io.Writer, g *Graph, pagerank []float64) error {
+	cw := csv.NewWriter(nodes)
+	header := []string{"pageId:ID", "title", "namespace", ":LABEL"}
+	if pagerank != nil {
+		header = append(header, "pagerank:double")
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(header))
+	for v := int32(0); int(v) < g.NumNodes(); v++ {
+		title := g.Title(v)
+		ns, _ := wikitext.SplitNamespace(title)
+		record[0] = strconv.Itoa(int(g.PageID(v)))
+		record[1] = title
+		record[2] = ns
+		record[3] = "Page"
+		if pagerank != nil {
+			record[4] = strconv.FormatFloat(pagerank[v], 'g', -1, 64)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	cw = csv.NewWriter(relationships)
+	if err := cw.Write([]string{":START_ID", ":END_ID", ":TYPE"}); err != nil {
+		return err
+	}
+	record = []string{"", "", "LINKS_TO"}
+	for v := int32(0); int(v) < g.NumNodes(); v++ {
+		record[0] = strconv.Itoa(int(g.PageID(v)))
+		for _, u := range g.Out(v) {
+			record[1] = strconv.Itoa(int(g.PageID(u)))
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// xmlWriter writes XML and keeps the first error, so that documents can
+// be written without checking each write.
+type xmlWriter struct {
+	w   *bufio.Writer
+	buf []byte
+	err error
+}
+
+func (x *xmlWriter) raw(s string) *xmlWriter {
+	if x.err == nil {
+		_, x.err = x.w.WriteString(s)
+	}
+	return x
+}
+
+// text writes escaped character data, which is also valid in attributes.
+func (x *xmlWriter) text(s string) *xmlWriter {
+	if x.err == nil {
+		x.err = xml.EscapeText(x.w, []byte(s))
+	}
+	return x
+}
+
+func (x *xmlWriter) int(n int32) *xmlWriter {
+	return x.int64(int64(n))
+}
+
+func (x *xmlWriter) int64(n int64) *xmlWriter {
+	x.buf = strconv.AppendInt(x.buf[:0], n, 10)
+	if x.err == nil {
+		_, x.err = x.w.Write(x.buf)
+	}
+	return x
+}
+
+func (x *xmlWriter) float(f float64) *xmlWriter {
+	x.buf = strconv.AppendFloat(x.buf[:0], f, 'g', -1, 64)
+	if x.err == nil {
+		_, x.err = x.w.Write(x.buf)
+	}
+	return x
+}
+
+func (x *xmlWriter) flush() error {
+	if x.err != nil {
+		return x.err
+	}
+	return x.w.Flush()
+}
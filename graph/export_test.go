@@ -0,0 +1,146 @@
+package graph_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/graph"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+func exportGraph(t *testing.T) *graph.Graph {
+	return buildGraph(t,
+		linktest.Page(1, "A & B", "Category:C", "D"),
+		linktest.Page(2, "Category:C", "A & B"),
+		linktest.Page(3, "D"),
+	)
+}
+
+func Test_WriteEdgeList(t *testing.T) {
+	g := exportGraph(t)
+	for _, tc := range []struct {
+		titles bool
+		want   string
+	}{
+		{false, "1\t2\n1\t3\n2\t1\n"},
+		{true, "A & B\tCategory:C\nA & B\tD\nCategory:C\tA & B\n"},
+	} {
+		var buf bytes.Buffer
+		if err := graph.WriteEdgeList(&buf, g, tc.titles); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); !cmp.Equal(tc.want, got) {
+			t.Errorf("invalid edge list with titles %v\n%v", tc.titles, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func Test_WriteNeo4j(t *testing.T) {
+	g := exportGraph(t)
+	var nodes, relationships bytes.Buffer
+	if err := graph.WriteNeo4j(&nodes, &relationships, g, []float64{0.5, 0.25, 0.25}); err != nil {
+		t.Fatal(err)
+	}
+	wantNodes := "pageId:ID,title,namespace,:LABEL,pagerank:double\n" +
+		"1,A & B,,Page,0.5\n" +
+		"2,Category:C,Category,Page,0.25\n" +
+		"3,D,,Page,0.25\n"
+	if !cmp.Equal(wantNodes, nodes.String()) {
+		t.Errorf("invalid nodes\n%v", cmp.Diff(wantNodes, nodes.String()))
+	}
+	wantRelationships := ":START_ID,:END_ID,:TYPE\n1,2,LINKS_TO\n1,3,LINKS_TO\n2,1,LINKS_TO\n"
+	if !cmp.Equal(wantRelationships, relationships.String()) {
+		t.Errorf("invalid relationships\n%v", cmp.Diff(wantRelationships, relationships.String()))
+	}
+}
+
+type xmlNode struct {
+	ID    string `xml:"id,attr"`
+	Label string `xml:"label,attr"`
+	Data  []struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"data"`
+	AttValues []struct {
+		For   string `xml:"for,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"attvalues>attvalue"`
+}
+
+type xmlEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+// exported is the contents of a GraphML or GEXF document.
+type exported struct {
+	Nodes []xmlNode `xml:"graph>node"`
+	Edges []xmlEdge `xml:"graph>edge"`
+
+	GEXFNodes []xmlNode `xml:"graph>nodes>node"`
+	GEXFEdges []xmlEdge `xml:"graph>edges>edge"`
+}
+
+func Test_WriteXML(t *testing.T) {
+	g := exportGraph(t)
+	pagerank := []float64{0.5, 0.25, 0.25}
+	wantEdges := []xmlEdge{{"1", "2"}, {"1", "3"}, {"2", "1"}}
+
+	var buf bytes.Buffer
+	if err := graph.WriteGraphML(&buf, g, pagerank); err != nil {
+		t.Fatal(err)
+	}
+	var got exported
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid GraphML: %v", err)
+	}
+	var nodes [][]string
+	for _, n := range got.Nodes {
+		node := []string{n.ID}
+		for _, d := range n.Data {
+			node = append(node, d.Key+"="+d.Value)
+		}
+		nodes = append(nodes, node)
+	}
+	wantNodes := [][]string{
+		{"1", "title=A & B", "namespace=", "pagerank=0.5"},
+		{"2", "title=Category:C", "namespace=Category", "pagerank=0.25"},
+		{"3", "title=D", "namespace=", "pagerank=0.25"},
+	}
+	if !cmp.Equal(wantNodes, nodes) {
+		t.Errorf("invalid GraphML nodes\n%v", cmp.Diff(wantNodes, nodes))
+	}
+	if !cmp.Equal(wantEdges, got.Edges) {
+		t.Errorf("invalid GraphML edges\n%v", cmp.Diff(wantEdges, got.Edges))
+	}
+
+	buf.Reset()
+	if err := graph.WriteGEXF(&buf, g, nil); err != nil {
+		t.Fatal(err)
+	}
+	got = exported{}
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid GEXF: %v", err)
+	}
+	nodes = nil
+	for _, n := range got.GEXFNodes {
+		node := []string{n.ID, n.Label}
+		for _, v := range n.AttValues {
+			node = append(node, v.For+"="+v.Value)
+		}
+		nodes = append(nodes, node)
+	}
+	wantNodes = [][]string{
+		{"1", "A & B", "namespace="},
+		{"2", "Category:C", "namespace=Category"},
+		{"3", "D", "namespace="},
+	}
+	if !cmp.Equal(wantNodes, nodes) {
+		t.Errorf("invalid GEXF nodes\n%v", cmp.Diff(wantNodes, nodes))
+	}
+	if !cmp.Equal(wantEdges, got.GEXFEdges) {
+		t.Errorf("invalid GEXF edges\n%v", cmp.Diff(wantEdges, got.GEXFEdges))
+	}
+}
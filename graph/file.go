@@ -0,0 +1,292 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// The graph file starts with a header, followed by the arrays of the
+// graph in the order of the sections below. All integers are stored in
+// little-endian byte order, and each array is padded to a multiple of
+// eight bytes, so that arrays are aligned when the file is mapped into
+// memory.
+//
+// Header:
+//
+//	magic       [8]byte  "WIKIGRPH"
+//	version     uint64
+//	nodes       uint64
+//	edges       uint64
+//	titleBytes  uint64
+const (
+	fileMagic   = "WIKIGRPH"
+	fileVersion = 1
+	headerSize  = 40
+)
+
+var errInvalidFile = errors.New("invalid graph file")
+
+// nativeLittleEndian is true if the byte order of the machine is little
+// endian, in which case arrays are used directly from the file.
+var nativeLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// WriteFile writes the graph to a file at the provided path, which can be
+// opened with Open.
+//
+// If the provided path already exists, an error is returned.
+func (g *Graph) WriteFile(path string) error {
+	f, err := os.OpenFile(path, os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if err := g.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (g *Graph) write(w io.Writer) error {
+	buf := bufio.NewWriterSize(w, 1<<20)
+	header := make([]byte, headerSize)
+	copy(header, fileMagic)
+	binary.LittleEndian.PutUint64(header[8:], fileVersion)
+	binary.LittleEndian.PutUint64(header[16:], uint64(len(g.ids)))
+	binary.LittleEndian.PutUint64(header[24:], uint64(len(g.out)))
+	binary.LittleEndian.PutUint64(header[32:], uint64(len(g.titles)))
+	if _, err := buf.Write(header); err != nil {
+		return err
+	}
+	for _, section := range []interface{}{
+		g.ids, g.outOffsets, g.out, g.inOffsets, g.in,
+		g.titleOffsets, g.byTitle, g.titles,
+	} {
+		if err := writeSection(buf, section); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// writeSection writes an array in little-endian byte order, padded to a
+// multiple of eight bytes.
+func writeSection(w *bufio.Writer, section interface{}) error {
+	var n int
+	switch s := section.(type) {
+	case []byte:
+		if _, err := w.Write(s); err != nil {
+			return err
+		}
+		n = len(s)
+	case []int32:
+		if nativeLittleEndian {
+			if _, err := w.Write(int32Bytes(s)); err != nil {
+				return err
+			}
+		} else {
+			var b [4]byte
+			for _, x := range s {
+				binary.LittleEndian.PutUint32(b[:], uint32(x))
+				if _, err := w.Write(b[:]); err != nil {
+					return err
+				}
+			}
+		}
+		n = 4 * len(s)
+	case []int64:
+		if nativeLittleEndian {
+			if _, err := w.Write(int64Bytes(s)); err != nil {
+				return err
+			}
+		} else {
+			var b [8]byte
+			for _, x := range s {
+				binary.LittleEndian.PutUint64(b[:], uint64(x))
+				if _, err := w.Write(b[:]); err != nil {
+					return err
+				}
+			}
+		}
+		n = 8 * len(s)
+	}
+	var pad [8]byte
+	_, err := w.Write(pad[:padding(n)])
+	return err
+}
+
+func padding(n int) int {
+	return (8 - n%8) % 8
+}
+
+// Open opens a graph file written by WriteFile. Where supported, the file
+// is memory mapped rather than read, so that its memory is shared with
+// other processes using the same file. The graph must be closed to release
+// the mapping.
+//
+// Only the header and the bounds of the sections are checked, so that the
+// arrays of the graph are not read when it is opened. A corrupt file can
+// cause a panic when the graph is used, unless it is checked with Verify.
+func Open(path string) (*Graph, error) {
+	data, unmap, err := mmapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := parseFile(data)
+	if err != nil {
+		unmap()
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	g.close = unmap
+	return g, nil
+}
+
+// parseFile returns the graph stored in the contents of a graph file. On
+// little-endian machines, the arrays of the graph refer to data directly.
+func parseFile(data []byte) (*Graph, error) {
+	if len(data) < headerSize || string(data[:8]) != fileMagic {
+		return nil, errInvalidFile
+	}
+	if v := binary.LittleEndian.Uint64(data[8:]); v != fileVersion {
+		return nil, fmt.Errorf("unsupported graph file version: %v", v)
+	}
+	nodes := binary.LittleEndian.Uint64(data[16:])
+	edges := binary.LittleEndian.Uint64(data[24:])
+	titleBytes := binary.LittleEndian.Uint64(data[32:])
+
+	// Sizes are checked before conversion to int, so that a corrupt
+	// header cannot cause an overflow
+	const maxSize = 1 << 40
+	if nodes > maxSize || edges > maxSize || titleBytes > maxSize {
+		return nil, errInvalidFile
+	}
+	n, m := int(nodes), int(edges)
+	sizes := []int{4 * n, 8 * (n + 1), 4 * m, 8 * (n + 1), 4 * m, 8 * (n + 1), 4 * n, int(titleBytes)}
+	total := headerSize
+	for _, size := range sizes {
+		total += size + padding(size)
+	}
+	if total != len(data) {
+		return nil, errInvalidFile
+	}
+
+	sections := make([][]byte, len(sizes))
+	off := headerSize
+	for i, size := range sizes {
+		sections[i] = data[off : off+size : off+size]
+		off += size + padding(size)
+	}
+	g := &Graph{
+		ids:          bytesInt32(sections[0]),
+		outOffsets:   bytesInt64(sections[1]),
+		out:          bytesInt32(sections[2]),
+		inOffsets:    bytesInt64(sections[3]),
+		in:           bytesInt32(sections[4]),
+		titleOffsets: bytesInt64(sections[5]),
+		byTitle:      bytesInt32(sections[6]),
+		titles:       sections[7],
+	}
+	for _, offsets := range [][]int64{g.outOffsets, g.inOffsets} {
+		if offsets[0] != 0 || offsets[n] != int64(m) {
+			return nil, errInvalidFile
+		}
+	}
+	if g.titleOffsets[0] != 0 || g.titleOffsets[n] != int64(titleBytes) {
+		return nil, errInvalidFile
+	}
+	return g, nil
+}
+
+// Verify checks that the arrays of the graph are consistent, i.e. that
+// offsets never decrease, that links and titles refer to nodes of the
+// graph and that nodes are sorted by page ID and title. Verify reads all
+// arrays of the graph, and is meant to check a graph opened with Open,
+// which does not read them, before it is used.
+func (g *Graph) Verify() error {
+	n := len(g.ids)
+	for _, offsets := range [][]int64{g.outOffsets, g.inOffsets, g.titleOffsets} {
+		for i := 1; i < len(offsets); i++ {
+			if offsets[i] < offsets[i-1] {
+				return errors.New("invalid graph: offsets decrease")
+			}
+		}
+	}
+	for _, nodes := range [][]int32{g.out, g.in, g.byTitle} {
+		for _, x := range nodes {
+			if x < 0 || int(x) >= n {
+				return fmt.Errorf("invalid graph: node %v is out of range", x)
+			}
+		}
+	}
+	for i := 1; i < n; i++ {
+		if g.ids[i] <= g.ids[i-1] {
+			return errors.New("invalid graph: page IDs are not sorted")
+		}
+		if g.title(g.byTitle[i]) < g.title(g.byTitle[i-1]) {
+			return errors.New("invalid graph: titles are not sorted")
+		}
+	}
+	return nil
+}
+
+// bytesInt32 returns little-endian encoded data as an []int32. On
+// little-endian machines, the result refers to data.
+func bytesInt32(data []byte) []int32 {
+	n := len(data) / 4
+	if !nativeLittleEndian {
+		res := make([]int32, n)
+		for i := range res {
+			res[i] = int32(binary.LittleEndian.Uint32(data[4*i:]))
+		}
+		return res
+	}
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*int32)(unsafe.Pointer(&data[0])), n)
+}
+
+// bytesInt64 returns little-endian encoded data as an []int64. On
+// little-endian machines, the result refers to data.
+func bytesInt64(data []byte) []int64 {
+	n := len(data) / 8
+	if !nativeLittleEndian {
+		res := make([]int64, n)
+		for i := range res {
+			res[i] = int64(binary.LittleEndian.Uint64(data[8*i:]))
+		}
+		return res
+	}
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*int64)(unsafe.Pointer(&data[0])), n)
+}
+
+// int32Bytes returns the memory of s as a []byte.
+func int32Bytes(s []int32) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), 4*len(s))
+}
+
+// int64Bytes returns the memory of s as a []byte.
+func int64Bytes(s []int64) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), 8*len(s))
+}
+
+// unsafeString returns b as a string without copying it.
+func unsafeString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
@@ -0,0 +1,259 @@
+// Package graph stores the link graph of Wikipedia in a compact form.
+//
+// Pages are mapped to dense node IDs in the order of their page IDs, and
+// the outgoing and incoming links of each node are stored in compressed
+// sparse row (CSR) form. Graphs can be written to a file which is memory
+// mapped when opened, so that large graphs load instantly.
+package graph
+
+import (
+	"bytes"
+	"io"
+	"sort"
+
+	"github.com/sebnyberg/wikipedia"
+)
+
+// Graph is a directed graph of pages and the links between them.
+//
+// Nodes are numbered from zero to NumNodes()-1. Links to titles which are
+// not pages of the graph are not stored.
+type Graph struct {
+	// ids contains the page ID of each node, in ascending order.
+	ids []int32
+
+	// The outgoing links of node n are out[outOffsets[n]:outOffsets[n+1]],
+	// and the incoming links in[inOffsets[n]:inOffsets[n+1]]. Both lists
+	// are sorted.
+	outOffsets []int64
+	out        []int32
+	inOffsets  []int64
+	in         []int32
+
+	// The title of node n is titles[titleOffsets[n]:titleOffsets[n+1]].
+	// byTitle contains the nodes sorted by title.
+	titleOffsets []int64
+	titles       []byte
+	byTitle      []int32
+
+	// close releases the memory of a graph opened from a file.
+	close func() error
+}
+
+// Build reads all pages from r and returns their link graph. If multiple
+// pages share an ID, the first one read is used. If multiple pages share a
+// title, links to the title go to the page with the lowest ID.
+func Build(r wikipedia.LinkedPageReader) (*Graph, error) {
+	b := newBuilder()
+	for {
+		p, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				return b.graph(), nil
+			}
+			return nil, err
+		}
+		b.add(p)
+	}
+}
+
+// NumNodes returns the number of nodes in the graph.
+func (g *Graph) NumNodes() int {
+	return len(g.ids)
+}
+
+// NumEdges returns the number of edges in the graph.
+func (g *Graph) NumEdges() int {
+	return len(g.out)
+}
+
+// Node returns the node of the page with the provided ID.
+func (g *Graph) Node(pageID int32) (int32, bool) {
+	i := sort.Search(len(g.ids), func(i int) bool {
+		return g.ids[i] >= pageID
+	})
+	if i == len(g.ids) || g.ids[i] != pageID {
+		return -1, false
+	}
+	return int32(i), true
+}
+
+// NodeByTitle returns the node of the page with the provided title.
+func (g *Graph) NodeByTitle(title string) (int32, bool) {
+	i := sort.Search(len(g.byTitle), func(i int) bool {
+		return g.title(g.byTitle[i]) >= title
+	})
+	if i == len(g.byTitle) || g.title(g.byTitle[i]) != title {
+		return -1, false
+	}
+	return g.byTitle[i], true
+}
+
+// PageID returns the page ID of a node.
+func (g *Graph) PageID(n int32) int32 {
+	return g.ids[n]
+}
+
+// Title returns the title of a node.
+func (g *Graph) Title(n int32) string {
+	return string(g.titles[g.titleOffsets[n]:g.titleOffsets[n+1]])
+}
+
+// title returns the title of a node without copying it. The result must
+// not be retained.
+func (g *Graph) title(n int32) string {
+	b := g.titles[g.titleOffsets[n]:g.titleOffsets[n+1]]
+	return unsafeString(b)
+}
+
+// Out returns the nodes linked from a node, in ascending order. The
+// returned slice must not be modified.
+func (g *Graph) Out(n int32) []int32 {
+	return g.out[g.outOffsets[n]:g.outOffsets[n+1]]
+}
+
+// In returns the nodes linking to a node, in ascending order. The
+// returned slice must not be modified.
+func (g *Graph) In(n int32) []int32 {
+	return g.in[g.inOffsets[n]:g.inOffsets[n+1]]
+}
+
+// OutDegree returns the number of nodes linked from a node.
+func (g *Graph) OutDegree(n int32) int {
+	return int(g.outOffsets[n+1] - g.outOffsets[n])
+}
+
+// InDegree returns the number of nodes linking to a node.
+func (g *Graph) InDegree(n int32) int {
+	return int(g.inOffsets[n+1] - g.inOffsets[n])
+}
+
+// Close releases the resources of a graph opened with Open. Graphs must
+// not be used after they have been closed.
+func (g *Graph) Close() error {
+	if g.close == nil {
+		return nil
+	}
+	err := g.close()
+	g.close = nil
+	return err
+}
+
+type builderPage struct {
+	id    int32
+	title int32
+	links []int32
+}
+
+// builder collects pages and interns their titles, so that each distinct
+// title is held in memory once regardless of how often it is linked.
+type builder struct {
+	titleIDs map[string]int32
+	pageIDs  map[int32]bool
+	pages    []builderPage
+}
+
+func newBuilder() *builder {
+	return &builder{
+		titleIDs: make(map[string]int32),
+		pageIDs:  make(map[int32]bool),
+	}
+}
+
+func (b *builder) intern(title string) int32 {
+	id, ok := b.titleIDs[title]
+	if !ok {
+		id = int32(len(b.titleIDs))
+		b.titleIDs[title] = id
+	}
+	return id
+}
+
+func (b *builder) add(p *wikipedia.LinkedPage) {
+	if b.pageIDs[p.PageId] {
+		return
+	}
+	b.pageIDs[p.PageId] = true
+	links := make([]int32, len(p.Links))
+	for i, link := range p.Links {
+		links[i] = b.intern(link.TargetTitle)
+	}
+	b.pages = append(b.pages, builderPage{
+		id:    p.PageId,
+		title: b.intern(p.PageTitle),
+		links: links,
+	})
+}
+
+func (b *builder) graph() *Graph {
+	sort.Slice(b.pages, func(i, j int) bool {
+		return b.pages[i].id < b.pages[j].id
+	})
+	n := len(b.pages)
+
+	// Map interned titles to nodes
+	nodes := make([]int32, len(b.titleIDs))
+	for i := range nodes {
+		nodes[i] = -1
+	}
+	for i, p := range b.pages {
+		if nodes[p.title] == -1 {
+			nodes[p.title] = int32(i)
+		}
+	}
+	names := make([]string, len(b.titleIDs))
+	for title, id := range b.titleIDs {
+		names[id] = title
+	}
+	b.titleIDs = nil
+
+	g := &Graph{
+		ids:          make([]int32, n),
+		outOffsets:   make([]int64, n+1),
+		inOffsets:    make([]int64, n+1),
+		titleOffsets: make([]int64, n+1),
+		byTitle:      make([]int32, n),
+	}
+	var titles bytes.Buffer
+	for i, p := range b.pages {
+		g.ids[i] = p.id
+		titles.WriteString(names[p.title])
+		g.titleOffsets[i+1] = int64(titles.Len())
+		g.byTitle[i] = int32(i)
+
+		links := p.links[:0]
+		for _, link := range p.links {
+			if node := nodes[link]; node != -1 {
+				links = append(links, node)
+			}
+		}
+		sort.Slice(links, func(i, j int) bool { return links[i] < links[j] })
+		for j, link := range links {
+			if j == 0 || link != links[j-1] {
+				g.out = append(g.out, link)
+				g.inOffsets[link+1]++
+			}
+		}
+		g.outOffsets[i+1] = int64(len(g.out))
+		b.pages[i].links = nil
+	}
+	g.titles = titles.Bytes()
+	sort.SliceStable(g.byTitle, func(i, j int) bool {
+		return g.title(g.byTitle[i]) < g.title(g.byTitle[j])
+	})
+
+	// Sources are visited in order, so incoming links end up sorted
+	for i := 0; i < n; i++ {
+		g.inOffsets[i+1] += g.inOffsets[i]
+	}
+	g.in = make([]int32, len(g.out))
+	next := make([]int64, n)
+	copy(next, g.inOffsets[:n])
+	for src := int32(0); int(src) < n; src++ {
+		for _, dst := range g.Out(src) {
+			g.in[next[dst]] = src
+			next[dst]++
+		}
+	}
+	return g
+}
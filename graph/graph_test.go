@@ -0,0 +1,210 @@
+package graph_test
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/graph"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+// buildGraph returns the graph of the provided pages.
+func buildGraph(t *testing.T, pages ...*wikipedia.LinkedPage) *graph.Graph {
+	r := linktest.Reader(pages)
+	g, err := graph.Build(&r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+type nodeInfo struct {
+	ID    int32
+	Title string
+	Out   []int32
+	In    []int32
+}
+
+// dump returns the contents of a graph.
+func dump(g *graph.Graph) []nodeInfo {
+	var res []nodeInfo
+	for n := int32(0); int(n) < g.NumNodes(); n++ {
+		res = append(res, nodeInfo{
+			ID:    g.PageID(n),
+			Title: g.Title(n),
+			Out:   append([]int32(nil), g.Out(n)...),
+			In:    append([]int32(nil), g.In(n)...),
+		})
+	}
+	return res
+}
+
+func Test_Build(t *testing.T) {
+	g := buildGraph(t,
+		linktest.Page(30, "C", "A", "Missing", "A", "B"),
+		linktest.Page(10, "A", "B"),
+		linktest.Page(20, "B", "C", "B"),
+		linktest.Page(10, "Duplicate", "C"),
+	)
+	want := []nodeInfo{
+		{10, "A", []int32{1}, []int32{2}},
+		{20, "B", []int32{1, 2}, []int32{0, 1, 2}},
+		{30, "C", []int32{0, 1}, []int32{1}},
+	}
+	if got := dump(g); !cmp.Equal(want, got) {
+		t.Errorf("invalid graph\n%v", cmp.Diff(want, got))
+	}
+	if g.NumEdges() != 5 {
+		t.Errorf("invalid number of edges, expected: 5, got: %v", g.NumEdges())
+	}
+	if n, ok := g.Node(20); !ok || n != 1 {
+		t.Errorf("invalid node of page 20, expected: 1, true, got: %v, %v", n, ok)
+	}
+	if _, ok := g.Node(15); ok {
+		t.Error("Node(15) should not exist")
+	}
+	if n, ok := g.NodeByTitle("C"); !ok || n != 2 {
+		t.Errorf("invalid node of title C, expected: 2, true, got: %v, %v", n, ok)
+	}
+	if _, ok := g.NodeByTitle("Missing"); ok {
+		t.Error("NodeByTitle(Missing) should not exist")
+	}
+}
+
+func Test_WriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		name  string
+		pages []*wikipedia.LinkedPage
+	}{
+		{"empty", nil},
+		{"pages", []*wikipedia.LinkedPage{
+			linktest.Page(3, "Stockholm", "Sweden", "Uppsala"),
+			linktest.Page(1, "Sweden", "Stockholm"),
+			linktest.Page(2, "Uppsala", "Sweden", "Stockholm"),
+			linktest.Page(4, "Orphan"),
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := buildGraph(t, tc.pages...)
+			path := filepath.Join(dir, tc.name+".graph")
+			if err := g.WriteFile(path); err != nil {
+				t.Fatal(err)
+			}
+			opened, err := graph.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer opened.Close()
+			if want, got := dump(g), dump(opened); !cmp.Equal(want, got) {
+				t.Errorf("invalid opened graph\n%v", cmp.Diff(want, got))
+			}
+			for n := int32(0); int(n) < g.NumNodes(); n++ {
+				if got, ok := opened.NodeByTitle(g.Title(n)); !ok || got != n {
+					t.Errorf("invalid node of title %q, expected: %v, true, got: %v, %v", g.Title(n), n, got, ok)
+				}
+			}
+		})
+	}
+
+	path := filepath.Join(dir, "invalid.graph")
+	if err := ioutil.WriteFile(path, []byte("not a graph"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := graph.Open(path); err == nil {
+		t.Error("expected an error when opening an invalid file")
+	}
+}
+
+func Test_Verify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	g := buildGraph(t,
+		linktest.Page(1, "Sweden", "Stockholm"),
+		linktest.Page(2, "Uppsala", "Sweden", "Stockholm"),
+		linktest.Page(3, "Stockholm", "Sweden", "Uppsala"),
+		linktest.Page(4, "Orphan"),
+	)
+	if err := g.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "valid.graph")
+	if err := g.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Positions of the sections of the file, see graph/file.go
+	n, m := g.NumNodes(), g.NumEdges()
+	pad := func(size int) int { return (size + 7) / 8 * 8 }
+	ids := 40
+	outOffsets := ids + pad(4*n)
+	out := outOffsets + 8*(n+1)
+	inOffsets := out + pad(4*m)
+	in := inOffsets + 8*(n+1)
+	titleOffsets := in + pad(4*m)
+	byTitle := titleOffsets + 8*(n+1)
+
+	for _, tc := range []struct {
+		name     string
+		pos      int
+		value    uint64
+		size     int
+		openFail bool
+	}{
+		{"last out offset", outOffsets + 8*n, uint64(m + 1), 8, true},
+		{"first title offset", titleOffsets, 1, 8, true},
+		{"decreasing out offset", outOffsets + 8, uint64(m), 8, false},
+		{"decreasing in offset", inOffsets + 8, uint64(m), 8, false},
+		{"decreasing title offset", titleOffsets + 8, 1 << 20, 8, false},
+		{"out of range link", out, uint64(n), 4, false},
+		{"negative link", in, 1<<32 - 1, 4, false},
+		{"out of range title node", byTitle, uint64(n), 4, false},
+		{"unsorted page IDs", ids, 100, 4, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			corrupt := append([]byte(nil), data...)
+			if tc.size == 8 {
+				binary.LittleEndian.PutUint64(corrupt[tc.pos:], tc.value)
+			} else {
+				binary.LittleEndian.PutUint32(corrupt[tc.pos:], uint32(tc.value))
+			}
+			path := filepath.Join(dir, tc.name+".graph")
+			if err := ioutil.WriteFile(path, corrupt, 0644); err != nil {
+				t.Fatal(err)
+			}
+			g, err := graph.Open(path)
+			if tc.openFail {
+				if err == nil {
+					g.Close()
+					t.Error("expected an error when opening the file")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer g.Close()
+			if err := g.Verify(); err == nil {
+				t.Error("expected an error when verifying the graph")
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"math"
+	"runtime"
+	"sort"
+)
+
+// HITSOptions configures HITS. Zero values are replaced by their
+// defaults.
+type HITSOptions struct {
+	// Tolerance is the L1 norm of the change in hub and authority scores
+	// at which the iteration stops. Defaults to 1e-6.
+	Tolerance float64
+
+	// MaxIterations is the maximum number of iterations. Defaults to 100.
+	MaxIterations int
+
+	// Workers is the number of goroutines used to compute the scores.
+	// Defaults to GOMAXPROCS.
+	Workers int
+
+	// Nodes restricts the scores to the subgraph of the provided nodes
+	// and the links between them, such as the Neighborhood of a seed set.
+	// Nodes may be repeated and unordered. If nil, the whole graph is used.
+	Nodes []int32
+}
+
+// HITS computes the hub and authority scores of each node with
+// Kleinberg's Hyperlink-Induced Topic Search. Good hubs link to good
+// authorities, and good authorities are linked from good hubs. The scores
+// are indexed by node, and each sum to one over the nodes of the
+// subgraph. Nodes outside of the subgraph have a score of zero. The number
+// of iterations run is returned along with the scores.
+func HITS(g *Graph, opts HITSOptions) (hubs, authorities []float64, iterations int) {
+	if opts.Tolerance == 0 {
+		opts.Tolerance = 1e-6
+	}
+	if opts.MaxIterations == 0 {
+		opts.MaxIterations = 100
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	n := g.NumNodes()
+	if n == 0 {
+		return nil, nil, 0
+	}
+
+	var nodes []int32
+	var member []bool
+	if opts.Nodes == nil {
+		nodes = make([]int32, n)
+		for i := range nodes {
+			nodes[i] = int32(i)
+		}
+	} else {
+		// Nodes are collected from the membership, so that each node is
+		// scored once, in order
+		member = make([]bool, n)
+		for _, v := range opts.Nodes {
+			member[v] = true
+		}
+		nodes = make([]int32, 0, len(opts.Nodes))
+		for v, ok := range member {
+			if ok {
+				nodes = append(nodes, int32(v))
+			}
+		}
+	}
+	m := len(nodes)
+	hubs = make([]float64, n)
+	authorities = make([]float64, n)
+	next := make([]float64, n)
+	for _, v := range nodes {
+		hubs[v] = 1 / float64(m)
+	}
+
+	// update sets the scores of each node to the sum of the scores of its
+	// neighbours in the subgraph, normalizes them and returns the change
+	update := func(scores, from []float64, neighbours func(int32) []int32) float64 {
+		sum := parallelSum(m, opts.Workers, func(start, end int) float64 {
+			var sum float64
+			for _, v := range nodes[start:end] {
+				var s float64
+				for _, u := range neighbours(v) {
+					if member == nil || member[u] {
+						s += from[u]
+					}
+				}
+				next[v] = s
+				sum += s
+			}
+			return sum
+		})
+		if sum == 0 {
+			sum = 1
+		}
+		return parallelSum(m, opts.Workers, func(start, end int) float64 {
+			var delta float64
+			for _, v := range nodes[start:end] {
+				s := next[v] / sum
+				delta += math.Abs(s - scores[v])
+				scores[v] = s
+			}
+			return delta
+		})
+	}
+
+	for iterations < opts.MaxIterations {
+		iterations++
+		delta := update(authorities, hubs, g.In)
+		delta += update(hubs, authorities, g.Out)
+		if delta < opts.Tolerance {
+			break
+		}
+	}
+	return hubs, authorities, iterations
+}
+
+// Neighborhood returns the base set of a query-focused subgraph in HITS:
+// the seed nodes, the nodes they link to, and the nodes linking to them,
+// in ascending order. At most maxIn nodes linking to each seed are added,
+// those with the lowest node IDs, or all of them if maxIn is negative.
+func Neighborhood(g *Graph, seeds []int32, maxIn int) []int32 {
+	seen := make(map[int32]bool)
+	var res []int32
+	add := func(nodes []int32) {
+		for _, v := range nodes {
+			if !seen[v] {
+				seen[v] = true
+				res = append(res, v)
+			}
+		}
+	}
+	add(seeds)
+	for _, v := range seeds {
+		add(g.Out(v))
+		in := g.In(v)
+		if maxIn >= 0 && len(in) > maxIn {
+			in = in[:maxIn]
+		}
+		add(in)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
@@ -0,0 +1,61 @@
+package graph_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/graph"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+func Test_HITS(t *testing.T) {
+	// Two lists link to three topics, and the first list is linked from a
+	// page which links nowhere else
+	g := buildGraph(t,
+		linktest.Page(1, "List 1", "Topic A", "Topic B", "Topic C"),
+		linktest.Page(2, "List 2", "Topic A", "Topic B", "Topic C"),
+		linktest.Page(3, "Topic A"),
+		linktest.Page(4, "Topic B"),
+		linktest.Page(5, "Topic C"),
+		linktest.Page(6, "Other", "List 1"),
+		linktest.Page(7, "Unrelated", "Other"),
+	)
+	opt := cmp.Comparer(func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-6
+	})
+
+	hubs, authorities, _ := graph.HITS(g, graph.HITSOptions{Tolerance: 1e-12})
+	if !cmp.Equal([]float64{0.5, 0.5, 0, 0, 0, 0, 0}, hubs, opt) {
+		t.Errorf("invalid hubs\n%v", cmp.Diff([]float64{0.5, 0.5, 0, 0, 0, 0, 0}, hubs, opt))
+	}
+	if !cmp.Equal([]float64{0, 0, 1.0 / 3, 1.0 / 3, 1.0 / 3, 0, 0}, authorities, opt) {
+		t.Errorf("invalid authorities\n%v", cmp.Diff([]float64{0, 0, 1.0 / 3, 1.0 / 3, 1.0 / 3, 0, 0}, authorities, opt))
+	}
+
+	// Around the first list, the topics and the page linking to the list
+	nodes := graph.Neighborhood(g, []int32{0}, -1)
+	if !cmp.Equal([]int32{0, 2, 3, 4, 5}, nodes) {
+		t.Errorf("invalid neighborhood\n%v", cmp.Diff([]int32{0, 2, 3, 4, 5}, nodes))
+	}
+	if got := graph.Neighborhood(g, []int32{3}, 1); !cmp.Equal([]int32{0, 3}, got) {
+		t.Errorf("invalid neighborhood with one incoming link, expected: [0 3], got: %v", got)
+	}
+	hubs, authorities, _ = graph.HITS(g, graph.HITSOptions{Tolerance: 1e-12, Nodes: nodes})
+	if !cmp.Equal([]float64{1, 0, 0, 0, 0, 0, 0}, hubs, opt) {
+		t.Errorf("invalid subgraph hubs\n%v", cmp.Diff([]float64{1, 0, 0, 0, 0, 0, 0}, hubs, opt))
+	}
+	if !cmp.Equal([]float64{0, 0, 1.0 / 3, 1.0 / 3, 1.0 / 3, 0, 0}, authorities, opt) {
+		t.Errorf("invalid subgraph authorities\n%v", cmp.Diff([]float64{0, 0, 1.0 / 3, 1.0 / 3, 1.0 / 3, 0, 0}, authorities, opt))
+	}
+
+	// Repeated nodes are scored once
+	repeated := []int32{5, 0, 2, 3, 4, 0, 5, 2}
+	repHubs, repAuthorities, _ := graph.HITS(g, graph.HITSOptions{Tolerance: 1e-12, Nodes: repeated})
+	if !cmp.Equal(hubs, repHubs, opt) {
+		t.Errorf("invalid hubs for repeated nodes\n%v", cmp.Diff(hubs, repHubs, opt))
+	}
+	if !cmp.Equal(authorities, repAuthorities, opt) {
+		t.Errorf("invalid authorities for repeated nodes\n%v", cmp.Diff(authorities, repAuthorities, opt))
+	}
+}
@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+// PageRankOptions configures PageRank. Zero values are replaced by their
+// defaults.
+type PageRankOptions struct {
+	// Damping is the probability of following a link rather than jumping
+	// to a random page. Defaults to 0.85.
+	Damping float64
+
+	// Tolerance is the L1 norm of the change in scores at which the
+	// iteration stops. Defaults to 1e-6.
+	Tolerance float64
+
+	// MaxIterations is the maximum number of iterations. Defaults to 100.
+	MaxIterations int
+
+	// Workers is the number of goroutines used to compute the scores.
+	// Defaults to GOMAXPROCS.
+	Workers int
+}
+
+// PageRank computes the PageRank of each node by power iteration, and
+// returns the scores indexed by node, which sum to one, along with the
+// number of iterations run.
+//
+// The scores of dangling nodes, i.e. nodes without outgoing links, are
+// distributed evenly over all nodes, as if they linked to every page.
+func PageRank(g *Graph, opts PageRankOptions) ([]float64, int) {
+	if opts.Damping == 0 {
+		opts.Damping = 0.85
+	}
+	if opts.Tolerance == 0 {
+		opts.Tolerance = 1e-6
+	}
+	if opts.MaxIterations == 0 {
+		opts.MaxIterations = 100
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	n := g.NumNodes()
+	if n == 0 {
+		return nil, 0
+	}
+
+	rank := make([]float64, n)
+	next := make([]float64, n)
+	contrib := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	d := opts.Damping
+	var iter int
+	for iter < opts.MaxIterations {
+		iter++
+
+		// Each node contributes its score divided evenly over its links
+		dangling := parallelSum(n, opts.Workers, func(start, end int) float64 {
+			var sum float64
+			for v := start; v < end; v++ {
+				if deg := g.OutDegree(int32(v)); deg > 0 {
+					contrib[v] = rank[v] / float64(deg)
+				} else {
+					contrib[v] = 0
+					sum += rank[v]
+				}
+			}
+			return sum
+		})
+
+		base := (1-d)/float64(n) + d*dangling/float64(n)
+		delta := parallelSum(n, opts.Workers, func(start, end int) float64 {
+			var delta float64
+			for v := start; v < end; v++ {
+				var sum float64
+				for _, u := range g.In(int32(v)) {
+					sum += contrib[u]
+				}
+				next[v] = base + d*sum
+				delta += math.Abs(next[v] - rank[v])
+			}
+			return delta
+		})
+		rank, next = next, rank
+		if delta < opts.Tolerance {
+			break
+		}
+	}
+	return rank, iter
+}
+
+// parallelSum splits the range [0, n) into chunks processed by the
+// provided number of workers, and returns the sum of the results of f.
+// Results are summed in the order of the chunks, so that the sum does not
+// depend on scheduling.
+func parallelSum(n, workers int, f func(start, end int) float64) float64 {
+	const chunkSize = 4096
+	sums := make([]float64, (n+chunkSize-1)/chunkSize)
+	chunks := make(chan int, len(sums))
+	for i := range sums {
+		chunks <- i
+	}
+	close(chunks)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(sums); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				end := (chunk + 1) * chunkSize
+				if end > n {
+					end = n
+				}
+				sums[chunk] = f(chunk*chunkSize, end)
+			}
+		}()
+	}
+	wg.Wait()
+	var sum float64
+	for _, s := range sums {
+		sum += s
+	}
+	return sum
+}
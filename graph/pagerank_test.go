@@ -0,0 +1,76 @@
+package graph_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/graph"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+func Test_PageRank(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		pages []*wikipedia.LinkedPage
+		want  []float64
+	}{
+		{"cycle", []*wikipedia.LinkedPage{
+			linktest.Page(1, "A", "B"),
+			linktest.Page(2, "B", "C"),
+			linktest.Page(3, "C", "A"),
+		}, []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}},
+		{"dangling", []*wikipedia.LinkedPage{
+			linktest.Page(1, "A", "B"),
+			linktest.Page(2, "B"),
+		}, []float64{0.5 / 1.425, 1 - 0.5/1.425}},
+		{"no links", []*wikipedia.LinkedPage{
+			linktest.Page(1, "A"),
+			linktest.Page(2, "B"),
+		}, []float64{0.5, 0.5}},
+		{"empty", nil, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := buildGraph(t, tc.pages...)
+			got, _ := graph.PageRank(g, graph.PageRankOptions{Tolerance: 1e-12})
+			opt := cmp.Comparer(func(a, b float64) bool {
+				return math.Abs(a-b) < 1e-9
+			})
+			if !cmp.Equal(tc.want, got, opt) {
+				t.Errorf("invalid pagerank\n%v", cmp.Diff(tc.want, got, opt))
+			}
+		})
+	}
+}
+
+func Test_PageRankWorkers(t *testing.T) {
+	// Enough nodes to be split into several chunks
+	const n = 10000
+	var pages []*wikipedia.LinkedPage
+	for i := 0; i < n; i++ {
+		var links []string
+		if i%7 != 0 {
+			links = append(links, strconv.Itoa((i+1)%n), strconv.Itoa(i*i%n))
+		}
+		pages = append(pages, linktest.Page(int32(i+1), strconv.Itoa(i), links...))
+	}
+	g := buildGraph(t, pages...)
+
+	want, _ := graph.PageRank(g, graph.PageRankOptions{Workers: 1})
+	got, iter := graph.PageRank(g, graph.PageRankOptions{Workers: 8})
+	if !cmp.Equal(want, got) {
+		t.Errorf("results differ between worker counts\n%v", cmp.Diff(want, got))
+	}
+	if iter < 2 {
+		t.Errorf("invalid number of iterations, expected: several, got: %v", iter)
+	}
+	var sum float64
+	for _, score := range got {
+		sum += score
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("invalid sum of scores, expected: 1, got: %v", sum)
+	}
+}
@@ -0,0 +1,154 @@
+package graph
+
+// PathOptions configures shortest path searches.
+type PathOptions struct {
+	// Allow reports whether a path may pass through a node. The start and
+	// end of a path are always allowed. If nil, all nodes are allowed.
+	Allow func(n int32) bool
+
+	// MaxPaths is the maximum number of paths returned by ShortestPaths,
+	// zero for no limit.
+	MaxPaths int
+}
+
+// ShortestPath returns a shortest path of links from one node to another,
+// including both ends, or nil if there is no such path.
+func ShortestPath(g *Graph, from, to int32, opts PathOptions) []int32 {
+	opts.MaxPaths = 1
+	paths := ShortestPaths(g, from, to, opts)
+	if len(paths) == 0 {
+		return nil
+	}
+	return paths[0]
+}
+
+// ShortestPaths returns the shortest paths of links from one node to
+// another, including both ends, or nil if there is no such path.
+//
+// Paths are found by a breadth-first search from both ends, which expands
+// the side with the smallest frontier, so that only a fraction of the
+// nodes visited by a one-sided search are visited.
+func ShortestPaths(g *Graph, from, to int32, opts PathOptions) [][]int32 {
+	if from == to {
+		return [][]int32{{from}}
+	}
+	fwd := newSearch(from, g.Out)
+	bwd := newSearch(to, g.In)
+	allow := func(n int32) bool {
+		return n == from || n == to || opts.Allow == nil || opts.Allow(n)
+	}
+
+	for len(fwd.frontier) > 0 && len(bwd.frontier) > 0 {
+		s, other := fwd, bwd
+		if len(bwd.frontier) < len(fwd.frontier) {
+			s, other = bwd, fwd
+		}
+		s.expand(allow)
+
+		// Paths through the new frontier are the shortest ones, but they
+		// may differ in length depending on the distance to the other end
+		var meets []int32
+		best := -1
+		for _, n := range s.frontier {
+			d, ok := other.dist[n]
+			if !ok {
+				continue
+			}
+			switch {
+			case best == -1 || d < int32(best):
+				best = int(d)
+				meets = []int32{n}
+			case d == int32(best):
+				meets = append(meets, n)
+			}
+		}
+		if len(meets) > 0 {
+			return collectPaths(fwd, bwd, meets, opts.MaxPaths)
+		}
+	}
+	return nil
+}
+
+// search is one side of a bidirectional breadth-first search.
+type search struct {
+	next     func(n int32) []int32
+	dist     map[int32]int32
+	parents  map[int32][]int32
+	frontier []int32
+}
+
+func newSearch(start int32, next func(n int32) []int32) *search {
+	return &search{
+		next:     next,
+		dist:     map[int32]int32{start: 0},
+		parents:  make(map[int32][]int32),
+		frontier: []int32{start},
+	}
+}
+
+// expand visits the neighbours of the frontier, which become the new
+// frontier. All parents of each new node in the previous frontier are
+// recorded.
+func (s *search) expand(allow func(n int32) bool) {
+	var frontier []int32
+	for _, u := range s.frontier {
+		d := s.dist[u] + 1
+		for _, v := range s.next(u) {
+			if !allow(v) {
+				continue
+			}
+			dv, seen := s.dist[v]
+			if !seen {
+				s.dist[v] = d
+				frontier = append(frontier, v)
+			} else if dv != d {
+				continue
+			}
+			s.parents[v] = append(s.parents[v], u)
+		}
+	}
+	s.frontier = frontier
+}
+
+// collectPaths returns the paths through the meeting nodes of a
+// bidirectional search, up to max paths if max is positive. Paths are
+// enumerated lazily, since the number of shortest paths may be huge.
+func collectPaths(fwd, bwd *search, meets []int32, max int) [][]int32 {
+	var res [][]int32
+	for _, m := range meets {
+		done := fwd.walk(m, nil, func(head []int32) bool {
+			return bwd.walk(m, nil, func(tail []int32) bool {
+				// head runs from m back to the start, and tail from m to
+				// the end
+				path := make([]int32, 0, len(head)+len(tail)-1)
+				for i := len(head) - 1; i >= 0; i-- {
+					path = append(path, head[i])
+				}
+				path = append(path, tail[1:]...)
+				res = append(res, path)
+				return len(res) == max
+			})
+		})
+		if done {
+			break
+		}
+	}
+	return res
+}
+
+// walk calls f with each path from n back to the start of the search,
+// until f returns true. It returns whether f returned true. The path
+// passed to f is only valid during the call.
+func (s *search) walk(n int32, path []int32, f func(path []int32) bool) bool {
+	path = append(path, n)
+	parents := s.parents[n]
+	if len(parents) == 0 {
+		return f(path)
+	}
+	for _, p := range parents {
+		if s.walk(p, path, f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package graph_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/graph"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+func Test_ShortestPaths(t *testing.T) {
+	g := buildGraph(t,
+		linktest.Page(1, "A", "B", "C", "F"),
+		linktest.Page(2, "B", "D"),
+		linktest.Page(3, "C", "D", "A"),
+		linktest.Page(4, "D", "E"),
+		linktest.Page(5, "E"),
+		linktest.Page(6, "F", "G"),
+		linktest.Page(7, "G", "H"),
+		linktest.Page(8, "H", "E"),
+		linktest.Page(9, "Isolated", "A"),
+	)
+	node := func(title string) int32 {
+		n, ok := g.NodeByTitle(title)
+		if !ok {
+			t.Fatalf("missing node %q", title)
+		}
+		return n
+	}
+	titles := func(paths [][]int32) [][]string {
+		var res [][]string
+		for _, path := range paths {
+			var p []string
+			for _, n := range path {
+				p = append(p, g.Title(n))
+			}
+			res = append(res, p)
+		}
+		return res
+	}
+	except := func(title string) func(int32) bool {
+		return func(n int32) bool { return g.Title(n) != title }
+	}
+
+	for _, tc := range []struct {
+		name     string
+		from, to string
+		opts     graph.PathOptions
+		want     [][]string
+	}{
+		{"all", "A", "E", graph.PathOptions{},
+			[][]string{{"A", "B", "D", "E"}, {"A", "C", "D", "E"}}},
+		{"max paths", "A", "E", graph.PathOptions{MaxPaths: 1},
+			[][]string{{"A", "B", "D", "E"}}},
+		{"disallowed node", "A", "E", graph.PathOptions{Allow: except("B")},
+			[][]string{{"A", "C", "D", "E"}}},
+		{"detour", "A", "E", graph.PathOptions{Allow: except("D")},
+			[][]string{{"A", "F", "G", "H", "E"}}},
+		{"endpoints are always allowed", "A", "E", graph.PathOptions{Allow: except("A")},
+			[][]string{{"A", "B", "D", "E"}, {"A", "C", "D", "E"}}},
+		{"direct link", "C", "A", graph.PathOptions{},
+			[][]string{{"C", "A"}}},
+		{"same node", "A", "A", graph.PathOptions{},
+			[][]string{{"A"}}},
+		{"no path", "E", "A", graph.PathOptions{}, nil},
+		{"no incoming links", "A", "Isolated", graph.PathOptions{}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := titles(graph.ShortestPaths(g, node(tc.from), node(tc.to), tc.opts))
+			if !cmp.Equal(tc.want, got) {
+				t.Errorf("invalid paths\n%v", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+
+	if got := graph.ShortestPath(g, node("A"), node("H"), graph.PathOptions{}); len(got) != 4 {
+		t.Errorf("invalid shortest path from A to H, expected a path of length 4, got: %v", titles([][]int32{got}))
+	}
+}
@@ -0,0 +1,237 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WalkOptions configures random walks. Zero values are replaced by their
+// defaults.
+//
+// Walks are biased as in node2vec: after moving from t to v, the walk
+// moves to a link x of v with a weight of 1/P if x is t, 1 if t links to
+// x, and 1/Q otherwise. A low P keeps walks close to where they started,
+// and a low Q makes them explore. With P and Q set to one, the walks are
+// the uniform walks of DeepWalk.
+type WalkOptions struct {
+	// Length is the maximum number of nodes in a walk. Defaults to 80.
+	Length int
+
+	// WalksPerNode is the number of walks started from each node.
+	// Defaults to 10.
+	WalksPerNode int
+
+	// P is the return parameter. Defaults to 1.
+	P float64
+
+	// Q is the in-out parameter. Defaults to 1.
+	Q float64
+
+	// Seed determines the walks. The same seed gives the same walks.
+	Seed int64
+}
+
+func (opts *WalkOptions) setDefaults() {
+	if opts.Length == 0 {
+		opts.Length = 80
+	}
+	if opts.WalksPerNode == 0 {
+		opts.WalksPerNode = 10
+	}
+	if opts.P == 0 {
+		opts.P = 1
+	}
+	if opts.Q == 0 {
+		opts.Q = 1
+	}
+}
+
+// Walk returns the random walk of the provided round which starts at a
+// node. Walks follow outgoing links, and end early at nodes without
+// links. The walk only depends on the start node, the round and the
+// options, so walks can be generated in any order.
+func Walk(g *Graph, start int32, round int, opts WalkOptions) []int32 {
+	opts.setDefaults()
+	r := newRNG(opts.Seed, uint64(start)<<32|uint64(uint32(round)))
+	return walk(g, start, &r, opts, make([]int32, 0, opts.Length))
+}
+
+func walk(g *Graph, start int32, r *rng, opts WalkOptions, buf []int32) []int32 {
+	res := append(buf[:0], start)
+	// Weights are scaled so that the largest one is 1, and links are
+	// accepted with a probability of their weight
+	max := 1 / opts.P
+	if 1/opts.Q > max {
+		max = 1 / opts.Q
+	}
+	if max < 1 {
+		max = 1
+	}
+	returnWeight, inWeight, outWeight := 1/opts.P/max, 1/max, 1/opts.Q/max
+	uniform := opts.P == 1 && opts.Q == 1
+
+	for len(res) < opts.Length {
+		v := res[len(res)-1]
+		out := g.Out(v)
+		if len(out) == 0 {
+			break
+		}
+		if uniform || len(res) == 1 {
+			res = append(res, out[r.intn(len(out))])
+			continue
+		}
+		t := res[len(res)-2]
+		prev := g.Out(t)
+		for {
+			x := out[r.intn(len(out))]
+			w := outWeight
+			switch {
+			case x == t:
+				w = returnWeight
+			case contains(prev, x):
+				w = inWeight
+			}
+			if r.float64() < w {
+				res = append(res, x)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// contains reports whether a sorted list of nodes contains a node.
+func contains(nodes []int32, n int32) bool {
+	i := sort.Search(len(nodes), func(i int) bool { return nodes[i] >= n })
+	return i < len(nodes) && nodes[i] == n
+}
+
+// WriteWalks writes random walks from each node as lines of
+// space-separated tokens to files in the provided directory, e.g.
+// "walks_00000.txt". Nodes are split evenly over the provided number of
+// files, which are written in parallel. Tokens are page IDs, or titles
+// with spaces replaced by underscores if titles is true. Walks of a single
+// node are not written.
+//
+// Each round of walks visits the nodes of a file in a random order, which
+// like the walks themselves only depends on the seed.
+func WriteWalks(g *Graph, dir string, shards int, titles bool, opts WalkOptions) error {
+	opts.setDefaults()
+	if shards < 1 {
+		shards = 1
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	n := g.NumNodes()
+	errs := make([]error, shards)
+	var wg sync.WaitGroup
+	for i := 0; i < shards; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := filepath.Join(dir, fmt.Sprintf("walks_%05d.txt", i))
+			start, end := int32(n*i/shards), int32(n*(i+1)/shards)
+			errs[i] = writeWalks(g, path, start, end, titles, opts)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeWalks(g *Graph, path string, start, end int32, titles bool, opts WalkOptions) error {
+	f, err := os.OpenFile(path, os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	nodes := make([]int32, 0, end-start)
+	for v := start; v < end; v++ {
+		nodes = append(nodes, v)
+	}
+	buf := make([]int32, 0, opts.Length)
+	var line []byte
+	for round := 0; round < opts.WalksPerNode; round++ {
+		order := newRNG(opts.Seed, uint64(start)<<32|uint64(uint32(round)), 1)
+		for i := len(nodes) - 1; i > 0; i-- {
+			j := order.intn(i + 1)
+			nodes[i], nodes[j] = nodes[j], nodes[i]
+		}
+		for _, v := range nodes {
+			r := newRNG(opts.Seed, uint64(v)<<32|uint64(uint32(round)))
+			buf = walk(g, v, &r, opts, buf)
+			if len(buf) < 2 {
+				continue
+			}
+			line = line[:0]
+			for i, u := range buf {
+				if i > 0 {
+					line = append(line, ' ')
+				}
+				if titles {
+					line = append(line, strings.ReplaceAll(g.title(u), " ", "_")...)
+				} else {
+					line = strconv.AppendInt(line, int64(g.PageID(u)), 10)
+				}
+			}
+			line = append(line, '\n')
+			if _, err := w.Write(line); err != nil {
+				f.Close()
+				return err
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rng is a SplitMix64 random number generator. It is cheap to create, so
+// that each walk can have a generator of its own.
+type rng uint64
+
+// newRNG returns a generator for a seed and a sequence of keys.
+func newRNG(seed int64, keys ...uint64) rng {
+	r := rng(mix64(uint64(seed)))
+	for _, k := range keys {
+		r = rng(mix64(uint64(r) ^ mix64(k)))
+	}
+	return r
+}
+
+func (r *rng) next() uint64 {
+	*r += 0x9e3779b97f4a7c15
+	return mix64(uint64(*r))
+}
+
+// intn returns a number in [0, n).
+func (r *rng) intn(n int) int {
+	return int(r.next() % uint64(n))
+}
+
+// float64 returns a number in [0, 1).
+func (r *rng) float64() float64 {
+	return float64(r.next()>>11) / (1 << 53)
+}
+
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
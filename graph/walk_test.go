@@ -0,0 +1,151 @@
+package graph_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/graph"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+func Test_Walk(t *testing.T) {
+	g := cliquesGraph(t)
+	opts := graph.WalkOptions{Length: 10, P: 0.5, Q: 2, Seed: 1}
+	for start := int32(0); int(start) < g.NumNodes(); start++ {
+		for round := 0; round < 5; round++ {
+			walk := graph.Walk(g, start, round, opts)
+			if walk[0] != start {
+				t.Fatalf("invalid start of walk %v, expected: %v, got: %v", walk, start, walk[0])
+			}
+			if g.OutDegree(start) == 0 && len(walk) != 1 {
+				t.Fatalf("invalid walk %v from a node without links, expected a single node", walk)
+			}
+			if g.OutDegree(start) > 0 && len(walk) != opts.Length {
+				t.Fatalf("invalid length of walk %v, expected: %v, got: %v", walk, opts.Length, len(walk))
+			}
+			for i := 1; i < len(walk); i++ {
+				if !hasLink(g, walk[i-1], walk[i]) {
+					t.Fatalf("walk %v has no link from %v to %v", walk, walk[i-1], walk[i])
+				}
+			}
+			if diff := cmp.Diff(walk, graph.Walk(g, start, round, opts)); diff != "" {
+				t.Fatalf("walks differ for the same seed (-first +second):\n%v", diff)
+			}
+		}
+	}
+}
+
+func Test_WalkBias(t *testing.T) {
+	// A star where the center links to each leaf and each leaf links back.
+	// Leaves are not linked to each other, so from the center a walk
+	// either returns to the previous leaf or moves away from it.
+	g := buildGraph(t,
+		linktest.Page(1, "Center", "A", "B", "C", "D"),
+		linktest.Page(2, "A", "Center"),
+		linktest.Page(3, "B", "Center"),
+		linktest.Page(4, "C", "Center"),
+		linktest.Page(5, "D", "Center"),
+	)
+	center, _ := g.NodeByTitle("Center")
+	for _, tc := range []struct {
+		name     string
+		p, q     float64
+		min, max float64
+	}{
+		{"uniform", 1, 1, 0.2, 0.3},
+		{"return", 0.01, 1, 0.95, 1},
+		{"explore", 100, 0.5, 0, 0.01},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var returns, steps int
+			opts := graph.WalkOptions{Length: 21, P: tc.p, Q: tc.q, Seed: 2}
+			for round := 0; round < 200; round++ {
+				walk := graph.Walk(g, center, round, opts)
+				for i := 3; i < len(walk); i += 2 {
+					steps++
+					if walk[i] == walk[i-2] {
+						returns++
+					}
+				}
+			}
+			if f := float64(returns) / float64(steps); f < tc.min || f > tc.max {
+				t.Errorf("invalid share of returning steps, expected: between %v and %v, got: %v", tc.min, tc.max, f)
+			}
+		})
+	}
+}
+
+func Test_WriteWalks(t *testing.T) {
+	g := cliquesGraph(t)
+	opts := graph.WalkOptions{Length: 5, WalksPerNode: 3, P: 2, Q: 0.5, Seed: 3}
+	dir, err := ioutil.TempDir("", "walks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The set of walks does not depend on the number of shards
+	var want []string
+	for _, shards := range []int{1, 3} {
+		out := filepath.Join(dir, "ids", strings.Repeat("x", shards))
+		if err := graph.WriteWalks(g, out, shards, false, opts); err != nil {
+			t.Fatal(err)
+		}
+		lines := readLines(t, out)
+		if want == nil {
+			want = lines
+			continue
+		}
+		if !cmp.Equal(want, lines) {
+			t.Errorf("invalid walks with %v shards\n%v", shards, cmp.Diff(want, lines))
+		}
+	}
+	// Node C has no links, so walks from it are not written
+	if n := len(want); n != 8*opts.WalksPerNode {
+		t.Errorf("invalid number of walks, expected: %v, got: %v", 8*opts.WalksPerNode, n)
+	}
+
+	out := filepath.Join(dir, "titles")
+	if err := graph.WriteWalks(g, out, 2, true, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range readLines(t, out) {
+		for _, tok := range strings.Fields(line) {
+			if _, ok := g.NodeByTitle(tok); !ok {
+				t.Errorf("walk %q has unknown title %q", line, tok)
+			}
+		}
+	}
+}
+
+func hasLink(g *graph.Graph, from, to int32) bool {
+	for _, n := range g.Out(from) {
+		if n == to {
+			return true
+		}
+	}
+	return false
+}
+
+// readLines returns the sorted lines of all files in a directory.
+func readLines(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []string
+	for _, f := range files {
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")...)
+	}
+	sort.Strings(res)
+	return res
+}
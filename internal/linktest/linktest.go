@@ -0,0 +1,33 @@
+// Package linktest provides linked pages for tests.
+package linktest
+
+import (
+	"io"
+
+	"github.com/sebnyberg/wikipedia"
+)
+
+// Page returns a linked page with a link to each of the targets.
+func Page(id int32, title string, targets ...string) *wikipedia.LinkedPage {
+	p := &wikipedia.LinkedPage{PageId: id, PageTitle: title}
+	for _, t := range targets {
+		p.Links = append(p.Links, &wikipedia.Link{TargetTitle: t})
+	}
+	return p
+}
+
+// Reader reads linked pages from a slice.
+type Reader []*wikipedia.LinkedPage
+
+func (r *Reader) Next() (*wikipedia.LinkedPage, error) {
+	if len(*r) == 0 {
+		return nil, io.EOF
+	}
+	p := (*r)[0]
+	*r = (*r)[1:]
+	return p, nil
+}
+
+func (r *Reader) Close() error {
+	return nil
+}
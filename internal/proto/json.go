@@ -0,0 +1,106 @@
+package proto
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// JSONLinesWriter writes messages to a file in JSON Lines format,
+// i.e. one JSON-encoded message per line.
+type JSONLinesWriter struct {
+	buf   *bufio.Writer
+	opts  protojson.MarshalOptions
+	close func() error
+}
+
+// NewJSONLinesWriter returns a writer that puts messages into a file
+// in JSON Lines format.
+//
+// If the provided path already exists, an error is returned.
+func NewJSONLinesWriter(path string) (*JSONLinesWriter, error) {
+	f, err := os.OpenFile(path, os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufio.NewWriter(f)
+
+	close := func() error {
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	return &JSONLinesWriter{
+		buf:   buf,
+		opts:  protojson.MarshalOptions{UseProtoNames: true},
+		close: close,
+	}, nil
+}
+
+// Write appends a message to the file.
+func (w *JSONLinesWriter) Write(m pb.Message) error {
+	b, err := w.opts.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if _, err := w.buf.Write(b); err != nil {
+		return err
+	}
+	return w.buf.WriteByte('\n')
+}
+
+func (w *JSONLinesWriter) Close() error {
+	return w.close()
+}
+
+// JSONLinesReader reads messages from a file written by a JSONLinesWriter.
+type JSONLinesReader struct {
+	buf *bufio.Reader
+	f   *os.File
+}
+
+// NewJSONLinesReader returns a reader that retrieves messages from the
+// provided path.
+//
+// If a file does not exist at the provided path, an error is returned.
+func NewJSONLinesReader(path string) (*JSONLinesReader, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesReader{
+		buf: bufio.NewReader(f),
+		f:   f,
+	}, nil
+}
+
+// Read reads the next message into m. Empty lines are skipped.
+// If there are no more messages, io.EOF is returned.
+func (r *JSONLinesReader) Read(m pb.Message) error {
+	for {
+		line, err := r.buf.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			return protojson.Unmarshal(line, m)
+		}
+		if err == io.EOF {
+			return io.EOF
+		}
+	}
+}
+
+func (r *JSONLinesReader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,60 @@
+package proto_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sebnyberg/wikipedia"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+)
+
+func Test_JSONLines(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	links := []*wikipedia.ExternalLink{
+		{PageId: 1, PageTitle: "Sweden", Url: "https://www.government.se/", Label: "Government"},
+		{PageId: 2, PageTitle: "Stockholm", Url: "https://start.stockholm/", Section: "External links"},
+	}
+	path := filepath.Join(dir, "links.jsonl")
+	w, err := wikiproto.NewJSONLinesWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, link := range links {
+		if err := w.Write(link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := wikiproto.NewJSONLinesReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var got []*wikipedia.ExternalLink
+	for {
+		var link wikipedia.ExternalLink
+		if err := r.Read(&link); err != nil {
+			if err == io.EOF {
+				break
+			}
+			t.Fatal(err)
+		}
+		got = append(got, &link)
+	}
+	if !cmp.Equal(links, got, cmpopts.IgnoreUnexported(wikipedia.ExternalLink{})) {
+		t.Errorf("invalid links\n%v", cmp.Diff(links, got, cmpopts.IgnoreUnexported(wikipedia.ExternalLink{})))
+	}
+}
@@ -0,0 +1,137 @@
+package proto
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/DataDog/zstd"
+	"github.com/sebnyberg/protoio"
+	"github.com/sebnyberg/wikipedia"
+	pb "google.golang.org/protobuf/proto"
+)
+
+// MessageWriter writes messages of any type to a file in the same
+// zstd-compressed length-delimited format as the page writer.
+type MessageWriter struct {
+	protow *protoio.Writer
+	close  func() error
+}
+
+// NewMessageWriter returns a writer that puts messages into a file
+// in zstd-compressed length-delimited protobuf format.
+//
+// If the provided path already exists, an error is returned.
+func NewMessageWriter(path string) (*MessageWriter, error) {
+	f, err := os.OpenFile(path, os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufio.NewWriter(f)
+	zs := zstd.NewWriter(buf)
+	protow := protoio.NewWriter(zs)
+
+	close := func() error {
+		if err := zs.Close(); err != nil {
+			return err
+		}
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	return &MessageWriter{
+		protow: protow,
+		close:  close,
+	}, nil
+}
+
+// Write appends a message to the file.
+func (w *MessageWriter) Write(m pb.Message) error {
+	return w.protow.WriteMsg(m)
+}
+
+func (w *MessageWriter) Close() error {
+	return w.close()
+}
+
+// MessageReader reads messages from a file written by a MessageWriter.
+type MessageReader struct {
+	r     *protoio.Reader
+	close func() error
+}
+
+// NewMessageReader returns a reader that retrieves messages from the
+// provided path.
+//
+// If a file does not exist at the provided path, an error is returned.
+func NewMessageReader(path string) (*MessageReader, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bufio.NewReader(f)
+	zs := zstd.NewReader(buf)
+	r := protoio.NewReader(zs)
+
+	close := func() error {
+		if err := zs.Close(); err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return nil
+	}
+
+	return &MessageReader{
+		r:     r,
+		close: close,
+	}, nil
+}
+
+// Read reads the next message into m.
+// If there are no more messages, io.EOF is returned.
+func (r *MessageReader) Read(m pb.Message) error {
+	if err := r.r.ReadMsg(m); err != nil {
+		if err == io.EOF {
+			return io.EOF
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *MessageReader) Close() error {
+	return r.close()
+}
+
+type linkedPageReader struct {
+	*MessageReader
+}
+
+// NewLinkedPageReader returns a reader that retrieves LinkedPage messages
+// from a file written by a MessageWriter.
+//
+// If a file does not exist at the provided path, an error is returned.
+func NewLinkedPageReader(path string) (wikipedia.LinkedPageReader, error) {
+	r, err := NewMessageReader(path)
+	if err != nil {
+		return nil, err
+	}
+	return linkedPageReader{r}, nil
+}
+
+func (r linkedPageReader) Next() (*wikipedia.LinkedPage, error) {
+	var p wikipedia.LinkedPage
+	if err := r.Read(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
@@ -0,0 +1,235 @@
+// Package scan contains helpers for finding balanced wikitext markup,
+// such as templates, links and tables.
+package scan
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MatchBraces returns the end offset (exclusive) of the template, parser
+// function or template parameter starting at s[i]. Nested braces are
+// balanced by keeping a stack of open brace runs, so that "{{{1}}}" inside
+// a template closes with three braces rather than two.
+// If the braces are not balanced, -1 is returned.
+func MatchBraces(s string, i int) int {
+	var stack []int
+	for i < len(s) {
+		switch {
+		case strings.HasPrefix(s[i:], "{{{"):
+			stack = append(stack, 3)
+			i += 3
+		case strings.HasPrefix(s[i:], "{{"):
+			stack = append(stack, 2)
+			i += 2
+		case strings.HasPrefix(s[i:], "}}") && len(stack) > 0:
+			n := stack[len(stack)-1]
+			if n == 3 && !strings.HasPrefix(s[i:], "}}}") {
+				n = 2
+			}
+			stack = stack[:len(stack)-1]
+			i += n
+			if len(stack) == 0 {
+				return i
+			}
+		case len(stack) == 0:
+			return -1
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// MatchLink returns the end offset (exclusive) of the internal link starting
+// at s[i]. Links nested inside the link, such as links in image captions,
+// are balanced. If the link is not closed, -1 is returned.
+func MatchLink(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch {
+		case strings.HasPrefix(s[i:], "[["):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], "]]"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		case depth == 0:
+			return -1
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// MatchTable returns the end offset (exclusive) of the table starting at
+// s[i]. Nested tables are balanced. If the table is not closed, -1 is
+// returned.
+func MatchTable(s string, i int) int {
+	depth := 0
+	for i < len(s) {
+		switch {
+		case strings.HasPrefix(s[i:], "{|"):
+			depth++
+			i += 2
+		case strings.HasPrefix(s[i:], "|}"):
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		case depth == 0:
+			return -1
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// MatchComment returns the end offset (exclusive) of the HTML comment
+// starting at s[i]. An unterminated comment runs until the end of s.
+func MatchComment(s string, i int) int {
+	end := strings.Index(s[i+4:], "-->")
+	if end == -1 {
+		return len(s)
+	}
+	return i + 4 + end + 3
+}
+
+// SplitPipes splits s on top-level pipes, i.e. pipes that are not nested
+// inside templates, parameters or links.
+func SplitPipes(s string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			if end := MatchBraces(s, i); end != -1 {
+				i = end
+				continue
+			}
+			i += 2
+		case strings.HasPrefix(s[i:], "[["):
+			if end := MatchLink(s, i); end != -1 {
+				i = end
+				continue
+			}
+			i += 2
+		case s[i] == '|':
+			parts = append(parts, s[start:i])
+			i++
+			start = i
+		default:
+			i++
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// IndexTopLevel returns the index of the first occurrence of c in s that
+// is not nested inside a template, parameter or link, or -1 if there is
+// none.
+func IndexTopLevel(s string, c byte) int {
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			if end := MatchBraces(s, i); end != -1 {
+				i = end
+				continue
+			}
+			i += 2
+		case strings.HasPrefix(s[i:], "[["):
+			if end := MatchLink(s, i); end != -1 {
+				i = end
+				continue
+			}
+			i += 2
+		case s[i] == c:
+			return i
+		default:
+			i++
+		}
+	}
+	return -1
+}
+
+// MaskComments replaces the contents of HTML comments with spaces,
+// keeping line breaks so that byte and line offsets are preserved.
+func MaskComments(s string) string {
+	if !strings.Contains(s, "<!--") {
+		return s
+	}
+	b := []byte(s)
+	for i := 0; i < len(s); {
+		idx := strings.Index(s[i:], "<!--")
+		if idx == -1 {
+			break
+		}
+		start := i + idx
+		end := MatchComment(s, start)
+		for j := start; j < end; j++ {
+			if b[j] != '\n' {
+				b[j] = ' '
+			}
+		}
+		i = end
+	}
+	return string(b)
+}
+
+var attrRegexp = regexp.MustCompile(`([a-zA-Z][\w-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"']+))`)
+
+// SplitCells returns the offsets of the cell separators in s[start:],
+// ignoring separators nested inside templates and links.
+func SplitCells(s string, start int, seps []string) []int {
+	var res []int
+	for i := start; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			if end := MatchBraces(s, i); end != -1 {
+				i = end
+				continue
+			}
+			i += 2
+		case strings.HasPrefix(s[i:], "[["):
+			if end := MatchLink(s, i); end != -1 {
+				i = end
+				continue
+			}
+			i += 2
+		default:
+			found := false
+			for _, sep := range seps {
+				if strings.HasPrefix(s[i:], sep) {
+					found = true
+				}
+			}
+			if found {
+				res = append(res, i)
+				i += 2
+				continue
+			}
+			i++
+		}
+	}
+	return res
+}
+
+// ParseAttrs parses HTML attributes, e.g. `class="wikitable" rowspan=2`.
+// Attribute names are returned in lower case.
+func ParseAttrs(s string) map[string]string {
+	matches := attrRegexp.FindAllStringSubmatch(s, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(matches))
+	for _, m := range matches {
+		res[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+	}
+	return res
+}
@@ -0,0 +1,145 @@
+package minhash
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Match is a page found by an index query.
+type Match struct {
+	ID int32
+
+	// Similarity is the estimated Jaccard index of the features of the
+	// page and the query.
+	Similarity float64
+}
+
+// Index finds pages with similar signatures.
+//
+// Signatures are split into bands of rows. Pages whose signatures are
+// equal in all rows of any band are candidates for a query, and are
+// ranked by their estimated similarity. Two pages with similarity s are
+// candidates with probability 1-(1-s^rows)^bands. More bands of fewer
+// rows increase recall, while fewer bands of more rows make queries
+// faster by returning fewer candidates.
+type Index struct {
+	bands, rows int
+
+	// buckets contains a map from the hash of a band to the positions of
+	// the pages in it, for each band.
+	buckets []map[uint64][]int32
+	ids     []int32
+	sigs    []Signature
+	pos     map[int32]int32
+}
+
+// NewIndex returns an index for signatures of bands*rows values.
+func NewIndex(bands, rows int) (*Index, error) {
+	if bands < 1 || rows < 1 {
+		return nil, fmt.Errorf("invalid number of bands and rows: %v, %v", bands, rows)
+	}
+	buckets := make([]map[uint64][]int32, bands)
+	for i := range buckets {
+		buckets[i] = make(map[uint64][]int32)
+	}
+	return &Index{
+		bands:   bands,
+		rows:    rows,
+		buckets: buckets,
+		pos:     make(map[int32]int32),
+	}, nil
+}
+
+// Len returns the number of pages in the index.
+func (idx *Index) Len() int {
+	return len(idx.ids)
+}
+
+// Add adds the signature of a page to the index. Pages without a
+// signature, i.e. without features, and pages already in the index are
+// ignored. If the length of the signature does not match the index, an
+// error is returned.
+func (idx *Index) Add(id int32, sig Signature) error {
+	if sig == nil {
+		return nil
+	}
+	if len(sig) != idx.bands*idx.rows {
+		return fmt.Errorf("signature length %v does not match %v bands of %v rows",
+			len(sig), idx.bands, idx.rows)
+	}
+	if _, ok := idx.pos[id]; ok {
+		return nil
+	}
+	pos := int32(len(idx.ids))
+	idx.pos[id] = pos
+	idx.ids = append(idx.ids, id)
+	idx.sigs = append(idx.sigs, sig)
+	for band, buckets := range idx.buckets {
+		key := idx.bandHash(sig, band)
+		buckets[key] = append(buckets[key], pos)
+	}
+	return nil
+}
+
+// Query returns up to k pages which are similar to the signature, ordered
+// by descending similarity.
+func (idx *Index) Query(sig Signature, k int) []Match {
+	return idx.query(sig, k, -1)
+}
+
+// Similar returns up to k pages which are similar to the page with the
+// provided ID, excluding the page itself, ordered by descending
+// similarity. If the page is not in the index, nil is returned.
+func (idx *Index) Similar(id int32, k int) []Match {
+	pos, ok := idx.pos[id]
+	if !ok {
+		return nil
+	}
+	return idx.query(idx.sigs[pos], k, pos)
+}
+
+func (idx *Index) query(sig Signature, k int, exclude int32) []Match {
+	if len(sig) != idx.bands*idx.rows || k <= 0 {
+		return nil
+	}
+	seen := make(map[int32]bool)
+	var res []Match
+	for band, buckets := range idx.buckets {
+		for _, pos := range buckets[idx.bandHash(sig, band)] {
+			if pos == exclude || seen[pos] {
+				continue
+			}
+			seen[pos] = true
+			res = append(res, Match{
+				ID:         idx.ids[pos],
+				Similarity: sig.Similarity(idx.sigs[pos]),
+			})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Similarity == res[j].Similarity {
+			return res[i].ID < res[j].ID
+		}
+		return res[i].Similarity > res[j].Similarity
+	})
+	if len(res) > k {
+		res = res[:k]
+	}
+	return res
+}
+
+// bandHash returns the hash of the values of a band of a signature.
+func (idx *Index) bandHash(sig Signature, band int) uint64 {
+	return sig.BandHash(band, idx.rows)
+}
+
+// BandHash returns the hash of the values of a band of the signature,
+// where each band has the provided number of rows. Signatures with equal
+// hashes for any band are candidates in an Index.
+func (s Signature) BandHash(band, rows int) uint64 {
+	h := uint64(band)
+	for _, v := range s[band*rows : (band+1)*rows] {
+		h = mix(h ^ uint64(v))
+	}
+	return h
+}
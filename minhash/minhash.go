@@ -0,0 +1,124 @@
+// Package minhash finds similar pages with MinHash signatures and
+// locality-sensitive hashing (LSH).
+//
+// The signature of a page is computed over a set of features, such as its
+// links or the shingles of its text. The fraction of equal values in the
+// signatures of two pages estimates the Jaccard index of their feature
+// sets. An Index groups signatures into buckets by bands of values, so
+// that similar pages are found without comparing all pairs of pages.
+package minhash
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia"
+)
+
+// Signature is the MinHash signature of a feature set.
+type Signature []uint32
+
+// Similarity returns the estimated Jaccard index of the feature sets of
+// two signatures computed by the same Hasher.
+func (s Signature) Similarity(t Signature) float64 {
+	if len(s) == 0 || len(s) != len(t) {
+		return 0
+	}
+	var n int
+	for i := range s {
+		if s[i] == t[i] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(s))
+}
+
+// Hasher computes MinHash signatures.
+type Hasher struct {
+	seeds []uint64
+}
+
+// NewHasher returns a hasher computing signatures of the provided length.
+// Signatures are only comparable if computed by hashers with the same
+// length and seed. Longer signatures estimate similarity more accurately,
+// at the cost of memory and time.
+func NewHasher(length int, seed int64) *Hasher {
+	rnd := rand.New(rand.NewSource(seed))
+	seeds := make([]uint64, length)
+	for i := range seeds {
+		seeds[i] = rnd.Uint64()
+	}
+	return &Hasher{seeds: seeds}
+}
+
+// Len returns the length of the signatures of the hasher.
+func (h *Hasher) Len() int {
+	return len(h.seeds)
+}
+
+// Signature returns the signature of a set of features. Duplicate
+// features do not affect the signature. If there are no features, nil is
+// returned.
+func (h *Hasher) Signature(features []string) Signature {
+	if len(features) == 0 {
+		return nil
+	}
+	sig := make(Signature, len(h.seeds))
+	for i := range sig {
+		sig[i] = math.MaxUint32
+	}
+	for _, f := range features {
+		fh := fnv.New64a()
+		fh.Write([]byte(f))
+		base := fh.Sum64()
+		for i, seed := range h.seeds {
+			if v := uint32(mix(base ^ seed)); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// mix is the finalizer of SplitMix64, which turns a hash into an
+// independent hash for each seed.
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// LinkFeatures returns the link targets of a page as features.
+func LinkFeatures(p *wikipedia.LinkedPage) []string {
+	res := make([]string, len(p.Links))
+	for i, link := range p.Links {
+		res[i] = link.TargetTitle
+	}
+	return res
+}
+
+// Shingles returns the k-shingles of a text as features, i.e. each
+// sequence of k consecutive words. Words are lower-cased. Texts shorter
+// than k words form a single shingle.
+func Shingles(text string, k int) []string {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) == 0 {
+		return nil
+	}
+	if k < 1 {
+		k = 1
+	}
+	if len(words) <= k {
+		return []string{strings.Join(words, " ")}
+	}
+	res := make([]string, 0, len(words)-k+1)
+	for i := 0; i+k <= len(words); i++ {
+		res = append(res, strings.Join(words[i:i+k], " "))
+	}
+	return res
+}
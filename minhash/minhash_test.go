@@ -0,0 +1,108 @@
+package minhash_test
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/minhash"
+)
+
+// features returns the features "f<start>" to "f<end-1>".
+func features(start, end int) []string {
+	var res []string
+	for i := start; i < end; i++ {
+		res = append(res, "f"+strconv.Itoa(i))
+	}
+	return res
+}
+
+func Test_Signature(t *testing.T) {
+	h := minhash.NewHasher(512, 1)
+	for _, tc := range []struct {
+		name string
+		a, b []string
+		want float64
+	}{
+		{"identical", features(0, 100), features(0, 100), 1},
+		{"third", features(0, 100), features(50, 150), 1.0 / 3},
+		{"disjoint", features(0, 100), features(100, 200), 0},
+		{"duplicates", append(features(0, 10), features(0, 10)...), features(0, 10), 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := h.Signature(tc.a).Similarity(h.Signature(tc.b))
+			if math.Abs(got-tc.want) > 0.08 {
+				t.Errorf("invalid similarity, expected: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+
+	if sig := h.Signature(nil); sig != nil {
+		t.Errorf("invalid signature without features, expected: nil, got: %v", sig)
+	}
+	other := minhash.NewHasher(512, 1)
+	if want, got := h.Signature(features(0, 5)), other.Signature(features(0, 5)); !cmp.Equal(want, got) {
+		t.Errorf("signatures differ between hashers with the same seed\n%v", cmp.Diff(want, got))
+	}
+}
+
+func Test_Shingles(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		k    int
+		want []string
+	}{
+		{"The quick brown fox", 2, []string{"the quick", "quick brown", "brown fox"}},
+		{"short", 3, []string{"short"}},
+		{"  ", 2, nil},
+	} {
+		if got := minhash.Shingles(tc.text, tc.k); !cmp.Equal(tc.want, got) {
+			t.Errorf("invalid shingles of %q for k = %v\n%v", tc.text, tc.k, cmp.Diff(tc.want, got))
+		}
+	}
+}
+
+func Test_Index(t *testing.T) {
+	h := minhash.NewHasher(64, 1)
+	idx, err := minhash.NewIndex(32, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages := map[int32][]string{
+		1: features(0, 100),
+		2: append(features(0, 95), "x1", "x2"),
+		3: features(0, 60),
+		4: features(1000, 1100),
+		5: nil,
+	}
+	for id, f := range pages {
+		if err := idx.Add(id, h.Signature(f)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if idx.Len() != 4 {
+		t.Errorf("invalid length, expected: 4, got: %v", idx.Len())
+	}
+
+	var ids []int32
+	for _, m := range idx.Similar(1, 2) {
+		ids = append(ids, m.ID)
+	}
+	if !cmp.Equal([]int32{2, 3}, ids) {
+		t.Errorf("invalid similar pages\n%v", cmp.Diff([]int32{2, 3}, ids))
+	}
+	if got := idx.Query(h.Signature(features(1000, 1100)), 1); len(got) != 1 || got[0].ID != 4 || got[0].Similarity != 1 {
+		t.Errorf("invalid query result, expected: page 4 with similarity 1, got: %v", got)
+	}
+	if got := idx.Similar(5, 10); got != nil {
+		t.Errorf("invalid similar pages of a page without features, expected: nil, got: %v", got)
+	}
+
+	if err := idx.Add(6, minhash.NewHasher(10, 1).Signature(features(0, 1))); err == nil {
+		t.Error("expected an error when adding a signature of the wrong length")
+	}
+	if _, err := minhash.NewIndex(0, 4); err == nil {
+		t.Error("expected an error for zero bands")
+	}
+}
@@ -1,5 +1,179 @@
 package wikipedia
 
+import "math"
+
+// Scorer scores the relatedness of two pages based on their links. Scores
+// are symmetric, higher scores mean more related pages, and pages without
+// links score zero.
+type Scorer interface {
+	Score(a, b *LinkedPage) float64
+}
+
+// LinkCounts maps page titles to the number of pages linking to them.
+type LinkCounts map[string]int32
+
+// Add counts the links of a page. Multiple links to the same page are
+// counted once.
+func (c LinkCounts) Add(p *LinkedPage) {
+	for title := range linkSet(p) {
+		c[title]++
+	}
+}
+
+// Jaccard scores pages by the Jaccard index of their link sets, i.e. the
+// number of shared links divided by the number of distinct links.
+type Jaccard struct{}
+
+func (Jaccard) Score(a, b *LinkedPage) float64 {
+	as, bs := linkSet(a), linkSet(b)
+	n := shared(as, bs)
+	if n == 0 {
+		return 0
+	}
+	return float64(n) / float64(len(as)+len(bs)-n)
+}
+
+// Dice scores pages by the Sørensen–Dice coefficient of their link sets,
+// i.e. twice the number of shared links divided by the total number of
+// links.
+type Dice struct{}
+
+func (Dice) Score(a, b *LinkedPage) float64 {
+	as, bs := linkSet(a), linkSet(b)
+	n := shared(as, bs)
+	if n == 0 {
+		return 0
+	}
+	return 2 * float64(n) / float64(len(as)+len(bs))
+}
+
+// Cosine scores pages by the cosine similarity of their link sets, i.e.
+// the number of shared links divided by the geometric mean of the number
+// of links of each page.
+type Cosine struct{}
+
+func (Cosine) Score(a, b *LinkedPage) float64 {
+	as, bs := linkSet(a), linkSet(b)
+	n := shared(as, bs)
+	if n == 0 {
+		return 0
+	}
+	return float64(n) / math.Sqrt(float64(len(as))*float64(len(bs)))
+}
+
+// AdamicAdar scores pages by the Adamic-Adar index of their link sets.
+// Each shared link contributes 1/log(n), where n is the number of pages
+// linking to the target, so that links to rarely linked pages weigh more.
+// Unlike the other scores, the score is not bounded by one.
+type AdamicAdar struct {
+	// Inlinks contains the number of incoming links of each page.
+	Inlinks LinkCounts
+}
+
+func (s AdamicAdar) Score(a, b *LinkedPage) float64 {
+	as, bs := linkSet(a), linkSet(b)
+	var score float64
+	for title := range as {
+		if !bs[title] {
+			continue
+		}
+		// A page linked by both a and b has at least two incoming links
+		n := s.Inlinks[title]
+		if n < 2 {
+			n = 2
+		}
+		score += 1 / math.Log(float64(n))
+	}
+	return score
+}
+
+// Backlinks maps page titles to the IDs of the pages linking to them.
+type Backlinks map[string][]int32
+
+// Add adds the links of a page. Multiple links to the same page are
+// counted once.
+func (b Backlinks) Add(p *LinkedPage) {
+	for title := range linkSet(p) {
+		b[title] = append(b[title], p.PageId)
+	}
+}
+
+// MilneWitten scores pages by the relatedness measure of Milne and Witten
+// (2008), which compares the sets A and B of pages linking to each page:
+//
+//	1 - (log(max(|A|, |B|)) - log(|A∩B|)) / (log(W) - log(min(|A|, |B|)))
+//
+// where W is the total number of pages. Negative scores, for pages which
+// share few incoming links relative to their number, are clamped to zero.
+type MilneWitten struct {
+	// Backlinks contains the pages linking to each page.
+	Backlinks Backlinks
+
+	// Pages is the total number of pages.
+	Pages int
+}
+
+func (s MilneWitten) Score(a, b *LinkedPage) float64 {
+	as, bs := s.Backlinks[a.PageTitle], s.Backlinks[b.PageTitle]
+	if len(as) > len(bs) {
+		as, bs = bs, as
+	}
+	if len(as) == 0 {
+		return 0
+	}
+	linking := make(map[int32]bool, len(as))
+	for _, id := range as {
+		linking[id] = true
+	}
+	var n int
+	for _, id := range bs {
+		if linking[id] {
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	den := math.Log(float64(s.Pages)) - math.Log(float64(len(as)))
+	if den <= 0 {
+		// Both pages are linked from all pages
+		return 1
+	}
+	score := 1 - (math.Log(float64(len(bs)))-math.Log(float64(n)))/den
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// linkSet returns the distinct link targets of a page.
+func linkSet(p *LinkedPage) map[string]bool {
+	res := make(map[string]bool, len(p.Links))
+	for _, link := range p.Links {
+		res[link.TargetTitle] = true
+	}
+	return res
+}
+
+// shared returns the number of titles in both sets.
+func shared(a, b map[string]bool) int {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	var n int
+	for title := range a {
+		if b[title] {
+			n++
+		}
+	}
+	return n
+}
+
+// Score returns the fraction of the links of b which are shared with a,
+// counting the pages themselves as links.
+//
+// Deprecated: Score is asymmetric and undefined for pages without links.
+// Use a Scorer such as Jaccard instead.
 func Score(a *LinkedPage, b *LinkedPage) float32 {
 	aLinks := make(map[string]bool, len(a.Links))
 	aLinks[a.PageTitle] = true
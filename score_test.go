@@ -0,0 +1,67 @@
+package wikipedia_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/internal/linktest"
+)
+
+func Test_Scorer(t *testing.T) {
+	a := linktest.Page(0, "a", "b", "c", "d", "d")
+	b := linktest.Page(0, "b", "c", "d", "e", "f")
+	empty := linktest.Page(0, "empty")
+
+	inlinks := wikipedia.LinkCounts{"b": 5, "c": 2, "d": 10, "e": 50, "f": 100}
+	backlinks := wikipedia.Backlinks{"a": {1, 2, 3, 4}, "b": {3, 4, 5, 6, 7, 8, 9, 10}}
+	for _, tc := range []struct {
+		name   string
+		scorer wikipedia.Scorer
+		want   float64
+	}{
+		{"jaccard", wikipedia.Jaccard{}, 0.4},
+		{"dice", wikipedia.Dice{}, 4.0 / 7},
+		{"cosine", wikipedia.Cosine{}, 0.5773502691896258},
+		{"adamic-adar", wikipedia.AdamicAdar{Inlinks: inlinks}, 1.876989522792215},
+		{"milne-witten", wikipedia.MilneWitten{Backlinks: backlinks, Pages: 100}, 1 - math.Log(4)/math.Log(25)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.scorer.Score(a, b); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("invalid score, expected: %v, got: %v", tc.want, got)
+			}
+			if got := tc.scorer.Score(b, a); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("invalid reverse score, expected: %v, got: %v", tc.want, got)
+			}
+			if got := tc.scorer.Score(a, empty); got != 0 {
+				t.Errorf("invalid score with an empty page, expected: 0, got: %v", got)
+			}
+			if got := tc.scorer.Score(empty, empty); got != 0 {
+				t.Errorf("invalid score of empty pages, expected: 0, got: %v", got)
+			}
+		})
+	}
+}
+
+func Test_LinkCounts(t *testing.T) {
+	counts := make(wikipedia.LinkCounts)
+	counts.Add(linktest.Page(0, "a", "b", "c", "c"))
+	counts.Add(linktest.Page(0, "b", "c"))
+	want := wikipedia.LinkCounts{"b": 1, "c": 2}
+	if !cmp.Equal(want, counts) {
+		t.Errorf("invalid link counts\n%v", cmp.Diff(want, counts))
+	}
+}
+
+func Test_Backlinks(t *testing.T) {
+	a := linktest.Page(1, "a", "b", "c", "c")
+	b := linktest.Page(2, "b", "c")
+	backlinks := make(wikipedia.Backlinks)
+	backlinks.Add(a)
+	backlinks.Add(b)
+	want := wikipedia.Backlinks{"b": {1}, "c": {1, 2}}
+	if !cmp.Equal(want, backlinks) {
+		t.Errorf("invalid backlinks\n%v", cmp.Diff(want, backlinks))
+	}
+}
@@ -0,0 +1,37 @@
+package wikipedia
+
+import "io"
+
+// TitleIndex maps page titles to page IDs, so that pages in a PageStore
+// can be looked up by title. Titles are stored as they appear in the dump,
+// e.g. "Template:Infobox settlement".
+type TitleIndex map[string]int32
+
+// ReadTitleIndex indexes the titles of all pages read from r.
+func ReadTitleIndex(r PageReader) (TitleIndex, error) {
+	idx := make(TitleIndex)
+	for {
+		p, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				return idx, nil
+			}
+			return nil, err
+		}
+		idx[p.Title] = p.Id
+	}
+}
+
+// TitleLookup looks up pages by title. It is implemented by TitleIndex, and
+// by page stores which index the titles of their pages.
+type TitleLookup interface {
+	// PageID returns the ID of the page with the provided title. If there
+	// is no such page, false is returned.
+	PageID(title string) (int32, bool, error)
+}
+
+// PageID returns the ID of the page with the provided title.
+func (idx TitleIndex) PageID(title string) (int32, bool, error) {
+	id, ok := idx[title]
+	return id, ok, nil
+}
@@ -26,6 +26,14 @@ type PageWriter interface {
 	io.Closer
 }
 
+// LinkedPageReader reads pages with their outgoing links.
+type LinkedPageReader interface {
+	// Next returns the next page.
+	// If there are no more pages, io.EOF is returned.
+	Next() (*LinkedPage, error)
+	io.Closer
+}
+
 func Transfer(from PageReader, to PageWriter) error {
 	i := 0
 	n := 0
@@ -0,0 +1,297 @@
+// Package ast parses wikitext into a syntax tree.
+//
+// The parser is tolerant: malformed or unbalanced markup never causes an
+// error, it is kept as text instead. Each node records its location in the
+// source text, so that the original markup of any node can be recovered
+// with text[n.Pos():n.End()].
+package ast
+
+// Node is a node in the syntax tree.
+type Node interface {
+	// Pos returns the byte offset of the start of the node in the text.
+	Pos() int
+
+	// End returns the byte offset immediately after the node.
+	End() int
+}
+
+// Span is the location of a node in the source text.
+type Span struct {
+	Start int
+	Stop  int
+}
+
+func (s Span) Pos() int { return s.Start }
+func (s Span) End() int { return s.Stop }
+
+// Document is the root of a parsed text.
+type Document struct {
+	Span
+	Children []Node
+}
+
+// Text is plain text. Character references such as "&amp;" are kept as is.
+type Text struct {
+	Span
+	Value string
+}
+
+// Heading is a section heading, e.g. "== History ==".
+type Heading struct {
+	Span
+	Level    int
+	Children []Node
+}
+
+// Paragraph is a run of consecutive lines of text.
+type Paragraph struct {
+	Span
+	Children []Node
+}
+
+// List is a bulleted (*), numbered (#) or definition (; and :) list.
+// Nested lists are children of the items that they belong to.
+type List struct {
+	Span
+
+	// Marker is the marker of the list, i.e. one of '*', '#', ';' or ':'.
+	// Definition lists may contain items with both ';' and ':' markers.
+	Marker byte
+	Items  []*ListItem
+}
+
+// ListItem is an item in a list.
+type ListItem struct {
+	Span
+	Marker   byte
+	Children []Node
+}
+
+// HorizontalRule is a line starting with "----".
+type HorizontalRule struct {
+	Span
+}
+
+// Table is a wikitable, i.e. a table written as {| ... |}.
+type Table struct {
+	Span
+	Attrs   map[string]string
+	Caption []Node
+	Rows    []*TableRow
+}
+
+// TableRow is a row in a table. Cells before the first row separator
+// belong to an implicit row.
+type TableRow struct {
+	Span
+	Attrs map[string]string
+	Cells []*TableCell
+}
+
+// TableCell is a header or data cell in a table.
+type TableCell struct {
+	Span
+	Header   bool
+	Attrs    map[string]string
+	Children []Node
+}
+
+// Link is an internal link, e.g. [[Stockholm|the capital]].
+type Link struct {
+	Span
+
+	// Target is the link target as written, e.g. "Stockholm" or
+	// "File:Example.jpg".
+	Target string
+
+	// Options contains the raw parts between the target and the label,
+	// such as the image options of a file link.
+	Options []string
+
+	// Children is the label of the link, or nil if the link has no label.
+	Children []Node
+
+	// Trail contains the letters directly following the link, which are
+	// displayed as part of the link, e.g. "s" in [[dog]]s.
+	Trail string
+}
+
+// ExternalLink is a bracketed external link, e.g. [https://example.com
+// Example], or a bare URL.
+type ExternalLink struct {
+	Span
+	URL string
+
+	// Children is the label of the link, or nil if the link has no label.
+	Children []Node
+
+	// Bare is true if the URL is not enclosed in brackets.
+	Bare bool
+}
+
+// Template is a template or parser function invocation, e.g.
+// {{Infobox settlement|name=Stockholm}} or {{#if:x|y|z}}.
+type Template struct {
+	Span
+
+	// Name is the normalized name of the template, without the "Template:"
+	// prefix. For parser functions, the name includes the leading '#'.
+	Name string
+
+	// ParserFunction is true if the invocation is a parser function or a
+	// magic word taking arguments.
+	ParserFunction bool
+
+	Args []*Argument
+}
+
+// Argument is a template argument. Positional arguments are named by their
+// position, starting from "1", as in MediaWiki.
+type Argument struct {
+	Span
+	Name     string
+	Children []Node
+}
+
+// Parameter is a template parameter, e.g. {{{1|default}}}.
+type Parameter struct {
+	Span
+	Name string
+
+	// Default is the default value of the parameter, and HasDefault is true
+	// if the parameter has one, which may be empty.
+	Default    []Node
+	HasDefault bool
+}
+
+// Tag is an HTML or extension tag, e.g. <ref name="a">...</ref> or <br/>.
+// The contents of tags such as <nowiki> and <math> are not parsed, and
+// are kept as a single Text node.
+type Tag struct {
+	Span
+
+	// Name is the lower-case name of the tag.
+	Name        string
+	Attrs       map[string]string
+	SelfClosing bool
+	Children    []Node
+}
+
+// Comment is an HTML comment.
+type Comment struct {
+	Span
+
+	// Value is the text between "<!--" and "-->".
+	Value string
+}
+
+// Bold is text between markers of three apostrophes.
+type Bold struct {
+	Span
+	Children []Node
+}
+
+// Italic is text between markers of two apostrophes.
+type Italic struct {
+	Span
+	Children []Node
+}
+
+// MagicWord is a behavior switch such as __NOTOC__.
+type MagicWord struct {
+	Span
+
+	// Name is the name of the switch without underscores, e.g. "NOTOC".
+	Name string
+}
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree in depth-first order. It starts by calling
+// v.Visit(node); node must not be nil.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range Children(node) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree in depth-first order. It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the children of node, followed by a call of
+// f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Children returns the direct children of a node in source order.
+func Children(node Node) []Node {
+	switch n := node.(type) {
+	case *Document:
+		return n.Children
+	case *Heading:
+		return n.Children
+	case *Paragraph:
+		return n.Children
+	case *List:
+		res := make([]Node, len(n.Items))
+		for i, item := range n.Items {
+			res[i] = item
+		}
+		return res
+	case *ListItem:
+		return n.Children
+	case *Table:
+		res := append([]Node{}, n.Caption...)
+		for _, row := range n.Rows {
+			res = append(res, row)
+		}
+		return res
+	case *TableRow:
+		res := make([]Node, len(n.Cells))
+		for i, cell := range n.Cells {
+			res[i] = cell
+		}
+		return res
+	case *TableCell:
+		return n.Children
+	case *Link:
+		return n.Children
+	case *ExternalLink:
+		return n.Children
+	case *Template:
+		res := make([]Node, len(n.Args))
+		for i, arg := range n.Args {
+			res[i] = arg
+		}
+		return res
+	case *Argument:
+		return n.Children
+	case *Parameter:
+		return n.Default
+	case *Tag:
+		return n.Children
+	case *Bold:
+		return n.Children
+	case *Italic:
+		return n.Children
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package ast_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/wikitext/ast"
+)
+
+// dump returns a compact representation of the tree rooted at n.
+func dump(n ast.Node) string {
+	var sb strings.Builder
+	var visit func(n ast.Node)
+	visit = func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.Text:
+			fmt.Fprintf(&sb, "%q", n.Value)
+			return
+		case *ast.Heading:
+			fmt.Fprintf(&sb, "H%v", n.Level)
+		case *ast.List:
+			fmt.Fprintf(&sb, "List%c", n.Marker)
+		case *ast.ListItem:
+			fmt.Fprintf(&sb, "Item%c", n.Marker)
+		case *ast.Link:
+			fmt.Fprintf(&sb, "Link[%v]", n.Target)
+			if n.Trail != "" {
+				fmt.Fprintf(&sb, "+%v", n.Trail)
+			}
+		case *ast.ExternalLink:
+			fmt.Fprintf(&sb, "ExtLink[%v]", n.URL)
+		case *ast.Template:
+			fmt.Fprintf(&sb, "Template[%v]", n.Name)
+		case *ast.Argument:
+			fmt.Fprintf(&sb, "%v=", n.Name)
+		case *ast.Parameter:
+			fmt.Fprintf(&sb, "Param[%v]", n.Name)
+		case *ast.Tag:
+			fmt.Fprintf(&sb, "<%v>", n.Name)
+		case *ast.Comment:
+			fmt.Fprintf(&sb, "Comment%q", n.Value)
+			return
+		case *ast.MagicWord:
+			fmt.Fprintf(&sb, "Magic[%v]", n.Name)
+			return
+		default:
+			sb.WriteString(strings.TrimPrefix(fmt.Sprintf("%T", n), "*ast."))
+		}
+		children := ast.Children(n)
+		if len(children) == 0 {
+			return
+		}
+		sb.WriteString("(")
+		for i, child := range children {
+			if i > 0 {
+				sb.WriteString(" ")
+			}
+			visit(child)
+		}
+		sb.WriteString(")")
+	}
+	visit(n)
+	return sb.String()
+}
+
+func Test_Parse(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", "Document"},
+		{"paragraphs",
+			"a\nb\n\nc",
+			`Document(Paragraph("a\nb") Paragraph("c"))`},
+		{"heading",
+			"== A [[b]] ==\ntext",
+			`Document(H2("A " Link[b]) Paragraph("text"))`},
+		{"formatting",
+			"''a '''b''' c''",
+			`Document(Paragraph(Italic("a " Bold("b") " c")))`},
+		{"bold italic",
+			"'''''a''''' b",
+			`Document(Paragraph(Italic(Bold("a")) " b"))`},
+		{"unclosed formatting ends at line end",
+			"'''a\nb",
+			`Document(Paragraph(Bold("a") "\nb"))`},
+		{"overlapping formatting",
+			"'''a ''b''' c''",
+			`Document(Paragraph(Bold("a " Italic("b")) Italic(" c")))`},
+		{"links",
+			"[[Dog|dogs]], [[cat]]s and [[File:A.jpg|thumb|A ''cat'']]",
+			`Document(Paragraph(Link[Dog]("dogs") ", " Link[cat]+s " and " Link[File:A.jpg]("A " Italic("cat"))))`},
+		{"external links",
+			"[https://a.com A] and https://b.com/x.",
+			`Document(Paragraph(ExtLink[https://a.com]("A") " and " ExtLink[https://b.com/x] "."))`},
+		{"templates",
+			"{{cite web|url=http://a.com|{{lc:X}}}} {{{1|d}}}",
+			`Document(Paragraph(Template[Cite web](url=(ExtLink[http://a.com]) 1=(Template[lc](1=("X")))) " " Param[1]("d")))`},
+		{"template spanning lines",
+			"{{Infobox\n| name = A\n| list =\n* x\n* y\n}}",
+			`Document(Paragraph(Template[Infobox](name=("A") list=(List*(Item*("x") Item*("y"))))))`},
+		{"tags",
+			"a<ref name=\"x\">b {{c}}</ref><ref name=\"x\" /><br>",
+			`Document(Paragraph("a" <ref>("b " Template[C]) <ref> <br>))`},
+		{"raw tags",
+			"<nowiki>[[a]]</nowiki><math>x''</math>",
+			`Document(Paragraph(<nowiki>("[[a]]") <math>("x''")))`},
+		{"comments",
+			"a<!-- b\n\nc -->d",
+			`Document(Paragraph("a" Comment" b\n\nc " "d"))`},
+		{"lists",
+			"* a\n** b\n* c\n# d\n; e : f",
+			`Document(List*(Item*("a" List*(Item*("b"))) Item*("c")) List#(Item#("d")) List;(Item;("e") Item:("f")))`},
+		{"table",
+			"{|\n|+ Cap\n! A !! B\n|-\n| colspan=2 | ''c''\n|}",
+			`Document(Table("Cap" TableRow(TableCell("A") TableCell("B")) TableRow(TableCell(Italic("c")))))`},
+		{"nested table",
+			"{|\n|\n{|\n| a\n|}\n|}",
+			`Document(Table(TableRow(TableCell(Table(TableRow(TableCell("a")))))))`},
+		{"magic word and rule",
+			"__NOTOC__\n----",
+			`Document(Paragraph(Magic[NOTOC]) HorizontalRule)`},
+		{"malformed",
+			"{{a|[[b}} [[c {{{ '' <ref>x",
+			`Document(Paragraph(Template[A](1=("[[b")) " [[c {{{ " Italic(" " <ref> "x")))`},
+		{"unclosed tag around tag",
+			"<div><div>a</div>",
+			`Document(Paragraph(<div> <div>("a")))`},
+		{"template closing after its tag",
+			"<ref>{{a</ref>}}",
+			`Document(Paragraph(<ref>("{{a") "}}"))`},
+		{"unclosed link around link",
+			"[[a|[[b]]",
+			`Document(Paragraph("[[a|" Link[b]))`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := dump(ast.Parse(tc.in))
+			if got != tc.want {
+				t.Errorf("Parse(%q)\ngot:  %v\nwant: %v", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_ParseSpans(t *testing.T) {
+	text := "== A ==\n* [[b|c]] {{d|e}}\n{|\n| <ref>f</ref>\n|}"
+	var got []string
+	ast.Inspect(ast.Parse(text), func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.Heading, *ast.ListItem, *ast.Link, *ast.Template, *ast.Argument, *ast.TableCell, *ast.Tag:
+			got = append(got, text[n.Pos():n.End()])
+		}
+		return true
+	})
+	want := []string{
+		"== A ==",
+		"* [[b|c]] {{d|e}}",
+		"[[b|c]]",
+		"{{d|e}}",
+		"e",
+		"| <ref>f</ref>",
+		"<ref>f</ref>",
+	}
+	if !cmp.Equal(want, got) {
+		t.Errorf("spans differ\n%v", cmp.Diff(want, got))
+	}
+}
+
+func Test_ParseNested(t *testing.T) {
+	// Children must lie within their parents for arbitrary input
+	for _, text := range []string{
+		"{{a|{{b|[[c|''d'']]}}}}\n* e\n** f",
+		"{|\n| a || b\n|-\n! c\n|}\n[[x]]y",
+		"<div>\n* a\n</div>'''b",
+		"]]}}|}'''''{{{{{{",
+	} {
+		ast.Inspect(ast.Parse(text), func(n ast.Node) bool {
+			if n == nil {
+				return false
+			}
+			if n.Pos() > n.End() {
+				t.Errorf("%q: %T has negative span", text, n)
+			}
+			for _, child := range ast.Children(n) {
+				if child.Pos() < n.Pos() || child.End() > n.End() {
+					t.Errorf("%q: %T outside of parent %T", text, child, n)
+				}
+			}
+			return true
+		})
+	}
+}
+
+func Test_ParseLinear(t *testing.T) {
+	// Parsing malformed markup takes time proportional to its length,
+	// rather than scanning to the end of the text at every opening
+	for _, tc := range []struct {
+		name string
+		in   func(n int) string
+	}{
+		{"unclosed tags", repeat("<div>x ")},
+		{"unclosed refs", repeat("<ref>a ")},
+		{"unclosed tags on lines", repeat("para\n<p>\n")},
+		{"nested tags", func(n int) string {
+			return strings.Repeat("<div>", n) + strings.Repeat("</div>", n)
+		}},
+		{"unclosed raw tags", repeat("<nowiki>a ")},
+		{"unclosed templates", repeat("{{x|")},
+		{"unclosed links", repeat("[[x|")},
+		{"unclosed tables", repeat("{|\n| a\n")},
+		{"unclosed external links", repeat("[http://a.com x ")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			small := parseTime(tc.in(1000))
+			large := parseTime(tc.in(16000))
+			// Quadratic parsing would take 256 times longer
+			if large > 64*small {
+				t.Errorf("parsing 16 times the input took %v rather than %v", large, small)
+			}
+		})
+	}
+}
+
+func repeat(s string) func(n int) string {
+	return func(n int) string {
+		return strings.Repeat(s, n)
+	}
+}
+
+// parseTime returns the shortest time of a few runs of parsing text.
+func parseTime(text string) time.Duration {
+	var res time.Duration
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		ast.Parse(text)
+		if d := time.Since(start); i == 0 || d < res {
+			res = d
+		}
+	}
+	return res
+}
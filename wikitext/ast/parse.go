@@ -0,0 +1,935 @@
+package ast
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/internal/scan"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+// Parse parses wikitext, e.g. the text of a revision, into a syntax tree.
+func Parse(text string) *Document {
+	p := parser{
+		src:       text,
+		closing:   matchClosingTags(text),
+		braces:    matchAllBraces(text),
+		links:     matchAllPairs(text, "[[", "]]"),
+		tables:    matchAllPairs(text, "{|", "|}"),
+		extLinkTo: -1,
+	}
+	return &Document{
+		Span:     Span{0, len(text)},
+		Children: p.parseBlocks(0, len(text)),
+	}
+}
+
+// ParsePage parses the text of the latest revision of a page.
+func ParsePage(p *wikipedia.Page) *Document {
+	if len(p.Revisions) == 0 {
+		return Parse("")
+	}
+	return Parse(p.Revisions[len(p.Revisions)-1].Text)
+}
+
+var (
+	headingRegexp  = regexp.MustCompile(`^(={1,6})(.+?)(={1,6})[ \t]*$`)
+	extLinkRegexp  = regexp.MustCompile(`^\[(?i:https?://|ftp://|mailto:|//)`)
+	bareURLRegexp  = regexp.MustCompile(`^(?i:https?://|ftp://)[^\s<>\[\]{}|"]+`)
+	magicRegexp    = regexp.MustCompile(`^__([A-Z]+)__`)
+	openTagRegexp  = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9]*)((?:\s[^<>]*?)?)(/?)>`)
+	closeTagRegexp = regexp.MustCompile(`^</([a-zA-Z][a-zA-Z0-9]*)\s*>`)
+)
+
+// Tags that are parsed as tags. Other text within angle brackets is text.
+var knownTags = map[string]bool{
+	"abbr": true, "b": true, "bdi": true, "big": true, "blockquote": true,
+	"br": true, "caption": true, "center": true, "cite": true, "code": true,
+	"dd": true, "del": true, "dfn": true, "div": true, "dl": true, "dt": true,
+	"em": true, "font": true, "gallery": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "hr": true, "i": true,
+	"imagemap": true, "ins": true, "kbd": true, "li": true, "mark": true,
+	"ol": true, "p": true, "poem": true, "q": true, "ref": true,
+	"references": true, "rp": true, "rt": true, "ruby": true, "s": true,
+	"samp": true, "small": true, "span": true, "strike": true, "strong": true,
+	"sub": true, "sup": true, "table": true, "td": true, "th": true,
+	"time": true, "tr": true, "tt": true, "u": true, "ul": true, "var": true,
+	"wbr": true,
+
+	// Tags whose contents are not wikitext
+	"chem": true, "ce": true, "graph": true, "hiero": true, "inputbox": true,
+	"mapframe": true, "math": true, "nowiki": true, "pre": true,
+	"score": true, "source": true, "syntaxhighlight": true,
+	"templatedata": true, "timeline": true,
+}
+
+// Tags whose contents are kept as text.
+var rawTags = map[string]bool{
+	"chem": true, "ce": true, "graph": true, "hiero": true, "inputbox": true,
+	"mapframe": true, "math": true, "nowiki": true, "pre": true,
+	"score": true, "source": true, "syntaxhighlight": true,
+	"templatedata": true, "timeline": true,
+}
+
+// Tags which never have contents.
+var voidTags = map[string]bool{
+	"br": true, "hr": true, "wbr": true,
+}
+
+// parser parses regions of the source text. All offsets are offsets into
+// src, and regions are parsed by passing src[:end] to the scan functions
+// so that nested markup cannot extend beyond its region.
+type parser struct {
+	src string
+
+	// closing maps the offsets of opening tags to their closing tags.
+	closing map[int]closingTag
+
+	// braces, links and tables map the offsets of templates, internal
+	// links and tables to their end offsets, or -1 if they are not closed.
+	braces map[int]int
+	links  map[int]int
+	tables map[int]int
+
+	// extLinkTo caches the offset of the first ']' or line break at or
+	// after extLinkFrom.
+	extLinkFrom, extLinkTo int
+}
+
+// Markup is matched once for the whole text when it is parsed, since
+// scanning for the end of unclosed markup at each opening makes parsing
+// quadratic. A pass over the text finds the same ends as the scan functions
+// for the openings it reaches. Openings which it does not reach, e.g. the
+// inner braces of "{{{{", are scanned when they are parsed.
+
+// matchAllBraces returns the results of scan.MatchBraces for the braces
+// opened in src, keeping a stack of open brace runs as MatchBraces does.
+func matchAllBraces(src string) map[int]int {
+	type run struct{ start, n int }
+	res := make(map[int]int)
+	var stack []run
+	for i := 0; i < len(src); {
+		switch {
+		case strings.HasPrefix(src[i:], "{{{"):
+			stack = append(stack, run{i, 3})
+			i += 3
+		case strings.HasPrefix(src[i:], "{{"):
+			stack = append(stack, run{i, 2})
+			i += 2
+		case strings.HasPrefix(src[i:], "}}") && len(stack) > 0:
+			r := stack[len(stack)-1]
+			if r.n == 3 && !strings.HasPrefix(src[i:], "}}}") {
+				r.n = 2
+			}
+			stack = stack[:len(stack)-1]
+			i += r.n
+			res[r.start] = i
+		default:
+			i++
+		}
+	}
+	for _, r := range stack {
+		res[r.start] = -1
+	}
+	return res
+}
+
+// matchAllPairs returns the results of scan.MatchLink or scan.MatchTable
+// for the markup opened by open and closed by close in src.
+func matchAllPairs(src, open, close string) map[int]int {
+	res := make(map[int]int)
+	var stack []int
+	for i := 0; i < len(src); {
+		switch {
+		case strings.HasPrefix(src[i:], open):
+			stack = append(stack, i)
+			i += len(open)
+		case strings.HasPrefix(src[i:], close) && len(stack) > 0:
+			i += len(close)
+			res[stack[len(stack)-1]] = i
+			stack = stack[:len(stack)-1]
+		default:
+			i++
+		}
+	}
+	for _, start := range stack {
+		res[start] = -1
+	}
+	return res
+}
+
+// matchBraces returns scan.MatchBraces(src[:end], i).
+func (p *parser) matchBraces(i, end int) int {
+	if e, ok := p.braces[i]; ok && e <= end {
+		return e
+	}
+	// Braces which close beyond the region may be closed within it by a
+	// shorter closing run
+	return scan.MatchBraces(p.src[:end], i)
+}
+
+// matchLink returns scan.MatchLink(src[:end], i).
+func (p *parser) matchLink(i, end int) int {
+	return matchPair(p.links, p.src[:end], i, scan.MatchLink)
+}
+
+// matchTable returns scan.MatchTable(src[:end], i).
+func (p *parser) matchTable(i, end int) int {
+	return matchPair(p.tables, p.src[:end], i, scan.MatchTable)
+}
+
+// matchPair returns the end of the markup at s[i] from ends, or from match
+// if it is not in ends. Links and tables are closed by a single token, so
+// markup which closes beyond s is not closed within it.
+func matchPair(ends map[int]int, s string, i int, match func(string, int) int) int {
+	e, ok := ends[i]
+	if !ok {
+		return match(s, i)
+	}
+	if e > len(s) {
+		return -1
+	}
+	return e
+}
+
+// extLinkEnd returns the offset of the first ']' or line break at or after
+// src[i] within src[:end], or -1 if there is none.
+func (p *parser) extLinkEnd(i, end int) int {
+	if i < p.extLinkFrom || i > p.extLinkTo {
+		p.extLinkFrom = i
+		p.extLinkTo = len(p.src)
+		if idx := strings.IndexAny(p.src[i:], "]\n"); idx != -1 {
+			p.extLinkTo = i + idx
+		}
+	}
+	if p.extLinkTo >= end {
+		return -1
+	}
+	return p.extLinkTo
+}
+
+// parseContent parses the contents of a template argument, tag or table
+// cell. Surrounding whitespace is ignored, and contents consisting of a
+// single paragraph are returned without the paragraph.
+func (p *parser) parseContent(start, end int) []Node {
+	start, end = p.trim(start, end)
+	nodes := p.parseBlocks(start, end)
+	if len(nodes) == 1 {
+		if para, ok := nodes[0].(*Paragraph); ok {
+			return para.Children
+		}
+	}
+	return nodes
+}
+
+// trim moves start and end inwards past whitespace.
+func (p *parser) trim(start, end int) (int, int) {
+	for start < end && isSpace(p.src[start]) {
+		start++
+	}
+	for end > start && isSpace(p.src[end-1]) {
+		end--
+	}
+	return start, end
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// lineEnd returns the offset of the line break which ends the line starting
+// at s[i]. Line breaks inside comments, templates, links and tags do not end
+// the line.
+func (p *parser) lineEnd(i, end int) int {
+	s := p.src[:end]
+	for i < end {
+		switch {
+		case s[i] == '\n':
+			return i
+		case strings.HasPrefix(s[i:], "<!--"):
+			i = scan.MatchComment(s, i)
+		case strings.HasPrefix(s[i:], "{{"):
+			if e := p.matchBraces(i, end); e != -1 {
+				i = e
+				continue
+			}
+			i += 2
+		case strings.HasPrefix(s[i:], "[["):
+			if e := p.matchLink(i, end); e != -1 {
+				i = e
+				continue
+			}
+			i += 2
+		case s[i] == '<':
+			if t, ok := p.matchTag(i, end); ok {
+				i = t.stop
+				continue
+			}
+			i++
+		default:
+			i++
+		}
+	}
+	return end
+}
+
+// listStack holds the lists which are open at the current line.
+type listStack []*List
+
+// parseBlocks parses block-level markup, i.e. headings, lists, tables,
+// horizontal rules and paragraphs, in src[start:end].
+func (p *parser) parseBlocks(start, end int) []Node {
+	var res []Node
+	var lists listStack
+	paraStart, paraEnd := -1, -1
+
+	flush := func() {
+		lists = nil
+		if paraStart == -1 {
+			return
+		}
+		res = append(res, &Paragraph{
+			Span:     Span{paraStart, paraEnd},
+			Children: p.parseInline(paraStart, paraEnd),
+		})
+		paraStart, paraEnd = -1, -1
+	}
+
+	for pos := start; pos < end; {
+		le := p.lineEnd(pos, end)
+		line := p.src[pos:le]
+		next := le + 1
+		indented := strings.TrimLeft(line, " \t:")
+		tableStart, tableEnd := le-len(indented), -1
+		if strings.HasPrefix(indented, "{|") {
+			tableEnd = p.matchTable(tableStart, end)
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case tableEnd != -1:
+			flush()
+			res = append(res, p.parseTable(tableStart, tableEnd))
+			// Text after the end of the table is parsed as a new line
+			next = tableEnd
+		case headingRegexp.MatchString(line):
+			flush()
+			res = append(res, p.parseHeading(pos, le))
+		case strings.HasPrefix(line, "----"):
+			flush()
+			n := len(line) - len(strings.TrimLeft(line, "-"))
+			res = append(res, &HorizontalRule{Span{pos, pos + n}})
+			if strings.TrimSpace(line[n:]) != "" {
+				paraStart, paraEnd = pos+n, le
+			}
+		case strings.IndexByte("*#:;", line[0]) != -1:
+			if paraStart != -1 {
+				flush()
+			}
+			if list := lists.add(p, pos, le); list != nil {
+				res = append(res, list)
+			}
+		default:
+			lists = nil
+			if paraStart == -1 {
+				paraStart = pos
+			}
+			paraEnd = le
+		}
+		pos = next
+	}
+	flush()
+	return res
+}
+
+func (p *parser) parseHeading(start, end int) *Heading {
+	m := headingRegexp.FindStringSubmatchIndex(p.src[start:end])
+	open, close := m[3]-m[2], m[7]-m[6]
+	level := open
+	if close < level {
+		level = close
+	}
+	// Unbalanced '=' are part of the heading
+	innerStart, innerEnd := p.trim(start+m[2]+level, start+m[7]-level)
+	return &Heading{
+		Span:     Span{start, end},
+		Level:    level,
+		Children: p.parseInline(innerStart, innerEnd),
+	}
+}
+
+// add adds the list item on the line src[start:end] to the stack of open
+// lists. If the item starts a new top-level list, the list is returned.
+func (ls *listStack) add(p *parser, start, end int) *List {
+	markers := p.src[start:end]
+	markers = markers[:len(markers)-len(strings.TrimLeft(markers, "*#:;"))]
+
+	// A definition term may be followed by its definition on the same line
+	contentStart := start + len(markers)
+	contentEnd := end
+	defStart := -1
+	if markers[len(markers)-1] == ';' {
+		if idx := scan.IndexTopLevel(p.src[contentStart:end], ':'); idx != -1 {
+			contentEnd = contentStart + idx
+			defStart = contentEnd + 1
+		}
+	}
+
+	var res *List
+	item := func(markers string, itemStart, itemEnd, cs int) {
+		// Keep the open lists which match the markers of the item
+		n := 0
+		for n < len(*ls) && n < len(markers) && sameList((*ls)[n].Marker, markers[n]) {
+			n++
+		}
+		*ls = (*ls)[:n]
+		for i := n; i < len(markers); i++ {
+			list := &List{Span: Span{itemStart, itemEnd}, Marker: markers[i]}
+			if i == 0 {
+				res = list
+			} else {
+				parent := (*ls)[i-1]
+				if len(parent.Items) == 0 {
+					parent.Items = append(parent.Items, &ListItem{
+						Span:   Span{itemStart, itemStart},
+						Marker: parent.Marker,
+					})
+				}
+				last := parent.Items[len(parent.Items)-1]
+				last.Children = append(last.Children, list)
+			}
+			*ls = append(*ls, list)
+		}
+		cs, ce := p.trim(cs, itemEnd)
+		list := (*ls)[len(*ls)-1]
+		list.Items = append(list.Items, &ListItem{
+			Span:     Span{itemStart, itemEnd},
+			Marker:   markers[len(markers)-1],
+			Children: p.parseInline(cs, ce),
+		})
+		// Extend the open lists and their last items to the new item
+		for i, l := range *ls {
+			l.Stop = itemEnd
+			if i < len(*ls)-1 {
+				l.Items[len(l.Items)-1].Stop = itemEnd
+			}
+		}
+	}
+	item(markers, start, contentEnd, contentStart)
+	if defStart != -1 {
+		item(markers[:len(markers)-1]+":", contentEnd, end, defStart)
+	}
+	return res
+}
+
+// sameList returns true if items with the markers a and b belong to the
+// same list. Definition terms and definitions share a list.
+func sameList(a, b byte) bool {
+	if a == ':' || a == ';' {
+		return b == ':' || b == ';'
+	}
+	return a == b
+}
+
+// parseTable parses the table in src[start:end], which includes the
+// opening "{|" and closing "|}".
+func (p *parser) parseTable(start, end int) *Table {
+	t := &Table{Span: Span{start, end}}
+	var row *TableRow
+	var cell *TableCell
+	var cellStart int
+
+	finishCell := func(stop int) {
+		if cell == nil {
+			return
+		}
+		_, cell.Stop = p.trim(cellStart, stop)
+		cell.Children = p.parseContent(cellStart, stop)
+		row.Stop = cell.Stop
+		cell = nil
+	}
+	addCell := func(header bool, markerStart, contentStart, contentEnd int) {
+		raw := p.src[contentStart:contentEnd]
+		c := &TableCell{Span: Span{markerStart, contentEnd}, Header: header}
+		// A single pipe separates attributes from the contents
+		if idx := scan.IndexTopLevel(raw, '|'); idx != -1 && !strings.HasPrefix(raw[idx:], "||") {
+			c.Attrs = scan.ParseAttrs(raw[:idx])
+			contentStart += idx + 1
+		}
+		if row == nil {
+			row = &TableRow{Span: Span{markerStart, contentEnd}}
+			t.Rows = append(t.Rows, row)
+		}
+		row.Cells = append(row.Cells, c)
+		cell = c
+		cellStart = contentStart
+	}
+
+	bodyEnd := end - 2
+	firstLine := strings.IndexByte(p.src[start:bodyEnd], '\n')
+	if firstLine == -1 {
+		return t
+	}
+	t.Attrs = scan.ParseAttrs(p.src[start+2 : start+firstLine])
+
+	for pos := start + firstLine + 1; pos < bodyEnd; {
+		le := p.lineEnd(pos, bodyEnd)
+		line := p.src[pos:le]
+		trimmed := strings.TrimLeft(line, " \t")
+		lineStart := le - len(trimmed)
+		next := le + 1
+
+		switch {
+		case strings.HasPrefix(trimmed, "{|"):
+			// Nested tables are part of the current cell
+			if e := p.matchTable(lineStart, bodyEnd); e != -1 {
+				next = e
+			}
+		case strings.HasPrefix(trimmed, "|+"):
+			finishCell(pos)
+			cs, ce := p.trim(lineStart+2, le)
+			t.Caption = p.parseInline(cs, ce)
+		case strings.HasPrefix(trimmed, "|-"):
+			finishCell(pos)
+			row = &TableRow{
+				Span:  Span{lineStart, le},
+				Attrs: scan.ParseAttrs(strings.TrimLeft(trimmed, "|-")),
+			}
+			t.Rows = append(t.Rows, row)
+		case strings.HasPrefix(trimmed, "|"), strings.HasPrefix(trimmed, "!"):
+			finishCell(pos)
+			header := trimmed[0] == '!'
+			seps := []string{"||"}
+			if header {
+				seps = append(seps, "!!")
+			}
+			markerStart := lineStart
+			cs := lineStart + 1
+			for _, sep := range scan.SplitCells(p.src[:le], cs, seps) {
+				addCell(header, markerStart, cs, sep)
+				finishCell(sep)
+				markerStart = sep
+				cs = sep + 2
+			}
+			// The last cell may continue on the next lines
+			addCell(header, markerStart, cs, le)
+		}
+		pos = next
+	}
+	finishCell(bodyEnd)
+	return t
+}
+
+// frame is an open formatting node while parsing inline markup. The root
+// frame has no node.
+type frame struct {
+	node     Node
+	start    int
+	children []Node
+}
+
+// parseInline parses inline markup, i.e. links, templates, tags, comments
+// and formatting, in src[start:end].
+func (p *parser) parseInline(start, end int) []Node {
+	s := p.src[:end]
+	frames := []*frame{{}}
+	textStart := start
+
+	emit := func(n Node) {
+		top := frames[len(frames)-1]
+		top.children = append(top.children, n)
+	}
+	flushText := func(i int) {
+		if i > textStart {
+			emit(&Text{Span: Span{textStart, i}, Value: s[textStart:i]})
+		}
+		textStart = i
+	}
+	closeFrame := func(stop int) {
+		f := frames[len(frames)-1]
+		frames = frames[:len(frames)-1]
+		switch n := f.node.(type) {
+		case *Bold:
+			n.Span = Span{f.start, stop}
+			n.Children = f.children
+		case *Italic:
+			n.Span = Span{f.start, stop}
+			n.Children = f.children
+		}
+		emit(f.node)
+	}
+	// closeAll closes unterminated formatting at the end of a line. Empty
+	// formatting, e.g. reopened after closing an outer node, is dropped.
+	closeAll := func(stop int) {
+		for len(frames) > 1 {
+			if len(frames[len(frames)-1].children) == 0 {
+				frames = frames[:len(frames)-1]
+				continue
+			}
+			closeFrame(stop)
+		}
+	}
+	// toggle opens or closes bold or italic text at the marker s[i:j]
+	toggle := func(bold bool, i, j int) {
+		idx := -1
+		for k := len(frames) - 1; k > 0; k-- {
+			if _, ok := frames[k].node.(*Bold); ok == bold {
+				idx = k
+				break
+			}
+		}
+		if idx == -1 {
+			var n Node = &Italic{}
+			if bold {
+				n = &Bold{}
+			}
+			frames = append(frames, &frame{node: n, start: i})
+			return
+		}
+		// Close the intervening nodes and reopen them after the marker
+		var reopen []Node
+		for len(frames)-1 > idx {
+			top := frames[len(frames)-1]
+			if _, ok := top.node.(*Bold); ok {
+				reopen = append(reopen, &Bold{})
+			} else {
+				reopen = append(reopen, &Italic{})
+			}
+			closeFrame(i)
+		}
+		closeFrame(j)
+		for k := len(reopen) - 1; k >= 0; k-- {
+			frames = append(frames, &frame{node: reopen[k], start: j})
+		}
+	}
+	isOpen := func(bold bool) (open bool, top bool) {
+		for k := len(frames) - 1; k > 0; k-- {
+			if _, ok := frames[k].node.(*Bold); ok == bold {
+				return true, k == len(frames)-1
+			}
+		}
+		return false, false
+	}
+
+	for i := start; i < end; {
+		switch c := s[i]; {
+		case c == '\n':
+			if len(frames) > 1 {
+				flushText(i)
+				closeAll(i)
+			}
+			i++
+		case strings.HasPrefix(s[i:], "<!--"):
+			flushText(i)
+			e := scan.MatchComment(s, i)
+			valueEnd := e
+			if strings.HasSuffix(s[:e], "-->") && e-3 >= i+4 {
+				valueEnd = e - 3
+			}
+			emit(&Comment{Span: Span{i, e}, Value: s[i+4 : valueEnd]})
+			i, textStart = e, e
+		case strings.HasPrefix(s[i:], "{{"):
+			e := p.matchBraces(i, end)
+			if e == -1 {
+				i += 2
+				continue
+			}
+			flushText(i)
+			emit(p.parseBraces(i, e))
+			i, textStart = e, e
+		case strings.HasPrefix(s[i:], "[["):
+			e := p.matchLink(i, end)
+			if e == -1 {
+				i += 2
+				continue
+			}
+			flushText(i)
+			link := p.parseLink(i, e, end)
+			emit(link)
+			i, textStart = link.Stop, link.Stop
+		case c == '[' && extLinkRegexp.MatchString(s[i:]):
+			e := p.extLinkEnd(i, end)
+			if e == -1 || s[e] != ']' {
+				i++
+				continue
+			}
+			flushText(i)
+			emit(p.parseExternalLink(i, e+1))
+			i, textStart = e+1, e+1
+		case (c == 'h' || c == 'H' || c == 'f' || c == 'F') && (i == start || !isWordByte(s[i-1])) && bareURLRegexp.MatchString(s[i:]):
+			url := strings.TrimRight(bareURLRegexp.FindString(s[i:]), ".,;:!?)'")
+			flushText(i)
+			emit(&ExternalLink{Span: Span{i, i + len(url)}, URL: url, Bare: true})
+			i, textStart = i+len(url), i+len(url)
+		case strings.HasPrefix(s[i:], "''"):
+			n := 2
+			for i+n < end && s[i+n] == '\'' {
+				n++
+			}
+			// Leading apostrophes beyond those of the marker are text
+			markerStart := i
+			switch {
+			case n == 4:
+				markerStart = i + 1
+			case n > 5:
+				markerStart = i + n - 5
+			}
+			flushText(markerStart)
+			stop := i + n
+			switch stop - markerStart {
+			case 2:
+				toggle(false, markerStart, stop)
+			case 3:
+				toggle(true, markerStart, stop)
+			case 5:
+				// Close the innermost node first
+				if _, boldTop := isOpen(true); boldTop {
+					toggle(true, markerStart, stop)
+					toggle(false, markerStart, stop)
+				} else {
+					toggle(false, markerStart, stop)
+					toggle(true, markerStart, stop)
+				}
+			}
+			i, textStart = stop, stop
+		case c == '<':
+			t, ok := p.matchTag(i, end)
+			if !ok {
+				i++
+				continue
+			}
+			flushText(i)
+			emit(p.parseTag(t))
+			i, textStart = t.stop, t.stop
+		case c == '_' && magicRegexp.MatchString(s[i:]):
+			m := magicRegexp.FindStringSubmatch(s[i:])
+			flushText(i)
+			emit(&MagicWord{Span: Span{i, i + len(m[0])}, Name: m[1]})
+			i, textStart = i+len(m[0]), i+len(m[0])
+		default:
+			i++
+		}
+	}
+	flushText(end)
+	closeAll(end)
+	return frames[0].children
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// parts splits src[start:end] on top-level pipes and returns the offsets
+// of each part.
+func (p *parser) parts(start, end int) []Span {
+	var res []Span
+	for _, part := range scan.SplitPipes(p.src[start:end]) {
+		res = append(res, Span{start, start + len(part)})
+		start += len(part) + 1
+	}
+	return res
+}
+
+// parseBraces parses the template or parameter in src[start:end].
+func (p *parser) parseBraces(start, end int) Node {
+	if strings.HasPrefix(p.src[start:end], "{{{") && strings.HasSuffix(p.src[start:end], "}}}") {
+		parts := p.parts(start+3, end-3)
+		param := &Parameter{
+			Span: Span{start, end},
+			Name: strings.TrimSpace(p.src[parts[0].Start:parts[0].Stop]),
+		}
+		if len(parts) > 1 {
+			param.HasDefault = true
+			param.Default = p.parseContent(parts[1].Start, parts[1].Stop)
+		}
+		return param
+	}
+
+	t := &Template{Span: Span{start, end}}
+	parts := p.parts(start+2, end-2)
+	name := p.src[parts[0].Start:parts[0].Stop]
+	args := parts[1:]
+	if idx := strings.IndexByte(name, ':'); idx != -1 {
+		fn := strings.TrimSpace(name[:idx])
+		if wikitext.IsParserFunction(fn) {
+			t.ParserFunction = true
+			t.Name = strings.ToLower(fn)
+			first := Span{parts[0].Start + idx + 1, parts[0].Stop}
+			args = append([]Span{first}, args...)
+		}
+	}
+	if !t.ParserFunction {
+		t.Name = wikitext.NormalizeTemplateName(name)
+	}
+
+	pos := 0
+	for _, arg := range args {
+		a := &Argument{Span: arg}
+		raw := p.src[arg.Start:arg.Stop]
+		valueStart := arg.Start
+		if idx := scan.IndexTopLevel(raw, '='); idx != -1 && !t.ParserFunction {
+			a.Name = strings.TrimSpace(raw[:idx])
+			valueStart += idx + 1
+		} else {
+			pos++
+			a.Name = strconv.Itoa(pos)
+		}
+		a.Children = p.parseContent(valueStart, arg.Stop)
+		t.Args = append(t.Args, a)
+	}
+	return t
+}
+
+// parseLink parses the internal link in src[start:end]. Letters following
+// the link up to limit are included in the link as its trail.
+func (p *parser) parseLink(start, end, limit int) *Link {
+	parts := p.parts(start+2, end-2)
+	link := &Link{
+		Span:   Span{start, end},
+		Target: strings.TrimSpace(p.src[parts[0].Start:parts[0].Stop]),
+	}
+	if len(parts) > 1 {
+		for _, part := range parts[1 : len(parts)-1] {
+			link.Options = append(link.Options, strings.TrimSpace(p.src[part.Start:part.Stop]))
+		}
+		label := parts[len(parts)-1]
+		link.Children = p.parseInline(p.trim(label.Start, label.Stop))
+		if link.Children == nil {
+			link.Children = []Node{}
+		}
+	}
+	trail := end
+	for trail < limit && (p.src[trail] >= 'a' && p.src[trail] <= 'z') {
+		trail++
+	}
+	link.Trail = p.src[end:trail]
+	link.Stop = trail
+	return link
+}
+
+// parseExternalLink parses the bracketed external link in src[start:end].
+func (p *parser) parseExternalLink(start, end int) *ExternalLink {
+	inner := p.src[start+1 : end-1]
+	link := &ExternalLink{Span: Span{start, end}, URL: inner}
+	if idx := strings.IndexAny(inner, " \t"); idx != -1 {
+		link.URL = inner[:idx]
+		link.Children = p.parseInline(p.trim(start+1+idx, end-1))
+	}
+	return link
+}
+
+// tagMatch is the location of a tag in the text.
+type tagMatch struct {
+	name        string
+	attrs       string
+	selfClosing bool
+	start       int
+
+	// contentStart and contentEnd are the offsets of the contents of the
+	// tag, and stop is the offset after the closing tag.
+	contentStart int
+	contentEnd   int
+	stop         int
+}
+
+// closingTag is the offset of a closing tag, and the offset after it.
+type closingTag struct {
+	start int
+	stop  int
+}
+
+// matchClosingTags matches the opening and closing tags of src in a single
+// pass, keeping a stack of open tags per name. Nested tags of the same name
+// are balanced, except for tags whose contents are text, which close at
+// the first closing tag.
+//
+// A tag is matched within a region of src if its closing tag ends within
+// the region, so that matching is done once rather than once per region.
+func matchClosingTags(src string) map[int]closingTag {
+	res := make(map[int]closingTag)
+	open := make(map[string][]int)
+	for i := strings.IndexByte(src, '<'); i != -1; {
+		if cm := closeTagRegexp.FindStringSubmatch(src[i:]); cm != nil {
+			name := strings.ToLower(cm[1])
+			c := closingTag{i, i + len(cm[0])}
+			if stack := open[name]; len(stack) > 0 {
+				if rawTags[name] {
+					for _, start := range stack {
+						res[start] = c
+					}
+					open[name] = stack[:0]
+				} else {
+					res[stack[len(stack)-1]] = c
+					open[name] = stack[:len(stack)-1]
+				}
+			}
+		} else if om := openTagRegexp.FindStringSubmatch(src[i:]); om != nil && om[3] == "" {
+			if name := strings.ToLower(om[1]); knownTags[name] && !voidTags[name] {
+				open[name] = append(open[name], i)
+			}
+		}
+		next := strings.IndexByte(src[i+1:], '<')
+		if next == -1 {
+			break
+		}
+		i += 1 + next
+	}
+	return res
+}
+
+// matchTag matches the tag opening at src[i] and its closing tag within
+// src[:end]. A known tag without a closing tag is treated as an empty tag,
+// except for tags whose contents are text, which are not tags in that case.
+func (p *parser) matchTag(i, end int) (tagMatch, bool) {
+	s := p.src[:end]
+	m := openTagRegexp.FindStringSubmatchIndex(s[i:])
+	if m == nil {
+		return tagMatch{}, false
+	}
+	name := strings.ToLower(s[i+m[2] : i+m[3]])
+	if !knownTags[name] {
+		return tagMatch{}, false
+	}
+	t := tagMatch{
+		name:        name,
+		attrs:       s[i+m[4] : i+m[5]],
+		selfClosing: m[7] > m[6],
+		start:       i,
+	}
+	openEnd := i + m[1]
+	t.contentStart, t.contentEnd, t.stop = openEnd, openEnd, openEnd
+	if t.selfClosing || voidTags[name] {
+		return t, true
+	}
+	if c, ok := p.closing[i]; ok && c.stop <= end {
+		t.contentEnd, t.stop = c.start, c.stop
+		return t, true
+	}
+	if rawTags[name] {
+		return tagMatch{}, false
+	}
+	return t, true
+}
+
+func (p *parser) parseTag(t tagMatch) *Tag {
+	tag := &Tag{
+		Span:        Span{t.start, t.stop},
+		Name:        t.name,
+		Attrs:       scan.ParseAttrs(t.attrs),
+		SelfClosing: t.selfClosing,
+	}
+	switch {
+	case t.contentEnd == t.contentStart:
+	case rawTags[t.name]:
+		tag.Children = []Node{&Text{
+			Span:  Span{t.contentStart, t.contentEnd},
+			Value: p.src[t.contentStart:t.contentEnd],
+		}}
+	default:
+		tag.Children = p.parseContent(t.contentStart, t.contentEnd)
+	}
+	return tag
+}
@@ -0,0 +1,65 @@
+package wikitext
+
+import (
+	"strings"
+
+	"github.com/sebnyberg/wikipedia/internal/scan"
+)
+
+// CategoryLink is a category membership declared in the text of a page,
+// e.g. [[Category:Capitals in Europe|Stockholm]].
+type CategoryLink struct {
+	// Name is the normalized name of the category, without the
+	// "Category:" prefix.
+	Name string
+
+	// SortKey is the key used to sort the page within the category.
+	// If no sort key is provided, SortKey is empty.
+	SortKey string
+}
+
+// ParseCategories returns the categories that the provided text declares
+// membership in, in order of appearance. Links to categories which do not
+// add the page to the category, such as [[:Category:Foo]], are ignored.
+func ParseCategories(text string) []CategoryLink {
+	var res []CategoryLink
+	for i := 0; i < len(text); {
+		switch {
+		case strings.HasPrefix(text[i:], "<!--"):
+			i = scan.MatchComment(text, i)
+		case strings.HasPrefix(text[i:], "[["):
+			end := scan.MatchLink(text, i)
+			if end == -1 {
+				i += 2
+				continue
+			}
+			parts := scan.SplitPipes(text[i+2 : end-2])
+			if name, ok := CategoryName(parts[0]); ok {
+				link := CategoryLink{Name: name}
+				if len(parts) > 1 {
+					link.SortKey = strings.TrimSpace(parts[1])
+				}
+				res = append(res, link)
+			}
+			// Categories may not be nested inside links, but links may
+			// be nested inside other links
+			i += 2
+		default:
+			i++
+		}
+	}
+	return res
+}
+
+// CategoryName returns the normalized name of the category with the
+// provided title, e.g. "Category:Capitals_in_Europe" returns
+// "Capitals in Europe". If the title is not in the category namespace,
+// false is returned.
+func CategoryName(title string) (string, bool) {
+	title = strings.TrimSpace(title)
+	if namespace(title) != "category" {
+		return "", false
+	}
+	name := normalizeTitle(title[strings.IndexByte(title, ':')+1:])
+	return name, name != ""
+}
@@ -0,0 +1,231 @@
+package wikitext
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia/internal/scan"
+)
+
+// Citation is a reference or citation template in an article.
+type Citation struct {
+	// RefName is the name of the <ref> tag containing the citation, or
+	// an empty string for unnamed references and citation templates
+	// outside of <ref> tags.
+	RefName string
+
+	// Reused is true if the citation is a reuse of a named reference,
+	// e.g. <ref name="a" />. The fields of a reused citation are copied
+	// from the definition of the reference.
+	Reused bool
+
+	// Template is the name of the citation template, e.g. "Cite web", or
+	// an empty string if the reference does not use a citation template.
+	Template string
+
+	Title     string
+	Authors   []string
+	Date      string
+	URL       string
+	DOI       string
+	ISBN      string
+	Publisher string
+
+	// Work is the name of the journal, newspaper or website containing
+	// the cited work.
+	Work string
+
+	// Text is the plain text of the reference.
+	Text string
+
+	// Offset is the byte offset of the citation in the text.
+	Offset int
+
+	// SentenceStart and SentenceEnd are the byte offsets of the sentence
+	// that the citation is attached to.
+	SentenceStart int
+	SentenceEnd   int
+}
+
+var (
+	refRegexp     = regexp.MustCompile(`(?is)<ref(\s[^>]*?)?(?:/>|>(.*?)</ref\s*>)`)
+	refNameRegexp = regexp.MustCompile(`(?i)\bname\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s/>]+))`)
+)
+
+// ParseCitations returns the references and citation templates in the
+// provided text, ordered by offset. Every use of a named reference is
+// returned as a separate citation.
+func ParseCitations(text string) []Citation {
+	masked := scan.MaskComments(text)
+	var res []Citation
+	defs := make(map[string]int)
+	var reuses []int
+
+	b := []byte(masked)
+	for _, m := range refRegexp.FindAllStringSubmatchIndex(masked, -1) {
+		c := Citation{Offset: m[0]}
+		if m[2] != -1 {
+			c.RefName = refName(masked[m[2]:m[3]])
+		}
+		if m[4] != -1 && strings.TrimSpace(masked[m[4]:m[5]]) != "" {
+			content := masked[m[4]:m[5]]
+			templates := ParseTemplates(content)
+			for _, t := range templates {
+				if IsCitationTemplate(t.Name) {
+					setCitationFields(&c, t)
+					break
+				}
+			}
+			c.Text = PlainText(content, PlainTextOptions{})
+			if c.RefName != "" {
+				if _, exists := defs[c.RefName]; !exists {
+					defs[c.RefName] = len(res)
+				}
+			}
+		} else {
+			c.Reused = true
+			reuses = append(reuses, len(res))
+		}
+		res = append(res, c)
+		for i := m[0]; i < m[1]; i++ {
+			if b[i] != '\n' {
+				b[i] = ' '
+			}
+		}
+	}
+
+	// Citation templates outside of references, e.g. in bibliographies
+	for _, t := range ParseTemplates(string(b)) {
+		if t.Depth == 0 && IsCitationTemplate(t.Name) {
+			c := Citation{Offset: t.Start}
+			setCitationFields(&c, t)
+			c.Text = PlainText(c.Title, PlainTextOptions{})
+			res = append(res, c)
+		}
+		if t.Depth == 0 {
+			for i := t.Start; i < t.End; i++ {
+				if b[i] != '\n' {
+					b[i] = ' '
+				}
+			}
+		}
+	}
+
+	for _, idx := range reuses {
+		def, ok := defs[res[idx].RefName]
+		if !ok {
+			continue
+		}
+		reuse := res[def]
+		reuse.Reused = true
+		reuse.Offset = res[idx].Offset
+		res[idx] = reuse
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Offset < res[j].Offset
+	})
+
+	masked = string(b)
+	for i := range res {
+		res[i].SentenceStart, res[i].SentenceEnd = citingSentence(masked, res[i].Offset)
+	}
+	return res
+}
+
+// refName returns the name attribute of a <ref> tag.
+func refName(attrs string) string {
+	m := refNameRegexp.FindStringSubmatch(attrs)
+	if m == nil {
+		return ""
+	}
+	for _, name := range m[1:] {
+		if name != "" {
+			return strings.TrimSpace(name)
+		}
+	}
+	return ""
+}
+
+// setCitationFields sets the fields of a citation from the arguments of a
+// citation template.
+func setCitationFields(c *Citation, t Template) {
+	arg := func(names ...string) string {
+		for _, name := range names {
+			if v, ok := t.Arg(name); ok && strings.TrimSpace(v) != "" {
+				return PlainText(v, PlainTextOptions{})
+			}
+		}
+		return ""
+	}
+	c.Template = t.Name
+	c.Title = arg("title", "chapter", "script-title")
+	c.Date = arg("date", "year")
+	c.URL = strings.TrimSpace(arg("url", "chapter-url", "chapterurl"))
+	c.DOI = arg("doi")
+	c.ISBN = arg("isbn", "ISBN")
+	c.Publisher = arg("publisher")
+	c.Work = arg("work", "journal", "newspaper", "website", "magazine", "periodical")
+	c.Authors = citationAuthors(arg)
+}
+
+// citationAuthors returns the authors of a citation template, which are
+// passed as last/first pairs, as author arguments, or as a list in the
+// authors or vauthors argument.
+func citationAuthors(arg func(names ...string) string) []string {
+	var res []string
+	author := func(n string) bool {
+		last := arg("last"+n, "surname"+n, "author-last"+n)
+		first := arg("first"+n, "given"+n, "author-first"+n)
+		switch {
+		case last != "" && first != "":
+			res = append(res, last+", "+first)
+		case last != "":
+			res = append(res, last)
+		default:
+			a := arg("author" + n)
+			if a == "" {
+				return false
+			}
+			res = append(res, a)
+		}
+		return true
+	}
+	author("")
+	// Numbered authors are consecutive
+	for i := 1; author(strconv.Itoa(i)); i++ {
+	}
+	if len(res) == 0 {
+		if authors := arg("authors", "vauthors"); authors != "" {
+			res = append(res, authors)
+		}
+	}
+	return res
+}
+
+// citingSentence returns the start and end offsets of the sentence which
+// precedes the provided offset. Markup such as references and templates are
+// expected to be masked in the provided text.
+func citingSentence(masked string, offset int) (int, int) {
+	// Citations are usually placed after the sentence punctuation, so
+	// the search for the previous sentence starts before it
+	end := len(strings.TrimRight(masked[:offset], " \t\n"))
+	s := strings.TrimRight(masked[:end], ".!?,;:")
+	start := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == '\n' {
+			start = i + 1
+			break
+		}
+		if strings.IndexByte(".!?", s[i]) != -1 && i+1 < len(s) && (s[i+1] == ' ' || s[i+1] == '\t') {
+			start = i + 1
+			break
+		}
+	}
+	for start < end && strings.IndexByte(" \t\n", masked[start]) != -1 {
+		start++
+	}
+	return start, end
+}
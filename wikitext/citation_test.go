@@ -0,0 +1,48 @@
+package wikitext_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+func Test_ParseCitations(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  []wikitext.Citation
+	}{
+		{"no citations", "text <!-- <ref>x</ref> -->", nil},
+		{"plain reference", "A sentence. Cited one.<ref>Smith 2001, p. 3</ref>", []wikitext.Citation{
+			{Text: "Smith 2001, p. 3", Offset: 22, SentenceStart: 12, SentenceEnd: 22},
+		}},
+		{"cite web", `Lead.<ref name="a">{{cite web |url=http://a.org |title=''A'' |last1=Doe |first1=J |last2=Roe |date=2001 |publisher=P |website=W}}</ref>`,
+			[]wikitext.Citation{{
+				RefName: "a", Template: "Cite web", Title: "A", Authors: []string{"Doe, J", "Roe"},
+				Date: "2001", URL: "http://a.org", Publisher: "P", Work: "W",
+				Offset: 5, SentenceEnd: 5,
+			}},
+		},
+		{"reused reference", "One.<ref name=b/> Two {{x}}.<ref name=\"b\">{{cite journal|title=T|doi=10.1/x|author=A}}</ref>",
+			[]wikitext.Citation{
+				{RefName: "b", Reused: true, Template: "Cite journal", Title: "T", DOI: "10.1/x",
+					Authors: []string{"A"}, Offset: 4, SentenceEnd: 4},
+				{RefName: "b", Template: "Cite journal", Title: "T", DOI: "10.1/x",
+					Authors: []string{"A"}, Offset: 28, SentenceStart: 18, SentenceEnd: 28},
+			},
+		},
+		{"bibliography", "== Sources ==\n* {{cite book|title=B|isbn=978-0|year=1999}}", []wikitext.Citation{
+			{Template: "Cite book", Title: "B", ISBN: "978-0", Date: "1999", Text: "B",
+				Offset: 16, SentenceStart: 14, SentenceEnd: 15},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := wikitext.ParseCitations(tc.input)
+			if !cmp.Equal(tc.want, got, cmpopts.EquateEmpty()) {
+				t.Errorf("invalid citations\n%v", cmp.Diff(tc.want, got, cmpopts.EquateEmpty()))
+			}
+		})
+	}
+}
@@ -0,0 +1,621 @@
+package wikitext
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/internal/scan"
+)
+
+// Expander expands templates, template parameters, parser functions and
+// magic words in wikitext. Templates are read from a PageStore, and looked
+// up by title through a TitleIndex.
+//
+// The core parser functions #if, #ifeq, #iferror, #ifexpr, #switch, #expr
+// and #tag are evaluated, as are simple string functions such as lc and
+// padleft. Other parser functions, such as #invoke, are left unexpanded.
+//
+// An Expander caches the text of the templates that it has read, and must
+// not be used concurrently.
+type Expander struct {
+	store  wikipedia.PageStore
+	titles wikipedia.TitleIndex
+
+	// MaxDepth is the maximum nesting depth of templates. Templates nested
+	// deeper than this are replaced by an error message, as in MediaWiki.
+	MaxDepth int
+
+	// MaxExpansions is the maximum number of templates and parser functions
+	// expanded per page. Invocations beyond the limit are left unexpanded.
+	MaxExpansions int
+
+	// SiteName is the value of the {{SITENAME}} magic word.
+	SiteName string
+
+	// Now returns the time used for magic words such as {{CURRENTYEAR}}.
+	Now func() time.Time
+
+	// templates caches the text of templates by title. Missing templates
+	// are cached as nil.
+	templates map[string]*string
+}
+
+// NewExpander returns an expander which reads templates from store.
+func NewExpander(store wikipedia.PageStore, titles wikipedia.TitleIndex) *Expander {
+	return &Expander{
+		store:         store,
+		titles:        titles,
+		MaxDepth:      40,
+		MaxExpansions: 20000,
+		SiteName:      "Wikipedia",
+		Now:           time.Now,
+		templates:     make(map[string]*string),
+	}
+}
+
+// Expand returns the text of the latest revision of a page with all
+// templates expanded.
+func (e *Expander) Expand(p *wikipedia.Page) (string, error) {
+	return e.ExpandText(p.Title, latestText(p))
+}
+
+// ExpandText expands the text of the page with the provided title. The
+// title is used for magic words such as {{PAGENAME}}.
+func (e *Expander) ExpandText(title, text string) (string, error) {
+	x := expansion{e: e, now: e.Now()}
+	res := x.expand(pageText(text), &frame{title: title})
+	return res, x.err
+}
+
+// expansion holds the state of expanding a single page.
+type expansion struct {
+	e     *Expander
+	now   time.Time
+	count int
+
+	// err is the first error returned by the page store
+	err error
+}
+
+// frame is a template being expanded, or the page itself.
+type frame struct {
+	title  string
+	args   map[string]*frameArg
+	parent *frame
+	depth  int
+}
+
+// frameArg is an argument passed to a template. Arguments are expanded in
+// the frame of the caller when they are first used.
+type frameArg struct {
+	raw      string
+	frame    *frame
+	expanded *string
+}
+
+func (x *expansion) arg(a *frameArg) string {
+	if a.expanded == nil {
+		v := x.expand(a.raw, a.frame)
+		a.expanded = &v
+	}
+	return *a.expanded
+}
+
+var (
+	noincludeRegexp    = regexp.MustCompile(`(?is)<noinclude\s*>.*?(?:</noinclude\s*>|$)`)
+	includeonlyRegexp  = regexp.MustCompile(`(?is)<includeonly\s*>.*?(?:</includeonly\s*>|$)`)
+	onlyincludeRegexp  = regexp.MustCompile(`(?is)<onlyinclude\s*>(.*?)(?:</onlyinclude\s*>|$)`)
+	inclusionTagRegexp = regexp.MustCompile(`(?i)</?(?:noinclude|includeonly|onlyinclude)\s*>`)
+	rawTagRegexp       = regexp.MustCompile(`(?i)^<(` + strings.Join(rawTagNames, "|") + `)(?:\s[^<>]*)?>`)
+	rawCloseRegexps    = make(map[string]*regexp.Regexp, len(rawTagNames))
+)
+
+// Tags whose contents are not expanded.
+var rawTagNames = []string{
+	"nowiki", "pre", "math", "syntaxhighlight", "source", "score", "timeline",
+	"chem", "ce", "hiero", "graph", "templatedata",
+}
+
+func init() {
+	for _, name := range rawTagNames {
+		rawCloseRegexps[name] = regexp.MustCompile(`(?i)</` + name + `\s*>`)
+	}
+}
+
+// pageText returns the text of a page as it is rendered on the page
+// itself, where <includeonly> sections are removed.
+func pageText(text string) string {
+	text = includeonlyRegexp.ReplaceAllString(text, "")
+	return inclusionTagRegexp.ReplaceAllString(text, "")
+}
+
+// transclusionText returns the text of a page as it is rendered when the
+// page is transcluded, where only <onlyinclude> sections are used if there
+// are any, and <noinclude> sections are removed.
+func transclusionText(text string) string {
+	if ms := onlyincludeRegexp.FindAllStringSubmatch(text, -1); ms != nil {
+		var sb strings.Builder
+		for _, m := range ms {
+			sb.WriteString(m[1])
+		}
+		text = sb.String()
+	}
+	text = noincludeRegexp.ReplaceAllString(text, "")
+	return inclusionTagRegexp.ReplaceAllString(text, "")
+}
+
+// expand expands the templates and parameters in s within frame f.
+// Comments are removed, and the contents of tags such as <nowiki> are
+// kept as is.
+func (x *expansion) expand(s string, f *frame) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "<!--"):
+			i = scan.MatchComment(s, i)
+		case s[i] == '<' && rawTagRegexp.MatchString(s[i:]):
+			m := rawTagRegexp.FindStringSubmatch(s[i:])
+			loc := rawCloseRegexps[strings.ToLower(m[1])].FindStringIndex(s[i+len(m[0]):])
+			if loc == nil {
+				sb.WriteByte(s[i])
+				i++
+				continue
+			}
+			end := i + len(m[0]) + loc[1]
+			sb.WriteString(s[i:end])
+			i = end
+		case strings.HasPrefix(s[i:], "{{"):
+			end := scan.MatchBraces(s, i)
+			if end == -1 {
+				sb.WriteString("{{")
+				i += 2
+				continue
+			}
+			if strings.HasPrefix(s[i:], "{{{") && strings.HasSuffix(s[:end], "}}}") {
+				sb.WriteString(x.parameter(s[i+3:end-3], f))
+			} else {
+				sb.WriteString(x.invocation(s[i:end], f))
+			}
+			i = end
+		default:
+			sb.WriteByte(s[i])
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// parameter expands the template parameter with the provided contents,
+// e.g. "1|default".
+func (x *expansion) parameter(inner string, f *frame) string {
+	parts := scan.SplitPipes(inner)
+	name := strings.TrimSpace(x.expand(parts[0], f))
+	if a, ok := f.args[name]; ok {
+		return x.arg(a)
+	}
+	if len(parts) > 1 {
+		return x.expand(parts[1], f)
+	}
+	return "{{{" + name + "}}}"
+}
+
+// invocation expands the template, parser function or magic word s,
+// including its braces.
+func (x *expansion) invocation(s string, f *frame) string {
+	if x.count >= x.e.MaxExpansions {
+		return s
+	}
+	x.count++
+
+	parts := scan.SplitPipes(s[2 : len(s)-2])
+	name := strings.TrimSpace(x.expand(parts[0], f))
+	for _, prefix := range []string{"subst:", "safesubst:"} {
+		if len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix) {
+			name = strings.TrimSpace(name[len(prefix):])
+		}
+	}
+
+	if idx := strings.IndexByte(name, ':'); idx != -1 {
+		fn := strings.TrimSpace(name[:idx])
+		if IsParserFunction(fn) {
+			args := &funcArgs{x: x, f: f, first: strings.TrimSpace(name[idx+1:]), raw: parts[1:]}
+			if res, ok := x.parserFunction(strings.ToLower(fn), args); ok {
+				return res
+			}
+			return s
+		}
+	}
+	if len(parts) == 1 {
+		if v, ok := x.variable(name, f); ok {
+			return v
+		}
+	}
+	return x.template(name, parts[1:], f)
+}
+
+// template expands the template with the provided name and raw arguments.
+func (x *expansion) template(name string, rawArgs []string, f *frame) string {
+	title := templateTitle(name)
+	if title == "" {
+		return "{{" + name + "}}"
+	}
+	for p := f; p != nil; p = p.parent {
+		if p.title == title {
+			return `<span class="error">Template loop detected: [[` + title + `]]</span>`
+		}
+	}
+	if f.depth >= x.e.MaxDepth {
+		return `<span class="error">Expansion depth limit exceeded: [[` + title + `]]</span>`
+	}
+	text, ok := x.templateText(title)
+	if !ok {
+		return "[[:" + title + "]]"
+	}
+
+	args := make(map[string]*frameArg, len(rawArgs))
+	pos := 0
+	for _, raw := range rawArgs {
+		if idx := scan.IndexTopLevel(raw, '='); idx != -1 {
+			key := strings.TrimSpace(x.expand(raw[:idx], f))
+			args[key] = &frameArg{raw: strings.TrimSpace(raw[idx+1:]), frame: f}
+			continue
+		}
+		pos++
+		args[strconv.Itoa(pos)] = &frameArg{raw: raw, frame: f}
+	}
+	return x.expand(transclusionText(text), &frame{
+		title:  title,
+		args:   args,
+		parent: f,
+		depth:  f.depth + 1,
+	})
+}
+
+// templateTitle returns the title of the page transcluded by a template
+// name, e.g. "Template:Infobox settlement" for "infobox_settlement".
+// Names starting with a colon refer to pages in the main namespace, and
+// names with a namespace prefix such as "Category:" or "File:" to pages in
+// that namespace.
+func templateTitle(name string) string {
+	if _, ok := canonicalNamespaces[namespace(name)]; ok || strings.HasPrefix(name, ":") {
+		return NormalizeTitle(name)
+	}
+	if name = normalizeTitle(name); name == "" {
+		return ""
+	}
+	return "Template:" + name
+}
+
+// templateText returns the text of the page with the provided title,
+// following redirects.
+func (x *expansion) templateText(title string) (string, bool) {
+	if text, ok := x.e.templates[title]; ok {
+		return derefText(text)
+	}
+	orig := title
+	var text *string
+	for i := 0; i < 5; i++ {
+		id, ok := x.e.titles[title]
+		if !ok {
+			break
+		}
+		p, err := x.e.store.Get(id)
+		if err != nil {
+			if x.err == nil {
+				x.err = err
+			}
+			return "", false
+		}
+		if p.RedirectTitle == "" {
+			t := latestText(&p)
+			text = &t
+			break
+		}
+		title = p.RedirectTitle
+	}
+	x.e.templates[orig] = text
+	return derefText(text)
+}
+
+func derefText(text *string) (string, bool) {
+	if text == nil {
+		return "", false
+	}
+	return *text, true
+}
+
+// variable returns the value of a magic word without arguments, e.g.
+// {{PAGENAME}}, and whether the name is a known magic word.
+func (x *expansion) variable(name string, f *frame) (string, bool) {
+	title := x.pageTitle(f)
+	pagename := title
+	ns := ""
+	if idx := strings.IndexByte(title, ':'); idx != -1 {
+		if canonical, ok := canonicalNamespaces[namespace(title)]; ok {
+			ns, pagename = canonical, title[idx+1:]
+		}
+	}
+	now := x.now.UTC()
+	switch name {
+	case "!":
+		return "|", true
+	case "=":
+		return "=", true
+	case "PAGENAME":
+		return pagename, true
+	case "PAGENAMEE":
+		return url.PathEscape(strings.ReplaceAll(pagename, " ", "_")), true
+	case "FULLPAGENAME":
+		return title, true
+	case "BASEPAGENAME":
+		if idx := strings.LastIndexByte(pagename, '/'); idx != -1 {
+			return pagename[:idx], true
+		}
+		return pagename, true
+	case "SUBPAGENAME":
+		return pagename[strings.LastIndexByte(pagename, '/')+1:], true
+	case "NAMESPACE":
+		return ns, true
+	case "SITENAME":
+		return x.e.SiteName, true
+	case "CURRENTYEAR":
+		return strconv.Itoa(now.Year()), true
+	case "CURRENTMONTH", "CURRENTMONTH2":
+		return now.Format("01"), true
+	case "CURRENTMONTH1":
+		return strconv.Itoa(int(now.Month())), true
+	case "CURRENTMONTHNAME":
+		return now.Month().String(), true
+	case "CURRENTMONTHABBREV":
+		return now.Format("Jan"), true
+	case "CURRENTDAY":
+		return strconv.Itoa(now.Day()), true
+	case "CURRENTDAY2":
+		return now.Format("02"), true
+	case "CURRENTDAYNAME":
+		return now.Weekday().String(), true
+	case "CURRENTTIME":
+		return now.Format("15:04"), true
+	case "CURRENTHOUR":
+		return now.Format("15"), true
+	case "CURRENTTIMESTAMP":
+		return now.Format("20060102150405"), true
+	}
+	return "", false
+}
+
+// pageTitle returns the title of the page being expanded, i.e. the title
+// of the outermost frame.
+func (x *expansion) pageTitle(f *frame) string {
+	for f.parent != nil {
+		f = f.parent
+	}
+	return f.title
+}
+
+// funcArgs holds the arguments of a parser function. The first argument
+// is the text after the colon, and the others are expanded when used.
+type funcArgs struct {
+	x     *expansion
+	f     *frame
+	first string
+	raw   []string
+}
+
+func (a *funcArgs) len() int {
+	return len(a.raw) + 1
+}
+
+// get returns the i:th argument, expanded and trimmed, or an empty string
+// if there is no such argument.
+func (a *funcArgs) get(i int) string {
+	switch {
+	case i == 0:
+		return a.first
+	case i > len(a.raw):
+		return ""
+	}
+	return strings.TrimSpace(a.x.expand(a.raw[i-1], a.f))
+}
+
+// parserFunction evaluates a parser function, and returns false if the
+// function is not supported.
+func (x *expansion) parserFunction(fn string, a *funcArgs) (string, bool) {
+	switch fn {
+	case "#if":
+		if a.first != "" {
+			return a.get(1), true
+		}
+		return a.get(2), true
+	case "#ifeq":
+		if equalValues(a.first, a.get(1)) {
+			return a.get(2), true
+		}
+		return a.get(3), true
+	case "#iferror":
+		if strings.Contains(a.first, `class="error"`) {
+			return a.get(1), true
+		}
+		if a.len() > 2 {
+			return a.get(2), true
+		}
+		return a.first, true
+	case "#ifexpr":
+		v, err := evalExpr(a.first)
+		switch {
+		case err != nil:
+			return exprError(err), true
+		case v != "" && v != "0":
+			return a.get(1), true
+		}
+		return a.get(2), true
+	case "#expr":
+		v, err := evalExpr(a.first)
+		if err != nil {
+			return exprError(err), true
+		}
+		return v, true
+	case "#switch":
+		return x.switchFunction(a), true
+	case "#tag":
+		return tagFunction(a), true
+	case "lc":
+		return strings.ToLower(a.first), true
+	case "uc":
+		return strings.ToUpper(a.first), true
+	case "lcfirst":
+		return lowerFirst(a.first), true
+	case "ucfirst":
+		return upperFirst(a.first), true
+	case "urlencode":
+		return url.QueryEscape(a.first), true
+	case "anchorencode":
+		return strings.ReplaceAll(a.first, " ", "_"), true
+	case "padleft", "padright":
+		return pad(a.first, a.get(1), a.get(2), fn == "padleft"), true
+	case "formatnum":
+		return formatNum(a.first), true
+	case "displaytitle", "defaultsort":
+		return "", true
+	case "int":
+		// Interface messages are not available, so the message name is
+		// returned
+		return a.first, true
+	case "grammar", "plural":
+		// Language rules are not available, so the first form is used
+		return a.get(1), true
+	}
+	return "", false
+}
+
+// switchFunction evaluates {{#switch:value|case=result|...|default}}.
+// Cases without a result fall through to the next case with a result.
+func (x *expansion) switchFunction(a *funcArgs) string {
+	found := false
+	def := ""
+	for i, raw := range a.raw {
+		idx := scan.IndexTopLevel(raw, '=')
+		if idx == -1 {
+			key := strings.TrimSpace(x.expand(raw, a.f))
+			if i == len(a.raw)-1 {
+				// The last case without a result is the default
+				return key
+			}
+			if equalValues(key, a.first) {
+				found = true
+			}
+			continue
+		}
+		key := strings.TrimSpace(x.expand(raw[:idx], a.f))
+		if found || equalValues(key, a.first) {
+			return strings.TrimSpace(x.expand(raw[idx+1:], a.f))
+		}
+		if key == "#default" {
+			def = raw[idx+1:]
+		}
+	}
+	return strings.TrimSpace(x.expand(def, a.f))
+}
+
+// equalValues compares two values numerically if both are numbers, and
+// as strings otherwise.
+func equalValues(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		return fa == fb
+	}
+	return a == b
+}
+
+func exprError(err error) string {
+	return `<strong class="error">Expression error: ` + err.Error() + `</strong>`
+}
+
+// tagFunction evaluates {{#tag:name|contents|attr=value}}.
+func tagFunction(a *funcArgs) string {
+	name := strings.ToLower(a.first)
+	var sb strings.Builder
+	sb.WriteString("<" + name)
+	for i := 2; i < a.len(); i++ {
+		attr := a.get(i)
+		if idx := strings.IndexByte(attr, '='); idx != -1 {
+			value := strings.Trim(strings.TrimSpace(attr[idx+1:]), `"'`)
+			sb.WriteString(" " + strings.TrimSpace(attr[:idx]) + `="` + value + `"`)
+		}
+	}
+	sb.WriteString(">" + a.get(1) + "</" + name + ">")
+	return sb.String()
+}
+
+// maxPadLength is the maximum length of padleft and padright, as in
+// MediaWiki.
+const maxPadLength = 500
+
+// pad pads s to length n with the provided padding, which defaults to '0'.
+// Lengths above maxPadLength are reduced to it.
+func pad(s, length, padding string, left bool) string {
+	n, err := strconv.Atoi(length)
+	if err != nil {
+		return s
+	}
+	if n > maxPadLength {
+		n = maxPadLength
+	}
+	if padding == "" {
+		padding = "0"
+	}
+	missing := n - len([]rune(s))
+	if missing <= 0 {
+		return s
+	}
+	var fill []rune
+	for p := []rune(padding); len(fill) < missing; {
+		fill = append(fill, p[len(fill)%len(p)])
+	}
+	if left {
+		return string(fill) + s
+	}
+	return s + string(fill)
+}
+
+// formatNum adds thousands separators to the integer part of a number.
+// Numbers which are not plain decimals, such as "1e10", are returned
+// unchanged.
+func formatNum(s string) string {
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
+		return s
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	intPart, frac := s, ""
+	if idx := strings.IndexByte(s, '.'); idx != -1 {
+		intPart, frac = s[:idx], s[idx:]
+	}
+	if !isDigits(intPart) || frac != "" && !isDigits(frac[1:]) {
+		return sign + s
+	}
+	var sb strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(c)
+	}
+	return sign + sb.String() + frac
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
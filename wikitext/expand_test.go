@@ -0,0 +1,181 @@
+package wikitext_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+// memStore is a page store holding pages in memory.
+type memStore map[int32]*wikipedia.Page
+
+func (s memStore) Get(id int32) (wikipedia.Page, error) {
+	p, ok := s[id]
+	if !ok {
+		return wikipedia.Page{}, errors.New("not found")
+	}
+	return wikipedia.Page{
+		Id:            p.Id,
+		Title:         p.Title,
+		RedirectTitle: p.RedirectTitle,
+		Revisions:     p.Revisions,
+	}, nil
+}
+
+func (s memStore) Set(id int32, p *wikipedia.Page) error {
+	s[id] = p
+	return nil
+}
+
+// newTestStore returns a store and title index holding the provided
+// pages, keyed by title. Values starting with "#REDIRECT " are redirects.
+func newTestStore(pages map[string]string) (memStore, wikipedia.TitleIndex) {
+	store := make(memStore)
+	titles := make(wikipedia.TitleIndex)
+	var id int32
+	for title, text := range pages {
+		id++
+		p := &wikipedia.Page{Id: id, Title: title}
+		if len(text) > 10 && text[:10] == "#REDIRECT " {
+			p.RedirectTitle = text[10:]
+		} else {
+			p.Revisions = []*wikipedia.Revision{{Text: text}}
+		}
+		store[id] = p
+		titles[title] = id
+	}
+	return store, titles
+}
+
+func Test_Expander(t *testing.T) {
+	store, titles := newTestStore(map[string]string{
+		"Template:Greet":    "Hello, {{{1|world}}}{{{punct|!}}}",
+		"Template:Hi":       "#REDIRECT Template:Greet",
+		"Template:Loop":     "a{{loop}}",
+		"Template:Doc":      "<noinclude>docs</noinclude>body<includeonly>!</includeonly>",
+		"Template:Only":     "x<onlyinclude>y</onlyinclude>z",
+		"Template:Outer":    "{{inner|{{{1}}}}}",
+		"Template:Inner":    "[{{{1}}}]",
+		"Template:Lang":     "{{#switch:{{{1}}}|sv|se=Swedish|en=English|#default=Other}}",
+		"Template:Pipe":     "a{{!}}b",
+		"Wikipedia:Sandbox": "sand",
+	})
+	for _, tc := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"no templates", "plain [[text]]", "plain [[text]]"},
+		{"default parameter", "{{Greet}}", "Hello, world!"},
+		{"positional and named", "{{greet| you |punct=?}}", "Hello,  you ?"},
+		{"redirect", "{{Hi|there}}", "Hello, there!"},
+		{"missing template", "{{Nope}}", "[[:Template:Nope]]"},
+		{"loop", "{{Loop}}", `a<span class="error">Template loop detected: [[Template:Loop]]</span>`},
+		{"noinclude", "{{Doc}}", "body!"},
+		{"onlyinclude", "{{Only}}", "y"},
+		{"nested arguments", "{{Outer|x}}", "[x]"},
+		{"namespace", "{{Wikipedia:Sandbox}}", "sand"},
+		{"switch", "{{Lang|sv}} {{Lang|se}} {{Lang|en}} {{Lang|fi}}", "Swedish Swedish English Other"},
+		{"magic words", "{{PAGENAME}} {{CURRENTYEAR}} {{Pipe}}", "Test page 2020 a|b"},
+		{"if", "{{#if: x | yes | no }}{{#if: | yes | no }}", "yesno"},
+		{"ifeq", "{{#ifeq: 01 | 1 | eq | ne }}{{#ifeq: a | b | eq | ne }}", "eqne"},
+		{"expr", "{{#expr: 2 * (3 + 4) }} {{#expr: 10 / 4 }} {{#expr: 7 mod 3 }}", "14 2.5 1"},
+		{"expr round", "{{#expr: 2/3 round 2 }}", "0.67"},
+		{"expr error", "{{#expr: 1/0 }}", `<strong class="error">Expression error: Division by zero.</strong>`},
+		{"ifexpr", "{{#ifexpr: 3 > 2 and not 0 | yes | no }}", "yes"},
+		{"iferror", "{{#iferror: {{#expr: x }} | bad | good }}", "bad"},
+		{"string functions", "{{lc:ABC}} {{ucfirst:abc}} {{padleft:7|3}} {{formatnum:1234567.5}}", "abc Abc 007 1,234,567.5"},
+		{"first letter", "{{lcfirst:Émile}} {{ucfirst:émile}} {{lcfirst:}}", "émile Émile "},
+		{"pad limit", "{{padleft:x|50000000}}", strings.Repeat("0", 499) + "x"},
+		{"formatnum exponent", "{{formatnum:1e10}} {{formatnum:-12345}}", "1e10 -12,345"},
+		{"tag", "{{#tag:ref|text|name=a}}", `<ref name="a">text</ref>`},
+		{"unsupported", "{{#invoke:Mod|f}}", "{{#invoke:Mod|f}}"},
+		{"comments and nowiki", "a<!-- {{Greet}} --><nowiki>{{Greet}}</nowiki>", "a<nowiki>{{Greet}}</nowiki>"},
+		{"unbalanced", "{{Greet", "{{Greet"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := wikitext.NewExpander(store, titles)
+			e.Now = func() time.Time {
+				return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			}
+			got, err := e.ExpandText("Test page", tc.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("ExpandText(%q)\ngot:  %q\nwant: %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_ExpanderNamespaces(t *testing.T) {
+	store, titles := newTestStore(map[string]string{
+		"Template:X":   "template",
+		"X":            "main",
+		"Category:X":   "category",
+		"File:X":       "file",
+		"Talk:X":       "talk",
+		"Template:Nsp": "{{NAMESPACE}}",
+	})
+	for _, tc := range []struct {
+		name  string
+		title string
+		in    string
+		want  string
+	}{
+		{"template", "Page", "{{X}} {{Template:X}} {{template:x}}", "template template template"},
+		{"main", "Page", "{{:X}} {{:x}}", "main main"},
+		{"category", "Page", "{{Category:X}} {{category:x}} {{:Category:X}}", "category category category"},
+		{"file", "Page", "{{File:X}} {{Image:X}}", "file file"},
+		{"talk", "Page", "{{Talk:X}}", "talk"},
+		{"missing", "Page", "{{Category:Y}} {{:Y}}", "[[:Category:Y]] [[:Y]]"},
+		{"unknown prefix", "Page", "{{Foo:X}}", "[[:Template:Foo:X]]"},
+		{"namespace of main page", "Page", "{{NAMESPACE}}", ""},
+		{"namespace of category", "Category:Foo", "{{NAMESPACE}} {{PAGENAME}}", "Category Foo"},
+		{"namespace alias", "Image:Foo.png", "{{NAMESPACE}}", "File"},
+		{"namespace of unknown prefix", "Foo:Bar", "{{NAMESPACE}} {{PAGENAME}}", " Foo:Bar"},
+		{"namespace in template", "Help:Foo", "{{Nsp}}", "Help"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := wikitext.NewExpander(store, titles)
+			got, err := e.ExpandText(tc.title, tc.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("ExpandText(%q)\ngot:  %q\nwant: %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_ExpanderDepth(t *testing.T) {
+	store, titles := newTestStore(map[string]string{
+		"Template:A": "{{B}}",
+		"Template:B": "{{C}}",
+		"Template:C": "c",
+	})
+	e := wikitext.NewExpander(store, titles)
+	e.MaxDepth = 2
+	got, err := e.ExpandText("Page", "{{A}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<span class="error">Expansion depth limit exceeded: [[Template:C]]</span>`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func Test_ExpanderStoreError(t *testing.T) {
+	_, titles := newTestStore(map[string]string{"Template:A": "a"})
+	e := wikitext.NewExpander(make(memStore), titles)
+	if _, err := e.ExpandText("Page", "{{A}}"); err == nil {
+		t.Error("expected an error from the store")
+	}
+}
@@ -0,0 +1,256 @@
+package wikitext
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// exprParser evaluates expressions of the #expr parser function, e.g.
+// "2 * (3 + 4) round 1". Operators and their precedence follow MediaWiki.
+type exprParser struct {
+	toks []string
+	pos  int
+}
+
+var errDivisionByZero = errors.New("Division by zero.")
+
+// Precedence of binary operators, from lowest to highest.
+var exprBinary = map[string]int{
+	"or":    1,
+	"and":   2,
+	"=":     3,
+	"!=":    3,
+	"<>":    3,
+	"<":     3,
+	">":     3,
+	"<=":    3,
+	">=":    3,
+	"round": 4,
+	"+":     5,
+	"-":     5,
+	"*":     6,
+	"/":     6,
+	"div":   6,
+	"mod":   6,
+	"fmod":  6,
+	"^":     7,
+}
+
+// Unary functions, which bind tighter than all binary operators
+// except '^'.
+var exprFuncs = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"trunc": math.Trunc,
+	"sqrt":  math.Sqrt,
+	"exp":   math.Exp,
+	"ln":    math.Log,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+	"asin":  math.Asin,
+	"acos":  math.Acos,
+	"atan":  math.Atan,
+}
+
+// evalExpr evaluates an expression. An empty expression evaluates to an
+// empty string.
+func evalExpr(s string) (string, error) {
+	toks, err := tokenizeExpr(s)
+	if err != nil || len(toks) == 0 {
+		return "", err
+	}
+	p := exprParser{toks: toks}
+	v, err := p.parse(1)
+	if err != nil {
+		return "", err
+	}
+	if p.pos < len(p.toks) {
+		return "", fmt.Errorf("Unexpected %v.", p.toks[p.pos])
+	}
+	return formatNumber(v), nil
+}
+
+func tokenizeExpr(s string) ([]string, error) {
+	var toks []string
+	s = strings.ToLower(s)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			// Scientific notation, e.g. 1e3 or 1.5e-2
+			if j+1 < len(s) && s[j] == 'e' && (s[j+1] >= '0' && s[j+1] <= '9' || (s[j+1] == '-' || s[j+1] == '+') && j+2 < len(s) && s[j+2] >= '0' && s[j+2] <= '9') {
+				j += 2
+				for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+					j++
+				}
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		case c >= 'a' && c <= 'z':
+			j := i
+			for j < len(s) && s[j] >= 'a' && s[j] <= 'z' {
+				j++
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		case strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">=") ||
+			strings.HasPrefix(s[i:], "<>") || strings.HasPrefix(s[i:], "!="):
+			toks = append(toks, s[i:i+2])
+			i += 2
+		case strings.IndexByte("+-*/^()=<>", c) != -1:
+			toks = append(toks, s[i:i+1])
+			i++
+		default:
+			return nil, fmt.Errorf("Unrecognized punctuation character %q.", c)
+		}
+	}
+	return toks, nil
+}
+
+// parse parses binary operations with a precedence of at least minPrec.
+func (p *exprParser) parse(minPrec int) (float64, error) {
+	lhs, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for p.pos < len(p.toks) {
+		op := p.toks[p.pos]
+		prec, ok := exprBinary[op]
+		if !ok || prec < minPrec {
+			break
+		}
+		p.pos++
+		rhs, err := p.parse(prec + 1)
+		if err != nil {
+			return 0, err
+		}
+		if lhs, err = applyBinary(op, lhs, rhs); err != nil {
+			return 0, err
+		}
+	}
+	return lhs, nil
+}
+
+func (p *exprParser) unary() (float64, error) {
+	if p.pos >= len(p.toks) {
+		return 0, errors.New("Missing operand.")
+	}
+	tok := p.toks[p.pos]
+	p.pos++
+	switch {
+	case tok == "-" || tok == "+":
+		v, err := p.parse(exprBinary["^"])
+		if tok == "-" {
+			v = -v
+		}
+		return v, err
+	case tok == "not":
+		v, err := p.parse(exprBinary["^"])
+		return boolFloat(v == 0), err
+	case exprFuncs[tok] != nil:
+		v, err := p.parse(exprBinary["^"])
+		return exprFuncs[tok](v), err
+	case tok == "(":
+		v, err := p.parse(1)
+		if err != nil {
+			return 0, err
+		}
+		if p.pos >= len(p.toks) || p.toks[p.pos] != ")" {
+			return 0, errors.New("Missing closing parenthesis.")
+		}
+		p.pos++
+		return v, nil
+	case tok == "e":
+		return math.E, nil
+	case tok == "pi":
+		return math.Pi, nil
+	}
+	v, err := strconv.ParseFloat(tok, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Unrecognized word %q.", tok)
+	}
+	return v, nil
+}
+
+func applyBinary(op string, a, b float64) (float64, error) {
+	switch op {
+	case "or":
+		return boolFloat(a != 0 || b != 0), nil
+	case "and":
+		return boolFloat(a != 0 && b != 0), nil
+	case "=":
+		return boolFloat(a == b), nil
+	case "!=", "<>":
+		return boolFloat(a != b), nil
+	case "<":
+		return boolFloat(a < b), nil
+	case ">":
+		return boolFloat(a > b), nil
+	case "<=":
+		return boolFloat(a <= b), nil
+	case ">=":
+		return boolFloat(a >= b), nil
+	case "round":
+		scale := math.Pow(10, math.Trunc(b))
+		return math.Round(a*scale) / scale, nil
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/", "div":
+		if b == 0 {
+			return 0, errDivisionByZero
+		}
+		return a / b, nil
+	case "mod":
+		// mod truncates its operands to integers
+		if math.Trunc(b) == 0 {
+			return 0, errDivisionByZero
+		}
+		return math.Mod(math.Trunc(a), math.Trunc(b)), nil
+	case "fmod":
+		if b == 0 {
+			return 0, errDivisionByZero
+		}
+		return math.Mod(a, b), nil
+	case "^":
+		return math.Pow(a, b), nil
+	}
+	return 0, fmt.Errorf("Unexpected %v operator.", op)
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// formatNumber formats the result of an expression. Integers are formatted
+// without decimals.
+func formatNumber(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "INF"
+	case math.IsInf(v, -1):
+		return "-INF"
+	case math.IsNaN(v):
+		return "NAN"
+	case v == math.Trunc(v) && math.Abs(v) < 1e15:
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'g', 14, 64)
+}
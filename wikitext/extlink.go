@@ -0,0 +1,146 @@
+package wikitext
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia/internal/scan"
+)
+
+// ExternalLink is a link to a page outside of the wiki.
+type ExternalLink struct {
+	URL string
+
+	// Label is the anchor text of the link. For links in citation
+	// templates, the label is the title of the cited work.
+	Label string
+
+	// Section is the heading of the section containing the link,
+	// or an empty string for the lead section.
+	Section string
+
+	// Template is the name of the citation template containing the link,
+	// or an empty string if the link is not part of a citation template.
+	Template string
+
+	// Offset is the byte offset of the link in the text.
+	Offset int
+}
+
+var (
+	bracketedLinkRegexp = regexp.MustCompile(`\[((?:https?:|ftp:)?//[^\s\]<>"]+)(?:[ \t]+([^\]\n]*))?\]`)
+	bareURLRegexp       = regexp.MustCompile(`(?:https?|ftp)://[^\s<>\[\]{}|"]+`)
+)
+
+// Arguments of citation templates that hold URLs.
+var citationURLArgs = []string{"url", "chapter-url", "chapterurl", "archive-url", "archiveurl"}
+
+// ParseExternalLinks returns the external links in the provided text,
+// ordered by their offset. Bracketed links ([http://example.com label]),
+// bare URLs and URLs in citation templates are included.
+func ParseExternalLinks(text string) []ExternalLink {
+	masked := scan.MaskComments(text)
+	var res []ExternalLink
+
+	// URLs in citation templates are parsed from their arguments, and the
+	// templates are masked so that their URLs are not found again
+	b := []byte(masked)
+	for _, t := range ParseTemplates(masked) {
+		if !IsCitationTemplate(t.Name) {
+			continue
+		}
+		title, _ := t.Arg("title")
+		for _, name := range citationURLArgs {
+			u, ok := t.Arg(name)
+			if !ok || u == "" {
+				continue
+			}
+			res = append(res, ExternalLink{
+				URL:      u,
+				Label:    PlainText(title, PlainTextOptions{}),
+				Template: t.Name,
+				Offset:   t.Start,
+			})
+		}
+		for i := t.Start; i < t.End; i++ {
+			b[i] = ' '
+		}
+	}
+	masked = string(b)
+
+	for _, m := range bracketedLinkRegexp.FindAllStringSubmatchIndex(masked, -1) {
+		link := ExternalLink{
+			URL:    masked[m[2]:m[3]],
+			Offset: m[0],
+		}
+		if m[4] != -1 {
+			link.Label = PlainText(masked[m[4]:m[5]], PlainTextOptions{})
+		}
+		res = append(res, link)
+		for i := m[0]; i < m[1]; i++ {
+			b[i] = ' '
+		}
+	}
+	masked = string(b)
+
+	for _, m := range bareURLRegexp.FindAllStringIndex(masked, -1) {
+		res = append(res, ExternalLink{
+			URL:    trimURL(masked[m[0]:m[1]]),
+			Offset: m[0],
+		})
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Offset < res[j].Offset
+	})
+
+	sections := SplitSections(text)
+	for i := range res {
+		res[i].Section = sectionAt(sections, res[i].Offset).Heading
+	}
+	return res
+}
+
+// IsCitationTemplate returns true if the template with the provided
+// name is a citation template, e.g. {{Cite web}} or {{Citation}}.
+func IsCitationTemplate(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "cite ") || name == "citation" || name == "cite"
+}
+
+// Domain returns the host name of a URL in lower case, without any
+// leading "www.". If the URL cannot be parsed, an empty string is returned.
+func Domain(rawurl string) string {
+	if strings.HasPrefix(rawurl, "//") {
+		rawurl = "http:" + rawurl
+	}
+	u, err := url.Parse(strings.TrimSpace(rawurl))
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	return strings.TrimPrefix(host, "www.")
+}
+
+// trimURL removes trailing punctuation which is not considered part of
+// a bare URL.
+func trimURL(u string) string {
+	u = strings.TrimRight(u, ".,;:!?'")
+	if strings.HasSuffix(u, ")") && !strings.Contains(u, "(") {
+		u = strings.TrimRight(u, ")")
+	}
+	return u
+}
+
+// sectionAt returns the section containing the provided offset.
+func sectionAt(sections []Section, offset int) Section {
+	idx := sort.Search(len(sections), func(i int) bool {
+		return sections[i].Start > offset
+	})
+	if idx == 0 {
+		return sections[0]
+	}
+	return sections[idx-1]
+}
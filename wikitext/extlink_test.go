@@ -0,0 +1,55 @@
+package wikitext_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+func Test_ParseExternalLinks(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  []wikitext.ExternalLink
+	}{
+		{"no links", "[[Stockholm]] <!-- http://a.b -->", nil},
+		{"bracketed", "see [https://example.com/a ''Example''] and [//b.org]", []wikitext.ExternalLink{
+			{URL: "https://example.com/a", Label: "Example", Offset: 4},
+			{URL: "//b.org", Offset: 44},
+		}},
+		{"bare", "lead\n== Links ==\nVisit http://a.org/x. Or (http://b.org/y)", []wikitext.ExternalLink{
+			{URL: "http://a.org/x", Section: "Links", Offset: 23},
+			{URL: "http://b.org/y", Section: "Links", Offset: 43},
+		}},
+		{"citation", "a<ref>{{cite web |url=http://c.org |title=[[C]] |archive-url=http://archive.org/c}}</ref>",
+			[]wikitext.ExternalLink{
+				{URL: "http://c.org", Label: "C", Template: "Cite web", Offset: 6},
+				{URL: "http://archive.org/c", Label: "C", Template: "Cite web", Offset: 6},
+			}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := wikitext.ParseExternalLinks(tc.input)
+			if !cmp.Equal(tc.want, got, cmpopts.EquateEmpty()) {
+				t.Errorf("invalid links\n%v", cmp.Diff(tc.want, got, cmpopts.EquateEmpty()))
+			}
+		})
+	}
+}
+
+func Test_Domain(t *testing.T) {
+	for _, tc := range []struct {
+		url  string
+		want string
+	}{
+		{"https://www.BBC.co.uk/news", "bbc.co.uk"},
+		{"//archive.org/web", "archive.org"},
+		{"http://a.org:8080", "a.org"},
+		{"%%", ""},
+	} {
+		if got := wikitext.Domain(tc.url); got != tc.want {
+			t.Errorf("invalid domain for %v, expected: %v, got: %v", tc.url, tc.want, got)
+		}
+	}
+}
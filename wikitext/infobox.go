@@ -0,0 +1,94 @@
+package wikitext
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Infobox is an infobox template, e.g. {{Infobox settlement|...}}.
+type Infobox struct {
+	// Type is the name of the infobox template without the "Infobox"
+	// prefix, in lower case, e.g. "settlement".
+	Type string
+
+	// Fields contains the non-empty named arguments of the infobox.
+	Fields []InfoboxField
+}
+
+// InfoboxField is a key/value pair in an infobox.
+type InfoboxField struct {
+	Key string
+
+	// Raw is the wikitext value of the field.
+	Raw string
+
+	// Value is the plain text value of the field. Line breaks and list
+	// items in the value are separated by "; ".
+	Value string
+}
+
+// Get returns the field with the provided key, and whether it exists.
+func (b *Infobox) Get(key string) (InfoboxField, bool) {
+	for _, f := range b.Fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return InfoboxField{}, false
+}
+
+var lineBreakRegexp = regexp.MustCompile(`(?i)<br\s*/?>`)
+
+// ParseInfoboxes returns all infoboxes in the provided text.
+// An infobox is a template whose name starts with "Infobox".
+func ParseInfoboxes(text string) []Infobox {
+	var res []Infobox
+	for _, t := range ParseTemplates(text) {
+		if t.ParserFunction || !isInfobox(t.Name) {
+			continue
+		}
+		box := Infobox{
+			Type: strings.ToLower(strings.TrimSpace(t.Name[len("infobox"):])),
+		}
+		for _, arg := range t.Args {
+			raw := strings.TrimSpace(arg.Value)
+			if raw == "" || isPositional(arg.Name) {
+				continue
+			}
+			box.Fields = append(box.Fields, InfoboxField{
+				Key:   arg.Name,
+				Raw:   raw,
+				Value: infoboxValue(raw),
+			})
+		}
+		res = append(res, box)
+	}
+	return res
+}
+
+func isInfobox(name string) bool {
+	return len(name) >= len("infobox") && strings.EqualFold(name[:len("infobox")], "infobox")
+}
+
+func isPositional(name string) bool {
+	for i := range name {
+		if name[i] < '0' || name[i] > '9' {
+			return false
+		}
+	}
+	return len(name) > 0
+}
+
+// infoboxValue converts the wikitext value of an infobox field to
+// plain text.
+func infoboxValue(raw string) string {
+	raw = lineBreakRegexp.ReplaceAllString(raw, "\n")
+	text := PlainText(raw, PlainTextOptions{Lists: true})
+	var parts []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,47 @@
+package wikitext_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+func Test_ParseInfoboxes(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  []wikitext.Infobox
+	}{
+		{"no infobox", "{{Cite web|title=x}}", nil},
+		{"settlement", `{{Infobox settlement
+| name = [[Stockholm]]
+| native_name =
+| population_total = 975,551<ref>SCB</ref>
+| subdivision_name = '''Sweden'''<br />Europe
+}}`, []wikitext.Infobox{
+			{Type: "settlement", Fields: []wikitext.InfoboxField{
+				{Key: "name", Raw: "[[Stockholm]]", Value: "Stockholm"},
+				{Key: "population_total", Raw: "975,551<ref>SCB</ref>", Value: "975,551"},
+				{Key: "subdivision_name", Raw: "'''Sweden'''<br />Europe", Value: "Sweden; Europe"},
+			}},
+		}},
+		{"nested", "{{infobox person|name=A|module={{Infobox officeholder|office=B}}}}", []wikitext.Infobox{
+			{Type: "person", Fields: []wikitext.InfoboxField{
+				{Key: "name", Raw: "A", Value: "A"},
+				{Key: "module", Raw: "{{Infobox officeholder|office=B}}", Value: ""},
+			}},
+			{Type: "officeholder", Fields: []wikitext.InfoboxField{
+				{Key: "office", Raw: "B", Value: "B"},
+			}},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := wikitext.ParseInfoboxes(tc.input)
+			if !cmp.Equal(tc.want, got, cmpopts.EquateEmpty()) {
+				t.Errorf("invalid infoboxes\n%v", cmp.Diff(tc.want, got, cmpopts.EquateEmpty()))
+			}
+		})
+	}
+}
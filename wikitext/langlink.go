@@ -0,0 +1,172 @@
+package wikitext
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia/internal/scan"
+)
+
+// Interwiki holds the interwiki and language configuration of a wiki,
+// which decides whether a prefixed link such as [[de:Titel]] is an
+// interlanguage link.
+type Interwiki struct {
+	// Languages contains the interlanguage link prefixes of the wiki
+	// in lower case.
+	Languages map[string]bool
+
+	// Namespaces contains the namespace names and aliases of the wiki in
+	// lower case. Namespaces take precedence over interwiki prefixes.
+	Namespaces map[string]bool
+}
+
+// DefaultInterwiki returns the interwiki configuration used by Wikipedia,
+// where each language edition is reachable through its language code.
+func DefaultInterwiki() *Interwiki {
+	iw := &Interwiki{
+		Languages:  make(map[string]bool, len(wikipediaLanguages)),
+		Namespaces: make(map[string]bool),
+	}
+	for _, lang := range wikipediaLanguages {
+		iw.Languages[lang] = true
+	}
+	return iw
+}
+
+// ReadInterwiki reads the interwiki configuration from the response of
+// the MediaWiki siteinfo API, i.e. the output of
+//
+//	api.php?action=query&meta=siteinfo&siprop=interwikimap|namespaces|namespacealiases&format=json
+//
+// Interwiki prefixes which have a language are interlanguage prefixes.
+func ReadInterwiki(r io.Reader) (*Interwiki, error) {
+	var resp struct {
+		Query struct {
+			InterwikiMap []struct {
+				Prefix   string `json:"prefix"`
+				Language string `json:"language"`
+			} `json:"interwikimap"`
+			Namespaces map[string]struct {
+				Name      string `json:"name"`
+				Canonical string `json:"canonical"`
+			} `json:"namespaces"`
+			NamespaceAliases []struct {
+				Alias string `json:"alias"`
+			} `json:"namespacealiases"`
+		} `json:"query"`
+	}
+	if err := json.NewDecoder(r).Decode(&resp); err != nil {
+		return nil, err
+	}
+
+	iw := &Interwiki{
+		Languages:  make(map[string]bool),
+		Namespaces: make(map[string]bool),
+	}
+	for _, entry := range resp.Query.InterwikiMap {
+		if entry.Language != "" {
+			iw.Languages[strings.ToLower(entry.Prefix)] = true
+		}
+	}
+	for _, ns := range resp.Query.Namespaces {
+		iw.AddNamespace(ns.Name)
+		iw.AddNamespace(ns.Canonical)
+	}
+	for _, alias := range resp.Query.NamespaceAliases {
+		iw.AddNamespace(alias.Alias)
+	}
+	return iw, nil
+}
+
+// AddNamespace adds a namespace name to the configuration, e.g. the names
+// found in the siteinfo header of a dump.
+func (iw *Interwiki) AddNamespace(name string) {
+	if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+		iw.Namespaces[strings.ReplaceAll(name, "_", " ")] = true
+	}
+}
+
+// LanguageLink is a link to the same topic in another language edition.
+type LanguageLink struct {
+	// Language is the interlanguage prefix of the link in lower case,
+	// e.g. "de".
+	Language string
+
+	// Title is the normalized title of the page in the other language.
+	Title string
+}
+
+// ParseLanguageLinks returns the interlanguage links in the provided text.
+// If there are multiple links to the same language, only the first one is
+// returned, as in MediaWiki.
+func ParseLanguageLinks(text string, iw *Interwiki) []LanguageLink {
+	var res []LanguageLink
+	seen := make(map[string]bool)
+	masked := scan.MaskComments(text)
+	for i := 0; i < len(masked); {
+		if !strings.HasPrefix(masked[i:], "[[") {
+			i++
+			continue
+		}
+		end := scan.MatchLink(masked, i)
+		if end == -1 {
+			i += 2
+			continue
+		}
+		target := strings.TrimSpace(scan.SplitPipes(masked[i+2 : end-2])[0])
+		lang := namespace(target)
+		i += 2
+		if lang == "" || !iw.Languages[lang] || iw.Namespaces[lang] || seen[lang] {
+			continue
+		}
+		title := normalizeTitle(target[strings.IndexByte(target, ':')+1:])
+		if title == "" {
+			continue
+		}
+		seen[lang] = true
+		res = append(res, LanguageLink{
+			Language: lang,
+			Title:    title,
+		})
+	}
+	return res
+}
+
+// Language codes of Wikipedia editions, which are the interlanguage
+// prefixes on all Wikipedias.
+var wikipediaLanguages = []string{
+	"aa", "ab", "ace", "ady", "af", "ak", "als", "alt", "am", "ami", "an",
+	"ang", "anp", "ar", "arc", "ary", "arz", "as", "ast", "atj", "av", "avk",
+	"awa", "ay", "az", "azb", "ba", "ban", "bar", "bat-smg", "bbc", "bcl",
+	"be", "be-tarask", "be-x-old", "bew", "bg", "bh", "bi", "bjn", "blk",
+	"bm", "bn", "bo", "bpy", "br", "bs", "btm", "bug", "bxr", "ca",
+	"cbk-zam", "cdo", "ce", "ceb", "ch", "cho", "chr", "chy", "ckb", "co",
+	"cr", "crh", "cs", "csb", "cu", "cv", "cy", "da", "dag", "de", "dga",
+	"din", "diq", "dsb", "dtp", "dty", "dv", "dz", "ee", "el", "eml", "en",
+	"eo", "es", "et", "eu", "ext", "fa", "fat", "ff", "fi", "fiu-vro", "fj",
+	"fo", "fon", "fr", "frp", "frr", "fur", "fy", "ga", "gag", "gan", "gcr",
+	"gd", "gl", "glk", "gn", "gom", "gor", "got", "gpe", "gu", "guc", "gur",
+	"guw", "gv", "ha", "hak", "haw", "he", "hi", "hif", "ho", "hr", "hsb",
+	"ht", "hu", "hy", "hyw", "hz", "ia", "id", "ie", "ig", "igl", "ii",
+	"ik", "ilo", "inh", "io", "is", "it", "iu", "ja", "jam", "jbo", "jv",
+	"ka", "kaa", "kab", "kbd", "kbp", "kcg", "kg", "ki", "kj", "kk", "kl",
+	"km", "kn", "ko", "koi", "kr", "krc", "ks", "ksh", "ku", "kus", "kv",
+	"kw", "ky", "la", "lad", "lb", "lbe", "lez", "lfn", "lg", "li", "lij",
+	"lld", "lmo", "ln", "lo", "lrc", "lt", "ltg", "lv", "mad", "mai",
+	"map-bms", "mdf", "mg", "mh", "mhr", "mi", "min", "mk", "ml", "mn",
+	"mni", "mnw", "mo", "mr", "mrj", "ms", "mt", "mus", "mwl", "my", "myv",
+	"mzn", "na", "nah", "nap", "nds", "nds-nl", "ne", "new", "ng", "nia",
+	"nl", "nn", "no", "nov", "nqo", "nrm", "nso", "nv", "ny", "oc", "olo",
+	"om", "or", "os", "pa", "pag", "pam", "pap", "pcd", "pcm", "pdc", "pfl",
+	"pi", "pih", "pl", "pms", "pnb", "pnt", "ps", "pt", "pwn", "qu", "rm",
+	"rmy", "rn", "ro", "roa-rup", "roa-tara", "ru", "rue", "rw", "sa",
+	"sah", "sat", "sc", "scn", "sco", "sd", "se", "sg", "sh", "shi", "shn",
+	"si", "simple", "sk", "skr", "sl", "sm", "smn", "sn", "so", "sq", "sr",
+	"srn", "ss", "st", "stq", "su", "sv", "sw", "szl", "szy", "ta", "tay",
+	"tcy", "tdd", "te", "tet", "tg", "th", "ti", "tk", "tl", "tly", "tn",
+	"to", "tpi", "tr", "trv", "ts", "tt", "tum", "tw", "ty", "tyv", "udm",
+	"ug", "uk", "ur", "uz", "ve", "vec", "vep", "vi", "vls", "vo", "wa",
+	"war", "wo", "wuu", "xal", "xh", "xmf", "yi", "yo", "za", "zea", "zgh",
+	"zh", "zh-classical", "zh-min-nan", "zh-yue", "zu",
+}
@@ -0,0 +1,199 @@
+package wikitext
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia/internal/scan"
+)
+
+// PlainTextOptions controls which parts of an article are kept when
+// converting wikitext to plain text.
+type PlainTextOptions struct {
+	// Lists keeps list items, with their list markers removed.
+	// If false, list items are dropped altogether.
+	Lists bool
+
+	// SectionTitles keeps section headings as separate lines.
+	// If false, headings are dropped.
+	SectionTitles bool
+}
+
+// DefaultPlainTextOptions keeps both lists and section titles.
+var DefaultPlainTextOptions = PlainTextOptions{
+	Lists:         true,
+	SectionTitles: true,
+}
+
+// Tags whose contents are dropped along with the tag itself.
+var droppedTags = []string{"ref", "gallery", "math", "timeline", "imagemap", "score"}
+
+var droppedTagRegexps = func() []*regexp.Regexp {
+	res := make([]*regexp.Regexp, 0, 2*len(droppedTags))
+	for _, tag := range droppedTags {
+		res = append(res,
+			regexp.MustCompile(`(?is)<`+tag+`\b[^>]*/>`),
+			regexp.MustCompile(`(?is)<`+tag+`\b[^>]*>.*?</`+tag+`\s*>`),
+		)
+	}
+	return res
+}()
+
+var (
+	htmlTagRegexp    = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	magicWordRegexp  = regexp.MustCompile(`__[A-Z]+__`)
+	extLinkRegexp    = regexp.MustCompile(`\[(?:https?:|ftp:|mailto:|//)[^\s\]]*(?:\s+([^\]]*))?\]`)
+	headingRegexp    = regexp.MustCompile(`^(={1,6})\s*(.*?)\s*(={1,6})\s*$`)
+	blankLinesRegexp = regexp.MustCompile(`\n{3,}`)
+)
+
+// Link namespaces which are not rendered as text in an article.
+var hiddenNamespaces = map[string]bool{
+	"file":     true,
+	"image":    true,
+	"media":    true,
+	"category": true,
+}
+
+// PlainText converts wikitext to plain text.
+//
+// Templates, references, tables, file and image links, HTML comments and
+// formatting markup are removed. Internal and external links are replaced
+// by their anchor text.
+func PlainText(text string, opts PlainTextOptions) string {
+	text = stripComments(text)
+	for _, re := range droppedTagRegexps {
+		text = re.ReplaceAllString(text, "")
+	}
+	text = stripTemplates(text)
+	text = stripTables(text)
+	text = replaceLinks(text)
+	text = extLinkRegexp.ReplaceAllString(text, "$1")
+	text = strings.ReplaceAll(text, "'''", "")
+	text = strings.ReplaceAll(text, "''", "")
+	text = htmlTagRegexp.ReplaceAllString(text, "")
+	text = magicWordRegexp.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+
+	lines := strings.Split(text, "\n")
+	res := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		switch {
+		case strings.HasPrefix(line, "----"):
+			continue
+		case strings.HasPrefix(line, "="):
+			m := headingRegexp.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			if !opts.SectionTitles {
+				continue
+			}
+			line = m[2]
+			// Separate the title from the preceding paragraph
+			if len(res) > 0 && res[len(res)-1] != "" {
+				res = append(res, "")
+			}
+		case len(line) > 0 && strings.IndexByte("*#:;", line[0]) != -1:
+			if !opts.Lists {
+				continue
+			}
+			line = strings.TrimSpace(strings.TrimLeft(line, "*#:;"))
+		}
+		res = append(res, strings.TrimLeft(line, " \t"))
+	}
+
+	text = strings.Join(res, "\n")
+	text = blankLinesRegexp.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// stripComments removes all HTML comments from s.
+func stripComments(s string) string {
+	var sb strings.Builder
+	for {
+		idx := strings.Index(s, "<!--")
+		if idx == -1 {
+			sb.WriteString(s)
+			return sb.String()
+		}
+		sb.WriteString(s[:idx])
+		s = s[scan.MatchComment(s, idx):]
+	}
+}
+
+// stripTemplates removes all templates, parser functions and template
+// parameters from s.
+func stripTemplates(s string) string {
+	var sb strings.Builder
+	for {
+		idx := strings.Index(s, "{{")
+		if idx == -1 {
+			sb.WriteString(s)
+			return sb.String()
+		}
+		sb.WriteString(s[:idx])
+		end := scan.MatchBraces(s, idx)
+		if end == -1 {
+			// Drop the unbalanced braces and keep going
+			end = idx + 2
+		}
+		s = s[end:]
+	}
+}
+
+// stripTables removes all tables from s.
+func stripTables(s string) string {
+	var sb strings.Builder
+	for {
+		idx := strings.Index(s, "{|")
+		if idx == -1 {
+			sb.WriteString(s)
+			return sb.String()
+		}
+		sb.WriteString(s[:idx])
+		end := scan.MatchTable(s, idx)
+		if end == -1 {
+			end = len(s)
+		}
+		s = s[end:]
+	}
+}
+
+// replaceLinks replaces internal links with their anchor text. Links to
+// files, images and categories are removed.
+func replaceLinks(s string) string {
+	var sb strings.Builder
+	for {
+		idx := strings.Index(s, "[[")
+		if idx == -1 {
+			sb.WriteString(s)
+			return sb.String()
+		}
+		sb.WriteString(s[:idx])
+		end := scan.MatchLink(s, idx)
+		if end == -1 {
+			sb.WriteString("[[")
+			s = s[idx+2:]
+			continue
+		}
+		sb.WriteString(linkText(s[idx+2 : end-2]))
+		s = s[end:]
+	}
+}
+
+// linkText returns the text shown for the contents of an internal link.
+func linkText(inner string) string {
+	parts := scan.SplitPipes(inner)
+	target := strings.TrimSpace(parts[0])
+	if !strings.HasPrefix(target, ":") && hiddenNamespaces[namespace(target)] {
+		return ""
+	}
+	if len(parts) == 1 || strings.TrimSpace(parts[len(parts)-1]) == "" {
+		return strings.TrimPrefix(target, ":")
+	}
+	return replaceLinks(parts[len(parts)-1])
+}
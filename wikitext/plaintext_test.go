@@ -0,0 +1,44 @@
+package wikitext_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+func Test_PlainText(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		opts  wikitext.PlainTextOptions
+		want  string
+	}{
+		{"empty", "", wikitext.DefaultPlainTextOptions, ""},
+		{"formatting", "'''Anarchism''' is a ''political'' philosophy", wikitext.DefaultPlainTextOptions,
+			"Anarchism is a political philosophy"},
+		{"links", "A [[political philosophy]] and [[Social movement|movement]]s.", wikitext.DefaultPlainTextOptions,
+			"A political philosophy and movements."},
+		{"nested templates", "a{{Infobox|name={{lang|fr|x}}|v={{{1|}}}}}b", wikitext.DefaultPlainTextOptions, "ab"},
+		{"references", `a<ref name="x">{{cite web|url=http://a.b}}</ref>b<ref name="x" />c`, wikitext.DefaultPlainTextOptions,
+			"abc"},
+		{"comments", "a<!-- hidden\ncomment -->b", wikitext.DefaultPlainTextOptions, "ab"},
+		{"files and categories", "[[File:A.jpg|thumb|A [[caption]]]]text[[Category:B]]", wikitext.DefaultPlainTextOptions,
+			"text"},
+		{"visible category link", "see [[:Category:B]]", wikitext.DefaultPlainTextOptions, "see Category:B"},
+		{"external links", "[http://a.b label] and [http://c.d]", wikitext.DefaultPlainTextOptions, "label and"},
+		{"tables", "a\n{| class=wikitable\n|-\n| x || y\n|}\nb", wikitext.DefaultPlainTextOptions, "a\n\nb"},
+		{"entities", "a&nbsp;b &amp; c", wikitext.DefaultPlainTextOptions, "a b & c"},
+		{"section titles", "lead\n== History ==\ntext", wikitext.DefaultPlainTextOptions, "lead\n\nHistory\ntext"},
+		{"no section titles", "lead\n== History ==\ntext", wikitext.PlainTextOptions{}, "lead\ntext"},
+		{"lists", "items\n* one\n*# two", wikitext.DefaultPlainTextOptions, "items\none\ntwo"},
+		{"no lists", "items\n* one\n*# two", wikitext.PlainTextOptions{}, "items"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := wikitext.PlainText(tc.input, tc.opts)
+			if !cmp.Equal(tc.want, got) {
+				t.Errorf("invalid plain text\n%v", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
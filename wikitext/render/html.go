@@ -0,0 +1,560 @@
+// Package render renders parsed wikitext as HTML and Markdown.
+package render
+
+import (
+	"html"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/sebnyberg/wikipedia/wikitext/ast"
+)
+
+// HTMLOptions configures HTML rendering.
+type HTMLOptions struct {
+	// Expander expands the templates of a page before it is rendered by
+	// RenderPageHTML. If nil, templates are not rendered.
+	Expander *wikitext.Expander
+
+	// Titles looks up the pages that exist, e.g. a TitleIndex or a page
+	// store which indexes titles. Links to other pages are rendered as red
+	// links. If nil, all pages are assumed to exist.
+	Titles wikipedia.TitleLookup
+
+	// PageURL returns the URL of the page with the provided title. By
+	// default, pages are linked as "/wiki/Title".
+	PageURL func(title string) string
+
+	// Interwiki decides which links are interlanguage links, which are
+	// not rendered. By default, the configuration of Wikipedia is used.
+	Interwiki *wikitext.Interwiki
+}
+
+// DefaultPageURL returns the path of a page on Wikipedia, e.g.
+// "/wiki/New_York".
+func DefaultPageURL(title string) string {
+	return "/wiki/" + url.PathEscape(strings.ReplaceAll(title, " ", "_"))
+}
+
+// RenderPageHTML renders the latest revision of a page as sanitized HTML.
+// If opts.Expander is set, templates are expanded before rendering.
+func RenderPageHTML(w io.Writer, p *wikipedia.Page, opts HTMLOptions) error {
+	text := ""
+	if len(p.Revisions) > 0 {
+		text = p.Revisions[len(p.Revisions)-1].Text
+	}
+	if opts.Expander != nil {
+		var err error
+		if text, err = opts.Expander.Expand(p); err != nil {
+			return err
+		}
+	}
+	return RenderHTML(w, ast.Parse(text), opts)
+}
+
+// RenderHTML renders a document as sanitized HTML.
+//
+// Only whitelisted HTML tags and attributes are kept, and the text of all
+// other markup is escaped. Headings get anchors based on their text,
+// references are numbered and listed at the <references/> tag or at the
+// end of the document, and category links are listed at the end of the
+// document. Unexpanded templates and parameters are not rendered.
+func RenderHTML(w io.Writer, doc *ast.Document, opts HTMLOptions) error {
+	if opts.PageURL == nil {
+		opts.PageURL = DefaultPageURL
+	}
+	if opts.Interwiki == nil {
+		opts.Interwiki = wikitext.DefaultInterwiki()
+	}
+	r := &htmlRenderer{
+		opts:     opts,
+		anchors:  make(map[string]int),
+		refNames: make(map[string]int),
+	}
+	var sb strings.Builder
+	r.nodes(&sb, doc.Children)
+	r.references(&sb)
+	r.categoryLinks(&sb)
+	if r.err != nil {
+		return r.err
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type htmlRenderer struct {
+	opts HTMLOptions
+
+	// anchors counts the uses of each heading anchor
+	anchors map[string]int
+
+	// refs contains the rendered references which have not yet been
+	// listed, and refNames the numbers of named references
+	refs     []reference
+	refCount int
+	refNames map[string]int
+
+	categories []string
+	extLinks   int
+
+	// err is the first error returned when looking up titles
+	err error
+}
+
+type reference struct {
+	n    int
+	html string
+}
+
+func (r *htmlRenderer) nodes(sb *strings.Builder, nodes []ast.Node) {
+	for _, n := range nodes {
+		r.node(sb, n)
+	}
+}
+
+func (r *htmlRenderer) node(sb *strings.Builder, node ast.Node) {
+	switch n := node.(type) {
+	case *ast.Text:
+		sb.WriteString(escapeText(n.Value))
+	case *ast.Paragraph:
+		if blockOnly(n.Children) {
+			r.nodes(sb, n.Children)
+			return
+		}
+		sb.WriteString("<p>")
+		r.nodes(sb, n.Children)
+		sb.WriteString("</p>\n")
+	case *ast.Heading:
+		anchor := r.anchor(plainText(n.Children))
+		tag := "h" + strconv.Itoa(n.Level)
+		sb.WriteString("<" + tag + ` id="` + html.EscapeString(anchor) + `">`)
+		r.nodes(sb, n.Children)
+		sb.WriteString("</" + tag + ">\n")
+	case *ast.List:
+		tag := listTag(n.Marker)
+		sb.WriteString("<" + tag + ">\n")
+		for _, item := range n.Items {
+			itemTag := itemTag(item.Marker)
+			sb.WriteString("<" + itemTag + ">")
+			r.nodes(sb, item.Children)
+			sb.WriteString("</" + itemTag + ">\n")
+		}
+		sb.WriteString("</" + tag + ">\n")
+	case *ast.HorizontalRule:
+		sb.WriteString("<hr>\n")
+	case *ast.Table:
+		r.table(sb, n)
+	case *ast.Link:
+		r.link(sb, n)
+	case *ast.ExternalLink:
+		r.externalLink(sb, n)
+	case *ast.Tag:
+		r.tag(sb, n)
+	case *ast.Bold:
+		sb.WriteString("<b>")
+		r.nodes(sb, n.Children)
+		sb.WriteString("</b>")
+	case *ast.Italic:
+		sb.WriteString("<i>")
+		r.nodes(sb, n.Children)
+		sb.WriteString("</i>")
+	}
+}
+
+func listTag(marker byte) string {
+	switch marker {
+	case '*':
+		return "ul"
+	case '#':
+		return "ol"
+	}
+	return "dl"
+}
+
+func itemTag(marker byte) string {
+	switch marker {
+	case ';':
+		return "dt"
+	case ':':
+		return "dd"
+	}
+	return "li"
+}
+
+// anchor returns a unique anchor for a heading. Repeated headings get a
+// numeric suffix, as in MediaWiki.
+func (r *htmlRenderer) anchor(text string) string {
+	anchor := strings.ReplaceAll(strings.TrimSpace(text), " ", "_")
+	r.anchors[anchor]++
+	if n := r.anchors[anchor]; n > 1 {
+		anchor += "_" + strconv.Itoa(n)
+	}
+	return anchor
+}
+
+func (r *htmlRenderer) table(sb *strings.Builder, t *ast.Table) {
+	sb.WriteString("<table" + attrs(t.Attrs) + ">\n")
+	if len(t.Caption) > 0 {
+		sb.WriteString("<caption>")
+		r.nodes(sb, t.Caption)
+		sb.WriteString("</caption>\n")
+	}
+	for _, row := range t.Rows {
+		if len(row.Cells) == 0 {
+			continue
+		}
+		sb.WriteString("<tr" + attrs(row.Attrs) + ">\n")
+		for _, cell := range row.Cells {
+			tag := "td"
+			if cell.Header {
+				tag = "th"
+			}
+			sb.WriteString("<" + tag + attrs(cell.Attrs) + ">")
+			r.nodes(sb, cell.Children)
+			sb.WriteString("</" + tag + ">\n")
+		}
+		sb.WriteString("</tr>\n")
+	}
+	sb.WriteString("</table>\n")
+}
+
+func (r *htmlRenderer) link(sb *strings.Builder, l *ast.Link) {
+	target := strings.TrimSpace(l.Target)
+	colon := strings.HasPrefix(target, ":")
+	ns := namespace(target)
+	switch {
+	case !colon && ns == "category":
+		r.categories = append(r.categories, wikitext.NormalizeTitle(target))
+		return
+	case !colon && r.opts.Interwiki.Languages[ns] && !r.opts.Interwiki.Namespaces[ns]:
+		// Interlanguage links are shown outside of the content
+		return
+	case strings.HasPrefix(target, "#"):
+		sb.WriteString(`<a href="#` + html.EscapeString(strings.ReplaceAll(target[1:], " ", "_")) + `">`)
+		r.linkLabel(sb, l, target[1:])
+		sb.WriteString("</a>")
+		return
+	}
+
+	title := wikitext.NormalizeTitle(target)
+	href := r.opts.PageURL(title)
+	if idx := strings.IndexByte(target, '#'); idx != -1 {
+		href += "#" + url.PathEscape(strings.ReplaceAll(strings.TrimSpace(target[idx+1:]), " ", "_"))
+	}
+	class := ""
+	if ns == "file" || ns == "image" {
+		class = "mw-file"
+	}
+	if !r.exists(title) {
+		class = strings.TrimSpace(class + " new")
+	}
+	sb.WriteString(`<a href="` + html.EscapeString(href) + `"`)
+	if class != "" {
+		sb.WriteString(` class="` + class + `"`)
+	}
+	sb.WriteString(` title="` + html.EscapeString(title) + `">`)
+	r.linkLabel(sb, l, strings.TrimPrefix(target, ":"))
+	sb.WriteString("</a>")
+}
+
+// linkLabel renders the label of a link, which is the target if the link
+// has no label.
+func (r *htmlRenderer) linkLabel(sb *strings.Builder, l *ast.Link, target string) {
+	if l.Children != nil {
+		r.nodes(sb, l.Children)
+	} else {
+		sb.WriteString(escapeText(target))
+	}
+	sb.WriteString(escapeText(l.Trail))
+}
+
+func (r *htmlRenderer) exists(title string) bool {
+	if r.opts.Titles == nil || r.err != nil {
+		return true
+	}
+	_, ok, err := r.opts.Titles.PageID(title)
+	if err != nil {
+		r.err = err
+		return true
+	}
+	return ok
+}
+
+func (r *htmlRenderer) externalLink(sb *strings.Builder, l *ast.ExternalLink) {
+	if !safeURL(l.URL) {
+		sb.WriteString(escapeText(l.URL))
+		r.nodes(sb, l.Children)
+		return
+	}
+	sb.WriteString(`<a class="external" rel="nofollow" href="` + html.EscapeString(l.URL) + `">`)
+	switch {
+	case l.Children != nil:
+		r.nodes(sb, l.Children)
+	case l.Bare:
+		sb.WriteString(html.EscapeString(l.URL))
+	default:
+		// Links without a label are numbered
+		r.extLinks++
+		sb.WriteString("[" + strconv.Itoa(r.extLinks) + "]")
+	}
+	sb.WriteString("</a>")
+}
+
+// safeURL returns true if the URL uses a protocol which is safe to link.
+func safeURL(u string) bool {
+	lower := strings.ToLower(u)
+	for _, prefix := range []string{"http://", "https://", "ftp://", "mailto:", "//"} {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *htmlRenderer) tag(sb *strings.Builder, t *ast.Tag) {
+	switch t.Name {
+	case "ref":
+		r.ref(sb, t)
+	case "references":
+		// References defined in the list are numbered by their first use,
+		// and must be added before the list is rendered
+		for _, c := range t.Children {
+			if c, ok := c.(*ast.Tag); ok && c.Name == "ref" && c.Attrs["name"] != "" {
+				r.defineRef(c)
+			}
+		}
+		r.references(sb)
+	case "nowiki":
+		r.nodes(sb, t.Children)
+	case "pre", "syntaxhighlight", "source":
+		sb.WriteString("<pre>")
+		r.nodes(sb, t.Children)
+		sb.WriteString("</pre>")
+	case "poem":
+		sb.WriteString(`<div class="poem">`)
+		r.nodes(sb, t.Children)
+		sb.WriteString("</div>")
+	case "math", "chem", "ce":
+		sb.WriteString(`<span class="texhtml">`)
+		r.nodes(sb, t.Children)
+		sb.WriteString("</span>")
+	default:
+		if !allowedTags[t.Name] {
+			return
+		}
+		sb.WriteString("<" + t.Name + attrs(t.Attrs) + ">")
+		if voidTags[t.Name] {
+			return
+		}
+		r.nodes(sb, t.Children)
+		sb.WriteString("</" + t.Name + ">")
+	}
+}
+
+// ref renders a reference as a numbered footnote marker.
+func (r *htmlRenderer) ref(sb *strings.Builder, t *ast.Tag) {
+	id := strconv.Itoa(r.defineRef(t))
+	sb.WriteString(`<sup class="reference"><a href="#cite_note-` + id + `">[` + id + `]</a></sup>`)
+}
+
+// defineRef adds the contents of a reference to the list and returns its
+// number. Named references which have been used before reuse their number.
+func (r *htmlRenderer) defineRef(t *ast.Tag) int {
+	name := t.Attrs["name"]
+	n, seen := r.refNames[name]
+	if !seen || name == "" {
+		r.refCount++
+		n = r.refCount
+		if name != "" {
+			r.refNames[name] = n
+		}
+	}
+	if len(t.Children) > 0 || !seen {
+		var content strings.Builder
+		r.nodes(&content, t.Children)
+		r.addReference(n, content.String())
+	}
+	return n
+}
+
+// addReference adds the contents of a reference to the list, replacing
+// empty contents of a named reference which was defined later.
+func (r *htmlRenderer) addReference(n int, content string) {
+	for i := range r.refs {
+		if r.refs[i].n == n {
+			if r.refs[i].html == "" {
+				r.refs[i].html = content
+			}
+			return
+		}
+	}
+	r.refs = append(r.refs, reference{n, content})
+}
+
+// references lists the references which have not been listed yet.
+func (r *htmlRenderer) references(sb *strings.Builder) {
+	if len(r.refs) == 0 {
+		return
+	}
+	sort.Slice(r.refs, func(i, j int) bool { return r.refs[i].n < r.refs[j].n })
+	sb.WriteString(`<ol class="references">` + "\n")
+	for _, ref := range r.refs {
+		id := strconv.Itoa(ref.n)
+		sb.WriteString(`<li id="cite_note-` + id + `" value="` + id + `">` + ref.html + "</li>\n")
+	}
+	sb.WriteString("</ol>\n")
+	r.refs = nil
+}
+
+func (r *htmlRenderer) categoryLinks(sb *strings.Builder) {
+	if len(r.categories) == 0 {
+		return
+	}
+	sb.WriteString(`<div class="catlinks"><ul>`)
+	for _, c := range r.categories {
+		class := ""
+		if !r.exists(c) {
+			class = ` class="new"`
+		}
+		name := c[strings.IndexByte(c, ':')+1:]
+		sb.WriteString(`<li><a href="` + html.EscapeString(r.opts.PageURL(c)) + `"` + class + `>` + html.EscapeString(name) + "</a></li>")
+	}
+	sb.WriteString("</ul></div>\n")
+}
+
+// HTML tags which are rendered. The contents of other tags are dropped.
+var allowedTags = map[string]bool{
+	"abbr": true, "b": true, "bdi": true, "big": true, "blockquote": true,
+	"br": true, "center": true, "cite": true, "code": true, "dd": true,
+	"del": true, "dfn": true, "div": true, "dl": true, "dt": true, "em": true,
+	"font": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "hr": true, "i": true, "ins": true, "kbd": true, "li": true,
+	"mark": true, "ol": true, "p": true, "q": true, "rp": true, "rt": true,
+	"ruby": true, "s": true, "samp": true, "small": true, "span": true,
+	"strike": true, "strong": true, "sub": true, "sup": true, "table": true,
+	"td": true, "th": true, "time": true, "tr": true, "tt": true, "u": true,
+	"ul": true, "var": true, "wbr": true, "caption": true,
+}
+
+// Tags which are rendered as blocks, and are not wrapped in paragraphs.
+var blockTags = map[string]bool{
+	"blockquote": true, "center": true, "div": true, "dl": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "hr": true,
+	"ol": true, "p": true, "poem": true, "pre": true, "references": true,
+	"source": true, "syntaxhighlight": true, "table": true, "ul": true,
+}
+
+// blockOnly returns true if the nodes consist of block tags and
+// whitespace.
+func blockOnly(nodes []ast.Node) bool {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *ast.Tag:
+			if !blockTags[n.Name] {
+				return false
+			}
+		case *ast.Text:
+			if strings.TrimSpace(n.Value) != "" {
+				return false
+			}
+		case *ast.Comment:
+		default:
+			return false
+		}
+	}
+	return len(nodes) > 0
+}
+
+var voidTags = map[string]bool{
+	"br": true, "hr": true, "wbr": true,
+}
+
+// HTML attributes which are rendered. Other attributes, such as event
+// handlers, are dropped.
+var allowedAttrs = map[string]bool{
+	"class": true, "id": true, "title": true, "lang": true, "dir": true,
+	"style": true, "align": true, "valign": true, "colspan": true,
+	"rowspan": true, "width": true, "height": true, "border": true,
+	"cellpadding": true, "cellspacing": true, "scope": true, "datetime": true,
+	"cite": true, "color": true, "size": true, "face": true,
+}
+
+// attrs renders the allowed attributes, sorted by name.
+func attrs(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if allowedAttrs[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		v := html.UnescapeString(m[k])
+		if k == "style" && !safeStyle(v) {
+			continue
+		}
+		sb.WriteString(" " + k + `="` + html.EscapeString(v) + `"`)
+	}
+	return sb.String()
+}
+
+// safeStyle returns false for inline styles which may load resources or
+// run scripts.
+func safeStyle(style string) bool {
+	lower := strings.ToLower(style)
+	for _, s := range []string{"url(", "expression", "javascript:", "image(", "/*", `\`, "@import", "behavior", "-moz-binding"} {
+		if strings.Contains(lower, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeText escapes text for HTML. Character references in wikitext,
+// such as "&nbsp;", are decoded first so that they are not escaped twice.
+func escapeText(s string) string {
+	return html.EscapeString(html.UnescapeString(s))
+}
+
+// namespace returns the lower-cased namespace prefix of a link target,
+// ignoring a leading colon.
+func namespace(target string) string {
+	target = strings.TrimPrefix(target, ":")
+	idx := strings.IndexByte(target, ':')
+	if idx <= 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(target[:idx]))
+}
+
+// plainText returns the text of the nodes without markup.
+func plainText(nodes []ast.Node) string {
+	var sb strings.Builder
+	for _, n := range nodes {
+		ast.Inspect(n, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.Text:
+				sb.WriteString(html.UnescapeString(n.Value))
+			case *ast.Link:
+				if n.Children == nil {
+					sb.WriteString(strings.TrimPrefix(n.Target, ":"))
+				} else {
+					sb.WriteString(plainText(n.Children))
+				}
+				sb.WriteString(n.Trail)
+				return false
+			case *ast.Template, *ast.Parameter, *ast.Comment:
+				return false
+			case *ast.Tag:
+				return n.Name != "ref"
+			}
+			return true
+		})
+	}
+	return sb.String()
+}
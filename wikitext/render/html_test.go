@@ -0,0 +1,111 @@
+package render_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/sebnyberg/wikipedia/wikitext/ast"
+	"github.com/sebnyberg/wikipedia/wikitext/render"
+)
+
+func Test_RenderHTML(t *testing.T) {
+	opts := render.HTMLOptions{
+		Titles: wikipedia.TitleIndex{"Stockholm": 1, "Category:Cities": 2},
+	}
+	for _, tc := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"paragraph",
+			"Hello '''bold''' & ''it''",
+			"<p>Hello <b>bold</b> &amp; <i>it</i></p>\n"},
+		{"entities",
+			"a&nbsp;&amp;b",
+			"<p>a\u00a0&amp;b</p>\n"},
+		{"headings get unique anchors",
+			"== A b ==\n== A b ==",
+			"<h2 id=\"A_b\">A b</h2>\n<h2 id=\"A_b_2\">A b</h2>\n"},
+		{"links",
+			"[[stockholm|the city]] [[Uppsala]]s [[#Top]]",
+			`<p><a href="/wiki/Stockholm" title="Stockholm">the city</a> ` +
+				`<a href="/wiki/Uppsala" class="new" title="Uppsala">Uppsalas</a> ` +
+				`<a href="#Top">Top</a></p>` + "\n"},
+		{"categories and language links",
+			"a[[Category:Cities]][[Category:Nope]][[de:Stockholm]]",
+			"<p>a</p>\n" + `<div class="catlinks"><ul>` +
+				`<li><a href="/wiki/Category:Cities">Cities</a></li>` +
+				`<li><a href="/wiki/Category:Nope" class="new">Nope</a></li></ul></div>` + "\n"},
+		{"external links",
+			"[https://a.com A] [https://b.com] https://c.com [javascript:alert(1) x]",
+			`<p><a class="external" rel="nofollow" href="https://a.com">A</a> ` +
+				`<a class="external" rel="nofollow" href="https://b.com">[1]</a> ` +
+				`<a class="external" rel="nofollow" href="https://c.com">https://c.com</a> ` +
+				`[javascript:alert(1) x]</p>` + "\n"},
+		{"lists",
+			"* a\n** b\n# c\n; d : e",
+			"<ul>\n<li>a<ul>\n<li>b</li>\n</ul>\n</li>\n</ul>\n<ol>\n<li>c</li>\n</ol>\n<dl>\n<dt>d</dt>\n<dd>e</dd>\n</dl>\n"},
+		{"table",
+			"{| class=\"wikitable\" onclick=\"x()\"\n|+ Cap\n! A\n|-\n| colspan=\"2\" style=\"background:url(x)\" | b\n|}",
+			"<table class=\"wikitable\">\n<caption>Cap</caption>\n<tr>\n<th>A</th>\n</tr>\n<tr>\n<td colspan=\"2\">b</td>\n</tr>\n</table>\n"},
+		{"references",
+			"a<ref name=\"x\">one</ref> b<ref>two</ref> c<ref name=\"x\"/>\n== Notes ==\n<references/>",
+			`<p>a<sup class="reference"><a href="#cite_note-1">[1]</a></sup> ` +
+				`b<sup class="reference"><a href="#cite_note-2">[2]</a></sup> ` +
+				`c<sup class="reference"><a href="#cite_note-1">[1]</a></sup></p>` + "\n" +
+				`<h2 id="Notes">Notes</h2>` + "\n" +
+				`<ol class="references">` + "\n" +
+				`<li id="cite_note-1" value="1">one</li>` + "\n" +
+				`<li id="cite_note-2" value="2">two</li>` + "\n" +
+				"</ol>\n"},
+		{"list-defined references",
+			"a<ref name=\"x\"/> b<ref name=\"y\"/>\n\n<references>\n<ref name=\"y\">two</ref>\n<ref name=\"x\">one</ref>\n</references>",
+			`<p>a<sup class="reference"><a href="#cite_note-1">[1]</a></sup> ` +
+				`b<sup class="reference"><a href="#cite_note-2">[2]</a></sup></p>` + "\n" +
+				`<ol class="references">` + "\n" +
+				`<li id="cite_note-1" value="1">one</li>` + "\n" +
+				`<li id="cite_note-2" value="2">two</li>` + "\n" +
+				"</ol>\n"},
+		{"unsafe styles",
+			"<span style=\"behavior: url(x.htc)\">a</span><span style=\"BEHAVIOR:x\">b</span>",
+			"<p><span>a</span><span>b</span></p>\n"},
+		{"sanitized tags",
+			"<span style=\"color:red\" onmouseover=\"x()\">a</span><script>b</script><nowiki><b></nowiki>",
+			`<p><span style="color:red">a</span>&lt;script&gt;b&lt;/script&gt;&lt;b&gt;</p>` + "\n"},
+		{"templates are not rendered",
+			"a{{cite web|title=b}}c",
+			"<p>ac</p>\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := render.RenderHTML(&sb, ast.Parse(tc.in), opts); err != nil {
+				t.Fatal(err)
+			}
+			if got := sb.String(); got != tc.want {
+				t.Errorf("RenderHTML(%q)\ngot:  %q\nwant: %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_RenderPageHTML(t *testing.T) {
+	titles := wikipedia.TitleIndex{"Page": 1}
+	opts := render.HTMLOptions{
+		Expander: wikitext.NewExpander(nil, titles),
+		Titles:   titles,
+	}
+	p := &wikipedia.Page{
+		Title:     "Page",
+		Revisions: []*wikipedia.Revision{{Text: "{{#if:x|'''{{PAGENAME}}'''}} {{Missing}}"}},
+	}
+	var sb strings.Builder
+	if err := render.RenderPageHTML(&sb, p, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := `<p><b>Page</b> <a href="/wiki/Template:Missing" class="new" title="Template:Missing">Template:Missing</a></p>` + "\n"
+	if got := sb.String(); got != want {
+		t.Errorf("got:  %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,588 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/wikitext"
+	"github.com/sebnyberg/wikipedia/wikitext/ast"
+)
+
+// MarkdownOptions configures Markdown rendering.
+type MarkdownOptions struct {
+	// Expander expands the templates of a page before it is rendered. If
+	// nil, templates are not rendered.
+	Expander *wikitext.Expander
+
+	// Titles contains the pages that links may point to. Links to other
+	// pages are rendered as text. If nil, all links are rendered.
+	Titles wikipedia.TitleIndex
+
+	// PageURL returns the URL of the page with the provided title. By
+	// default, pages are linked as "/wiki/Title".
+	PageURL func(title string) string
+
+	// Interwiki decides which links are interlanguage links, which are
+	// not rendered. By default, the configuration of Wikipedia is used.
+	Interwiki *wikitext.Interwiki
+}
+
+// RenderMarkdown renders a document as Markdown.
+//
+// Headings, lists, emphasis, links and tables are converted to their
+// Markdown equivalents, with tables written as pipe tables. References
+// become footnotes. Unexpanded templates, comments, categories and
+// interlanguage links are not rendered.
+func RenderMarkdown(w io.Writer, doc *ast.Document, opts MarkdownOptions) error {
+	md, _ := renderMarkdown(doc, opts)
+	_, err := io.WriteString(w, md)
+	return err
+}
+
+// renderMarkdown renders a document as Markdown, and returns the
+// categories of the document.
+func renderMarkdown(doc *ast.Document, opts MarkdownOptions) (string, []string) {
+	if opts.PageURL == nil {
+		opts.PageURL = DefaultPageURL
+	}
+	if opts.Interwiki == nil {
+		opts.Interwiki = wikitext.DefaultInterwiki()
+	}
+	r := &mdRenderer{
+		opts:     opts,
+		refNames: make(map[string]int),
+	}
+	md := r.blocks(doc.Children)
+	if len(r.footnotes) > 0 {
+		md += "\n\n" + strings.Join(r.footnotes, "\n")
+	}
+	if md != "" {
+		md += "\n"
+	}
+	return md, r.categories
+}
+
+type mdRenderer struct {
+	opts MarkdownOptions
+
+	footnotes  []string
+	refCount   int
+	refNames   map[string]int
+	categories []string
+}
+
+// blocks renders nodes as Markdown blocks separated by blank lines.
+// Consecutive inline nodes form a paragraph.
+func (r *mdRenderer) blocks(nodes []ast.Node) string {
+	var res []string
+	var inline []ast.Node
+	add := func(block string) {
+		if block = strings.TrimSpace(block); block != "" {
+			res = append(res, block)
+		}
+	}
+	flush := func() {
+		add(r.inline(inline))
+		inline = nil
+	}
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ast.Paragraph:
+			flush()
+			add(r.inline(n.Children))
+		case *ast.Heading:
+			flush()
+			if text := strings.TrimSpace(r.inline(n.Children)); text != "" {
+				add(strings.Repeat("#", n.Level) + " " + text)
+			}
+		case *ast.List:
+			flush()
+			add(r.list(n))
+		case *ast.Table:
+			flush()
+			add(r.table(n))
+		case *ast.HorizontalRule:
+			flush()
+			add("---")
+		case *ast.Tag:
+			switch n.Name {
+			case "pre", "syntaxhighlight", "source":
+				flush()
+				add(codeBlock(n, n.Attrs["lang"]))
+			case "blockquote":
+				flush()
+				add(quote(r.blocks(n.Children)))
+			case "div", "center", "poem", "references":
+				flush()
+				add(r.blocks(n.Children))
+			default:
+				inline = append(inline, n)
+			}
+		default:
+			inline = append(inline, n)
+		}
+	}
+	flush()
+	return strings.Join(res, "\n\n")
+}
+
+// codeBlock renders the contents of a tag as a fenced code block.
+func codeBlock(t *ast.Tag, lang string) string {
+	var sb strings.Builder
+	for _, child := range t.Children {
+		if text, ok := child.(*ast.Text); ok {
+			sb.WriteString(text.Value)
+		}
+	}
+	code := strings.Trim(sb.String(), "\n")
+	if t.Name == "pre" {
+		code = html.UnescapeString(code)
+	}
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+func quote(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// list renders a list. Nested lists are indented to the content of their
+// item. Definition terms are rendered in bold, and definitions as text.
+func (r *mdRenderer) list(l *ast.List) string {
+	var items []string
+	for i, item := range l.Items {
+		var marker string
+		switch item.Marker {
+		case '*':
+			marker = "- "
+		case '#':
+			marker = strconv.Itoa(i+1) + ". "
+		}
+		var inline, nested []ast.Node
+		for _, child := range item.Children {
+			if _, ok := child.(*ast.List); ok {
+				nested = append(nested, child)
+			} else {
+				inline = append(inline, child)
+			}
+		}
+		text := strings.TrimSpace(r.inline(inline))
+		if item.Marker == ';' && text != "" {
+			text = "**" + text + "**"
+		}
+		lines := []string{marker + text}
+		indent := strings.Repeat(" ", len(marker))
+		for _, n := range nested {
+			for _, line := range strings.Split(r.list(n.(*ast.List)), "\n") {
+				if line != "" {
+					line = indent + line
+				}
+				lines = append(lines, line)
+			}
+		}
+		items = append(items, strings.TrimRight(strings.Join(lines, "\n"), " "))
+	}
+	if l.Marker == ';' || l.Marker == ':' {
+		// Lines without list markers would be joined into one paragraph
+		return strings.Join(items, "\n\n")
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders a table as a pipe table. The first row is the header.
+// Cells spanning multiple columns are followed by empty cells, and block
+// content in cells is joined by line breaks.
+func (r *mdRenderer) table(t *ast.Table) string {
+	var rows [][]string
+	width := 0
+	for _, row := range t.Rows {
+		if len(row.Cells) == 0 {
+			continue
+		}
+		var cells []string
+		for _, cell := range row.Cells {
+			text := strings.ReplaceAll(r.blocks(cell.Children), "\n\n", "<br>")
+			text = strings.ReplaceAll(text, "\n", "<br>")
+			cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+			if n, err := strconv.Atoi(cell.Attrs["colspan"]); err == nil {
+				for i := 1; i < n && i < 1000; i++ {
+					cells = append(cells, "")
+				}
+			}
+		}
+		if len(cells) > width {
+			width = len(cells)
+		}
+		rows = append(rows, cells)
+	}
+	if len(rows) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	if caption := strings.TrimSpace(r.inline(t.Caption)); caption != "" {
+		sb.WriteString("**" + caption + "**\n\n")
+	}
+	writeRow := func(cells []string) {
+		sb.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+	}
+	writeRow(rows[0])
+	sep := make([]string, width)
+	for i := range sep {
+		sep[i] = "---"
+	}
+	writeRow(sep)
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// inline renders inline nodes. Block nodes nested in inline content, e.g.
+// lists in template arguments, are rendered as blocks.
+func (r *mdRenderer) inline(nodes []ast.Node) string {
+	var sb strings.Builder
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *ast.Text:
+			sb.WriteString(escapeMarkdown(n.Value))
+		case *ast.Bold:
+			sb.WriteString(emphasis(r.inline(n.Children), "**"))
+		case *ast.Italic:
+			sb.WriteString(emphasis(r.inline(n.Children), "*"))
+		case *ast.Link:
+			sb.WriteString(r.link(n))
+		case *ast.ExternalLink:
+			sb.WriteString(r.externalLink(n))
+		case *ast.Tag:
+			sb.WriteString(r.tag(n))
+		case *ast.Paragraph, *ast.Heading, *ast.List, *ast.Table, *ast.HorizontalRule:
+			sb.WriteString("\n\n" + r.blocks([]ast.Node{n}) + "\n\n")
+		}
+	}
+	return sb.String()
+}
+
+// emphasis wraps text in emphasis markers. Surrounding whitespace is kept
+// outside of the markers, as Markdown requires.
+func emphasis(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	start := strings.Index(text, trimmed)
+	return text[:start] + marker + trimmed + marker + text[start+len(trimmed):]
+}
+
+func (r *mdRenderer) link(l *ast.Link) string {
+	target := strings.TrimSpace(l.Target)
+	colon := strings.HasPrefix(target, ":")
+	ns := namespace(target)
+	label := ""
+	if l.Children != nil {
+		label = r.inline(l.Children)
+	} else {
+		label = escapeMarkdown(strings.TrimPrefix(strings.TrimPrefix(target, ":"), "#"))
+	}
+	label += escapeMarkdown(l.Trail)
+
+	switch {
+	case !colon && ns == "category":
+		r.categories = append(r.categories, wikitext.NormalizeTitle(target))
+		return ""
+	case !colon && (ns == "file" || ns == "image"):
+		// Media files are not exported
+		return ""
+	case !colon && r.opts.Interwiki.Languages[ns] && !r.opts.Interwiki.Namespaces[ns]:
+		return ""
+	case strings.HasPrefix(target, "#"):
+		return "[" + label + "](#" + markdownAnchor(target[1:]) + ")"
+	}
+
+	title := wikitext.NormalizeTitle(target)
+	if r.opts.Titles != nil {
+		if _, ok := r.opts.Titles[title]; !ok {
+			return label
+		}
+	}
+	href := r.opts.PageURL(title)
+	if idx := strings.IndexByte(target, '#'); idx != -1 {
+		href += "#" + markdownAnchor(target[idx+1:])
+	}
+	return "[" + label + "](" + href + ")"
+}
+
+// markdownAnchor returns the anchor that Markdown renderers commonly
+// generate for a heading, e.g. "early-life" for "Early life".
+func markdownAnchor(heading string) string {
+	heading = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(heading, "_", " ")))
+	return url.PathEscape(strings.ReplaceAll(heading, " ", "-"))
+}
+
+func (r *mdRenderer) externalLink(l *ast.ExternalLink) string {
+	if !safeURL(l.URL) {
+		return escapeMarkdown(l.URL) + r.inline(l.Children)
+	}
+	href := strings.NewReplacer("(", "%28", ")", "%29", " ", "%20").Replace(l.URL)
+	if l.Children == nil {
+		return "<" + href + ">"
+	}
+	return "[" + r.inline(l.Children) + "](" + href + ")"
+}
+
+func (r *mdRenderer) tag(t *ast.Tag) string {
+	switch t.Name {
+	case "ref":
+		return r.ref(t)
+	case "br":
+		return "<br>"
+	case "nowiki":
+		return r.inline(t.Children)
+	case "math", "chem", "ce":
+		var sb strings.Builder
+		for _, child := range t.Children {
+			if text, ok := child.(*ast.Text); ok {
+				sb.WriteString(text.Value)
+			}
+		}
+		return "$" + strings.TrimSpace(sb.String()) + "$"
+	case "b", "strong":
+		return emphasis(r.inline(t.Children), "**")
+	case "i", "em":
+		return emphasis(r.inline(t.Children), "*")
+	case "code", "tt", "kbd", "samp":
+		return "`" + strings.ReplaceAll(plainText(t.Children), "`", "'") + "`"
+	case "pre", "syntaxhighlight", "source", "blockquote", "div", "center", "poem":
+		return "\n\n" + r.blocks([]ast.Node{t}) + "\n\n"
+	case "references", "gallery", "imagemap", "score", "timeline", "graph",
+		"templatedata", "mapframe", "inputbox", "hiero":
+		return ""
+	}
+	return r.inline(t.Children)
+}
+
+// ref renders a reference as a footnote. Named references which have
+// been used before reuse their footnote.
+func (r *mdRenderer) ref(t *ast.Tag) string {
+	name := t.Attrs["name"]
+	n, seen := r.refNames[name]
+	if !seen || name == "" {
+		r.refCount++
+		n = r.refCount
+		if name != "" {
+			r.refNames[name] = n
+		}
+	}
+	id := strconv.Itoa(n)
+	if content := strings.TrimSpace(r.inline(t.Children)); content != "" && (!seen || name == "") {
+		content = strings.Join(strings.Fields(content), " ")
+		r.footnotes = append(r.footnotes, "[^"+id+"]: "+content)
+	}
+	return "[^" + id + "]"
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"[", `\[`,
+	"]", `\]`,
+	"<", `\<`,
+	">", `\>`,
+)
+
+// escapeMarkdown decodes character references and escapes characters
+// which are special in Markdown.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(html.UnescapeString(s))
+}
+
+// MarkdownPath returns the slash-separated path of the Markdown file of a
+// page. Pages outside of the main namespace are placed in a directory
+// named by their namespace, and subpages in a directory named by their
+// parent page, e.g. "Help/Editing/Tables.md" for "Help:Editing/Tables".
+// The main namespace has no subpages, so its slashes are percent-encoded
+// and its pages never share a path with a page in another namespace.
+// Characters which are not allowed in file names, and leading dots, are
+// percent-encoded as well, so that distinct titles have distinct paths.
+func MarkdownPath(title string) string {
+	ns, name := wikitext.SplitNamespace(title)
+	name = escapeFileName(strings.ReplaceAll(name, " ", "_"))
+	segments := strings.Split(name, "/")
+	subpage := ns != ""
+	for _, seg := range segments {
+		if seg == "" || seg == "." || seg == ".." {
+			// Not a valid directory name
+			subpage = false
+		}
+	}
+	if !subpage {
+		segments = []string{strings.ReplaceAll(name, "/", "%2F")}
+	}
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ".") {
+			segments[i] = "%2E" + seg[1:]
+		}
+	}
+	if ns != "" {
+		segments = append([]string{ns}, segments...)
+	}
+	return strings.Join(segments, "/") + ".md"
+}
+
+// escapeFileName percent-encodes characters that are not allowed in file
+// names on common file systems. Percent signs are encoded as well, so
+// that distinct titles never share a file name.
+func escapeFileName(name string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c < 0x20 || c == 0x7f || strings.IndexByte(`%\:*?"<>|`, c) != -1 {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// relativeURL returns the URL of the file at the slash-separated path to,
+// relative to the file at the path from.
+func relativeURL(from, to string) string {
+	fromDirs := strings.Split(path.Dir(from), "/")
+	toParts := strings.Split(to, "/")
+	if fromDirs[0] == "." {
+		fromDirs = nil
+	}
+	i := 0
+	for i < len(fromDirs) && i < len(toParts)-1 && fromDirs[i] == toParts[i] {
+		i++
+	}
+	var parts []string
+	for range fromDirs[i:] {
+		parts = append(parts, "..")
+	}
+	for _, part := range toParts[i:] {
+		parts = append(parts, url.PathEscape(part))
+	}
+	return strings.Join(parts, "/")
+}
+
+// ErrPathExists is returned when writing a page to a file which already
+// exists. The page is not written, and writing can continue with the next
+// page.
+var ErrPathExists = errors.New("path exists")
+
+type markdownWriter struct {
+	dir  string
+	opts MarkdownOptions
+}
+
+// NewMarkdownWriter returns a writer that converts the latest revision of
+// each page to Markdown, and writes it to a separate file in the provided
+// directory, at the path given by MarkdownPath.
+//
+// Links to pages in opts.Titles become links relative to the file of the
+// page, and opts.PageURL is ignored. Each file starts with a front matter
+// block holding the ID, title, revision timestamp and categories of the
+// page. Redirects are written as a link to their target.
+//
+// If the file of a page already exists, e.g. for titles which differ only
+// by case on a case-insensitive file system, the page is not written and
+// an error wrapping ErrPathExists is returned.
+func NewMarkdownWriter(dir string, opts MarkdownOptions) (wikipedia.PageWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &markdownWriter{dir: dir, opts: opts}, nil
+}
+
+func (w *markdownWriter) Write(p *wikipedia.Page) error {
+	name := MarkdownPath(p.Title)
+	opts := w.opts
+	opts.PageURL = func(title string) string {
+		return relativeURL(name, MarkdownPath(title))
+	}
+
+	var text string
+	switch {
+	case p.RedirectTitle != "":
+		text = "Redirect to [[" + p.RedirectTitle + "]]"
+	case w.opts.Expander != nil:
+		var err error
+		if text, err = w.opts.Expander.Expand(p); err != nil {
+			return err
+		}
+	case len(p.Revisions) > 0:
+		text = p.Revisions[len(p.Revisions)-1].Text
+	}
+	body, categories := renderMarkdown(ast.Parse(text), opts)
+
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	fmt.Fprintf(&sb, "id: %v\n", p.Id)
+	fmt.Fprintf(&sb, "title: %v\n", strconv.Quote(p.Title))
+	if len(p.Revisions) > 0 && p.Revisions[len(p.Revisions)-1].Ts != nil {
+		ts := p.Revisions[len(p.Revisions)-1].Ts.AsTime()
+		fmt.Fprintf(&sb, "timestamp: %v\n", ts.Format(time.RFC3339))
+	}
+	if len(categories) > 0 {
+		sb.WriteString("categories:\n")
+		seen := make(map[string]bool, len(categories))
+		for _, c := range categories {
+			if !seen[c] {
+				seen[c] = true
+				fmt.Fprintf(&sb, "  - %v\n", strconv.Quote(strings.TrimPrefix(c, "Category:")))
+			}
+		}
+	}
+	sb.WriteString("---\n\n")
+	sb.WriteString(body)
+
+	filename := filepath.Join(w.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filename, os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("page %v (%q): %v: %w", p.Id, p.Title, name, ErrPathExists)
+		}
+		return err
+	}
+	if _, err := io.WriteString(f, sb.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (w *markdownWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,158 @@
+package render_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sebnyberg/wikipedia"
+	"github.com/sebnyberg/wikipedia/wikitext/ast"
+	"github.com/sebnyberg/wikipedia/wikitext/render"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func Test_RenderMarkdown(t *testing.T) {
+	opts := render.MarkdownOptions{
+		Titles: wikipedia.TitleIndex{"Stockholm": 1},
+	}
+	for _, tc := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"emphasis",
+			"Hello '''bold''' and ''it'' *_x_*",
+			"Hello **bold** and *it* \\*\\_x\\_\\*\n"},
+		{"headings",
+			"== A ==\ntext\n=== B ===",
+			"## A\n\ntext\n\n### B\n"},
+		{"links",
+			"[[stockholm#Early life|the city]] [[Uppsala]]s [[#Top]] [https://a.com A] https://b.com",
+			"[the city](/wiki/Stockholm#early-life) Uppsalas [Top](#top) [A](https://a.com) <https://b.com>\n"},
+		{"categories, files and language links",
+			"a[[Category:Cities]][[File:X.png|thumb]][[de:Stockholm]]",
+			"a\n"},
+		{"lists",
+			"* a\n** b\n# c\n# d\n; e : f",
+			"- a\n  - b\n\n1. c\n2. d\n\n**e**\n\nf\n"},
+		{"table",
+			"{|\n|+ Cap\n! A !! B\n|-\n| colspan=\"2\" | x|y\n|-\n| 1 || 2\n|}",
+			"**Cap**\n\n| A | B |\n| --- | --- |\n| x\\|y |  |\n| 1 | 2 |\n"},
+		{"references",
+			"a<ref name=\"x\">one</ref> b<ref>two</ref> c<ref name=\"x\"/>",
+			"a[^1] b[^2] c[^1]\n\n[^1]: one\n[^2]: two\n"},
+		{"code",
+			"<syntaxhighlight lang=\"go\">\nx := *y\n</syntaxhighlight>\n----\n<code>a*b</code>",
+			"```go\nx := *y\n```\n\n---\n\n`a*b`\n"},
+		{"templates are not rendered",
+			"a{{cite web|title=b}}c",
+			"ac\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := render.RenderMarkdown(&sb, ast.Parse(tc.in), opts); err != nil {
+				t.Fatal(err)
+			}
+			if got := sb.String(); got != tc.want {
+				t.Errorf("RenderMarkdown(%q)\ngot:  %q\nwant: %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_MarkdownPath(t *testing.T) {
+	for _, tc := range []struct {
+		title string
+		want  string
+	}{
+		{"New York", "New_York.md"},
+		{"Category:Cities", "Category/Cities.md"},
+		{"Help:Editing/Tables", "Help/Editing/Tables.md"},
+		{"AC/DC", "AC%2FDC.md"},
+		{"Help/Foo", "Help%2FFoo.md"},
+		{"Help:Foo", "Help/Foo.md"},
+		{"./..", "%2E%2F...md"},
+		{"What?", "What%3F.md"},
+		{"100%", "100%25.md"},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			if got := render.MarkdownPath(tc.title); got != tc.want {
+				t.Errorf("MarkdownPath(%q) = %q, want %q", tc.title, got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_MarkdownWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	titles := wikipedia.TitleIndex{"Stockholm": 1, "Category:Cities": 2, "Sthlm": 3}
+	w, err := render.NewMarkdownWriter(dir, render.MarkdownOptions{Titles: titles})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2020, 6, 20, 19, 2, 32, 0, time.UTC)
+	pages := []*wikipedia.Page{
+		{
+			Id:    1,
+			Title: "Stockholm",
+			Revisions: []*wikipedia.Revision{{
+				Ts:   timestamppb.New(ts),
+				Text: "'''Stockholm''' is a [[Category:Cities|city]].[[Category:Cities]][[Category:Capitals]]",
+			}},
+		},
+		{
+			Id:        2,
+			Title:     "Category:Cities",
+			Revisions: []*wikipedia.Revision{{Text: "See [[:Category:Cities]] and [[Stockholm]]."}},
+		},
+		{Id: 3, Title: "Sthlm", RedirectTitle: "Stockholm"},
+	}
+	for _, p := range pages {
+		if err := w.Write(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Written to the same file as the first page, so it is skipped
+	dup := &wikipedia.Page{Id: 4, Title: "Stockholm", Revisions: []*wikipedia.Revision{{Text: "Duplicate"}}}
+	if err := w.Write(dup); !errors.Is(err, render.ErrPathExists) {
+		t.Errorf("invalid error for existing file: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{"Stockholm.md",
+			"---\nid: 1\ntitle: \"Stockholm\"\ntimestamp: 2020-06-20T19:02:32Z\n" +
+				"categories:\n  - \"Cities\"\n  - \"Capitals\"\n---\n\n" +
+				"**Stockholm** is a .\n"},
+		{"Category/Cities.md",
+			"---\nid: 2\ntitle: \"Category:Cities\"\n---\n\n" +
+				"See [Category:Cities](Cities.md) and [Stockholm](../Stockholm.md).\n"},
+		{"Sthlm.md",
+			"---\nid: 3\ntitle: \"Sthlm\"\n---\n\n" +
+				"Redirect to [Stockholm](Stockholm.md)\n"},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			b, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(tc.path)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); got != tc.want {
+				t.Errorf("got:  %q\nwant: %q", got, tc.want)
+			}
+		})
+	}
+}
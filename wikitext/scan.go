@@ -0,0 +1,13 @@
+package wikitext
+
+import "strings"
+
+// namespace returns the lower-cased namespace prefix of a link target, or
+// an empty string if the target has no prefix.
+func namespace(target string) string {
+	idx := strings.IndexByte(target, ':')
+	if idx <= 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(target[:idx]))
+}
@@ -0,0 +1,88 @@
+package wikitext
+
+import (
+	"regexp"
+
+	"github.com/sebnyberg/wikipedia/internal/scan"
+)
+
+// Section is a section of an article. The first section of an article is
+// the lead section, which has no heading and a level of zero.
+type Section struct {
+	// Heading is the plain text heading of the section.
+	Heading string
+
+	// Level is the heading level, i.e. the number of '=' around the
+	// heading. Top-level sections in articles have level 2.
+	Level int
+
+	// Parent is the index of the closest preceding section with a lower
+	// level, or -1 for the lead section.
+	Parent int
+
+	// Start and End are the byte offsets of the section in the text,
+	// including the heading line.
+	Start int
+	End   int
+
+	// BodyStart is the byte offset of the section body in the text.
+	BodyStart int
+
+	// Body is the text between the heading and the next heading.
+	// Subsections are not included.
+	Body string
+}
+
+var sectionHeadingRegexp = regexp.MustCompile(`(?m)^(={1,6})(.+?)(={1,6})[ \t]*$`)
+
+// SplitSections splits the text of an article into sections based on its
+// headings. The lead section is always returned first, even if it is empty.
+// Headings inside HTML comments are ignored.
+func SplitSections(text string) []Section {
+	masked := scan.MaskComments(text)
+	res := []Section{{Parent: -1}}
+	for _, m := range sectionHeadingRegexp.FindAllStringSubmatchIndex(masked, -1) {
+		open, close := m[3]-m[2], m[7]-m[6]
+		level := open
+		if close < level {
+			level = close
+		}
+		// Unbalanced '=' are part of the heading
+		inner := text[m[2]+level : m[7]-level]
+
+		prev := &res[len(res)-1]
+		prev.End = m[0]
+		prev.Body = text[prev.BodyStart:m[0]]
+
+		bodyStart := m[1]
+		if bodyStart < len(text) {
+			// Skip the line break after the heading
+			bodyStart++
+		}
+		res = append(res, Section{
+			Heading:   PlainText(inner, PlainTextOptions{}),
+			Level:     level,
+			Parent:    sectionParent(res, level),
+			Start:     m[0],
+			BodyStart: bodyStart,
+		})
+	}
+	last := &res[len(res)-1]
+	last.End = len(text)
+	if last.BodyStart > last.End {
+		last.BodyStart = last.End
+	}
+	last.Body = text[last.BodyStart:]
+	return res
+}
+
+// sectionParent returns the index of the closest section with a level
+// lower than the provided level.
+func sectionParent(sections []Section, level int) int {
+	for i := len(sections) - 1; i > 0; i-- {
+		if sections[i].Level < level {
+			return i
+		}
+	}
+	return 0
+}
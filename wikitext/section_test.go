@@ -0,0 +1,36 @@
+package wikitext_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+func Test_SplitSections(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  []wikitext.Section
+	}{
+		{"empty", "", []wikitext.Section{{Parent: -1}}},
+		{"lead only", "lead", []wikitext.Section{{Parent: -1, End: 4, Body: "lead"}}},
+		{"hierarchy", "lead\n== A ==\na\n=== B ===\nb\n== ''C'' ==<!-- x -->\n<!--\n== D ==\n-->", []wikitext.Section{
+			{Parent: -1, End: 5, Body: "lead\n"},
+			{Heading: "A", Level: 2, Parent: 0, Start: 5, End: 15, BodyStart: 13, Body: "a\n"},
+			{Heading: "B", Level: 3, Parent: 1, Start: 15, End: 27, BodyStart: 25, Body: "b\n"},
+			{Heading: "C", Level: 2, Parent: 0, Start: 27, End: 65, BodyStart: 49, Body: "<!--\n== D ==\n-->"},
+		}},
+		{"unbalanced", "==A===", []wikitext.Section{
+			{Parent: -1},
+			{Heading: "A=", Level: 2, Parent: 0, End: 6, BodyStart: 6},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := wikitext.SplitSections(tc.input)
+			if !cmp.Equal(tc.want, got) {
+				t.Errorf("invalid sections\n%v", cmp.Diff(tc.want, got))
+			}
+		})
+	}
+}
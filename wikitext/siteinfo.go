@@ -0,0 +1,46 @@
+package wikitext
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// SiteInfo contains the wiki configuration found in the <siteinfo> header
+// of a dump.
+type SiteInfo struct {
+	SiteName   string      `xml:"sitename"`
+	DBName     string      `xml:"dbname"`
+	Base       string      `xml:"base"`
+	Namespaces []Namespace `xml:"namespaces>namespace"`
+}
+
+// Namespace is a namespace of the wiki, e.g. {14, "Category"}.
+type Namespace struct {
+	Key  int32  `xml:"key,attr"`
+	Name string `xml:",chardata"`
+}
+
+// ReadSiteInfo reads the <siteinfo> header from the start of a dump.
+//
+// The reader is expected to read plaintext XML. For the multi-stream
+// download, the header is contained in the first bzip2 stream of the
+// pages file.
+func ReadSiteInfo(r io.Reader) (*SiteInfo, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find siteinfo tag, err: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "siteinfo" {
+			continue
+		}
+		var si SiteInfo
+		if err := dec.DecodeElement(&si, &start); err != nil {
+			return nil, fmt.Errorf("failed to parse siteinfo tag, err: %w", err)
+		}
+		return &si, nil
+	}
+}
@@ -0,0 +1,47 @@
+package wikitext_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+func Test_ReadSiteInfo(t *testing.T) {
+	const header = `<mediawiki xml:lang="sv">
+<siteinfo>
+	<sitename>Wikipedia</sitename>
+	<dbname>svwiki</dbname>
+	<base>https://sv.wikipedia.org/wiki/Portal:Huvudsida</base>
+	<case>first-letter</case>
+	<namespaces>
+		<namespace key="-2" case="first-letter">Media</namespace>
+		<namespace key="0" case="first-letter" />
+		<namespace key="14" case="first-letter">Kategori</namespace>
+	</namespaces>
+</siteinfo>
+<page>`
+
+	si, err := wikitext.ReadSiteInfo(strings.NewReader(header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &wikitext.SiteInfo{
+		SiteName: "Wikipedia",
+		DBName:   "svwiki",
+		Base:     "https://sv.wikipedia.org/wiki/Portal:Huvudsida",
+		Namespaces: []wikitext.Namespace{
+			{Key: -2, Name: "Media"},
+			{Key: 0},
+			{Key: 14, Name: "Kategori"},
+		},
+	}
+	if !cmp.Equal(want, si) {
+		t.Errorf("invalid siteinfo\n%v", cmp.Diff(want, si))
+	}
+
+	if _, err := wikitext.ReadSiteInfo(strings.NewReader("<mediawiki><page>")); err == nil {
+		t.Errorf("expected an error for a dump without siteinfo")
+	}
+}
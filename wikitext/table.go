@@ -0,0 +1,254 @@
+package wikitext
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sebnyberg/wikipedia/internal/scan"
+)
+
+// Table is a wikitable, i.e. a table written as {| ... |}.
+type Table struct {
+	Caption string
+	Attrs   map[string]string
+	Rows    []TableRow
+
+	// Start and End are the byte offsets of the table in the text.
+	Start int
+	End   int
+}
+
+// TableRow is a row in a table.
+type TableRow struct {
+	Attrs map[string]string
+	Cells []TableCell
+}
+
+// TableCell is a header or data cell in a table.
+type TableCell struct {
+	Header bool
+
+	// Text is the plain text contents of the cell.
+	Text string
+
+	// Raw is the wikitext contents of the cell.
+	Raw string
+
+	RowSpan int
+	ColSpan int
+	Attrs   map[string]string
+}
+
+// ParseTables returns the tables in the provided text, ordered by their
+// start offset. Tables nested inside cells of other tables are returned
+// as separate tables, and are removed from the text of the outer cell.
+func ParseTables(text string) []Table {
+	var res []Table
+	collectTables(scan.MaskComments(text), 0, &res)
+	return res
+}
+
+func collectTables(s string, offset int, res *[]Table) {
+	for i := 0; i < len(s); {
+		idx := strings.Index(s[i:], "{|")
+		if idx == -1 {
+			return
+		}
+		start := i + idx
+		end := scan.MatchTable(s, start)
+		if end == -1 {
+			return
+		}
+		if !atLineStart(s, start) {
+			i = start + 2
+			continue
+		}
+		t, cells := parseTable(s[start:end])
+		t.Start = offset + start
+		t.End = offset + end
+		*res = append(*res, t)
+		for _, c := range cells {
+			collectTables(s[start+c.start:start+c.end], offset+start+c.start, res)
+		}
+		i = end
+	}
+}
+
+// atLineStart returns true if s[i] is preceded only by whitespace or
+// indentation on its line.
+func atLineStart(s string, i int) bool {
+	for j := i - 1; j >= 0 && s[j] != '\n'; j-- {
+		if s[j] != ' ' && s[j] != '\t' && s[j] != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// span is the location of the raw contents of a cell within a table.
+type span struct {
+	start int
+	end   int
+}
+
+// parseTable parses a table from the text between and including the
+// opening "{|" and closing "|}". The spans of the cell contents are
+// returned so that nested tables can be parsed.
+func parseTable(s string) (Table, []span) {
+	var t Table
+	var spans []span
+	var row *TableRow
+	var cell *TableCell
+	var cellStart int
+
+	// finishCell sets the raw contents of the current cell, which may span
+	// multiple lines
+	finishCell := func(end int) {
+		if cell == nil {
+			return
+		}
+		cell.Raw = strings.TrimSpace(s[cellStart:end])
+		cell.Text = PlainText(lineBreakRegexp.ReplaceAllString(cell.Raw, " "), PlainTextOptions{Lists: true})
+		cell.Text = strings.Join(strings.Fields(cell.Text), " ")
+		spans = append(spans, span{cellStart, end})
+		cell = nil
+	}
+	addCell := func(header bool, start int, end int) {
+		raw := s[start:end]
+		c := TableCell{Header: header, RowSpan: 1, ColSpan: 1}
+		// A single pipe separates attributes from the contents
+		if idx := scan.IndexTopLevel(raw, '|'); idx != -1 && !strings.HasPrefix(raw[idx:], "||") {
+			c.Attrs = scan.ParseAttrs(raw[:idx])
+			start += idx + 1
+		}
+		if n, err := strconv.Atoi(c.Attrs["rowspan"]); err == nil && n > 0 {
+			c.RowSpan = n
+		}
+		if n, err := strconv.Atoi(c.Attrs["colspan"]); err == nil && n > 0 {
+			c.ColSpan = n
+		}
+		if row == nil {
+			t.Rows = append(t.Rows, TableRow{})
+			row = &t.Rows[len(t.Rows)-1]
+		}
+		row.Cells = append(row.Cells, c)
+		cell = &row.Cells[len(row.Cells)-1]
+		cellStart = start
+	}
+
+	firstLine := strings.IndexByte(s, '\n')
+	if firstLine == -1 {
+		return t, nil
+	}
+	t.Attrs = scan.ParseAttrs(s[2:firstLine])
+	bodyEnd := len(s) - 2
+
+	for pos := firstLine + 1; pos < bodyEnd; {
+		lineEnd := strings.IndexByte(s[pos:bodyEnd], '\n')
+		if lineEnd == -1 {
+			lineEnd = bodyEnd
+		} else {
+			lineEnd += pos
+		}
+		line := s[pos:lineEnd]
+		trimmed := strings.TrimLeft(line, " \t")
+		lineStart := pos + len(line) - len(trimmed)
+		next := lineEnd + 1
+
+		switch {
+		case strings.HasPrefix(trimmed, "{|"):
+			// Nested tables are part of the current cell
+			if end := scan.MatchTable(s[:bodyEnd], lineStart); end != -1 {
+				next = end
+			}
+		case strings.HasPrefix(trimmed, "|+"):
+			finishCell(pos)
+			t.Caption = PlainText(strings.TrimSpace(trimmed[2:]), PlainTextOptions{})
+		case strings.HasPrefix(trimmed, "|-"):
+			finishCell(pos)
+			t.Rows = append(t.Rows, TableRow{Attrs: scan.ParseAttrs(strings.TrimLeft(trimmed, "|-"))})
+			row = &t.Rows[len(t.Rows)-1]
+		case strings.HasPrefix(trimmed, "|"), strings.HasPrefix(trimmed, "!"):
+			finishCell(pos)
+			header := trimmed[0] == '!'
+			seps := []string{"||"}
+			if header {
+				seps = append(seps, "!!")
+			}
+			start := lineStart + 1
+			for _, end := range scan.SplitCells(s[:lineEnd], start, seps) {
+				addCell(header, start, end)
+				finishCell(end)
+				start = end + 2
+			}
+			// The last cell may continue on the next lines
+			addCell(header, start, lineEnd)
+		}
+		pos = next
+	}
+	finishCell(bodyEnd)
+	return t, spans
+}
+
+// Grid returns the plain text of the table as a rectangular grid, where
+// cells spanning multiple rows or columns are repeated in each position
+// that they cover.
+func (t *Table) Grid() [][]string {
+	var grid [][]string
+	taken := make(map[[2]int]bool)
+	set := func(r, c int, text string) {
+		for len(grid) <= r {
+			grid = append(grid, nil)
+		}
+		for len(grid[r]) <= c {
+			grid[r] = append(grid[r], "")
+		}
+		grid[r][c] = text
+		taken[[2]int{r, c}] = true
+	}
+
+	r := 0
+	for _, row := range t.Rows {
+		if len(row.Cells) == 0 {
+			continue
+		}
+		c := 0
+		for _, cell := range row.Cells {
+			for taken[[2]int{r, c}] {
+				c++
+			}
+			for i := 0; i < cell.RowSpan; i++ {
+				for j := 0; j < cell.ColSpan; j++ {
+					set(r+i, c+j, cell.Text)
+				}
+			}
+			c += cell.ColSpan
+		}
+		r++
+	}
+
+	// Pad rows to the same width
+	width := 0
+	for _, row := range grid {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	for i := range grid {
+		for len(grid[i]) < width {
+			grid[i] = append(grid[i], "")
+		}
+	}
+	return grid
+}
+
+// WriteCSV writes the grid of the table to w in CSV format.
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(t.Grid()); err != nil {
+		return err
+	}
+	return cw.Error()
+}
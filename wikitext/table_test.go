@@ -0,0 +1,79 @@
+package wikitext_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+const electionTable = `Results:
+{| class="wikitable" style="text-align:right"
+|+ ''2018 election''
+|-
+! Party !! Votes !! Seats
+|-
+| rowspan="2" | [[Social Democrats|S]] || 1,830,386 || 100
+|-
+| colspan=2 | ''(split)''
+|-
+| Moderates
+| 1,284,698<ref>SCB</ref>
+| 70
+{| class="wikitable"
+| nested
+|}
+|}`
+
+func Test_ParseTables(t *testing.T) {
+	tables := wikitext.ParseTables(electionTable)
+	if len(tables) != 2 {
+		t.Fatalf("expected 2 tables, got %v", len(tables))
+	}
+	table := tables[0]
+	if table.Caption != "2018 election" {
+		t.Errorf("invalid caption, expected: %v, got: %v", "2018 election", table.Caption)
+	}
+	if table.Attrs["class"] != "wikitable" {
+		t.Errorf("invalid table attributes: %v", table.Attrs)
+	}
+	if table.Start != 9 || table.End != len(electionTable) {
+		t.Errorf("invalid table offsets, got: %v-%v", table.Start, table.End)
+	}
+	cell := table.Rows[1].Cells[0]
+	want := wikitext.TableCell{
+		Text:    "S",
+		Raw:     "[[Social Democrats|S]]",
+		RowSpan: 2,
+		ColSpan: 1,
+		Attrs:   map[string]string{"rowspan": "2"},
+	}
+	if !cmp.Equal(want, cell) {
+		t.Errorf("invalid cell\n%v", cmp.Diff(want, cell))
+	}
+
+	wantGrid := [][]string{
+		{"Party", "Votes", "Seats"},
+		{"S", "1,830,386", "100"},
+		{"S", "(split)", "(split)"},
+		{"Moderates", "1,284,698", "70"},
+	}
+	if got := table.Grid(); !cmp.Equal(wantGrid, got) {
+		t.Errorf("invalid grid\n%v", cmp.Diff(wantGrid, got))
+	}
+
+	var buf bytes.Buffer
+	if err := table.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "Party,Votes,Seats\nS,\"1,830,386\",100\nS,(split),(split)\nModerates,\"1,284,698\",70\n"
+	if buf.String() != wantCSV {
+		t.Errorf("invalid csv\n%v", cmp.Diff(wantCSV, buf.String()))
+	}
+
+	nested := tables[1].Grid()
+	if !cmp.Equal([][]string{{"nested"}}, nested) {
+		t.Errorf("invalid nested table: %v", nested)
+	}
+}
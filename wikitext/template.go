@@ -0,0 +1,261 @@
+package wikitext
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sebnyberg/wikipedia/internal/scan"
+)
+
+// Template is a template or parser function invocation, e.g.
+// {{Infobox settlement|name=Stockholm}} or {{#if:x|y|z}}.
+type Template struct {
+	// Name is the normalized name of the template, without the "Template:"
+	// prefix. For parser functions, the name includes the leading '#'.
+	Name string
+
+	// Args contains the arguments passed to the template.
+	Args []Argument
+
+	// ParserFunction is true if the invocation is a parser function
+	// or a magic word taking arguments, such as {{#if:...}} or {{lc:...}}.
+	ParserFunction bool
+
+	// Depth is the number of templates that the invocation is nested in.
+	Depth int
+
+	// Start and End are the byte offsets of the invocation in the text.
+	Start int
+	End   int
+}
+
+// Argument is a template argument. Positional arguments are named by their
+// position, starting from "1", as in MediaWiki.
+type Argument struct {
+	Name  string
+	Value string
+}
+
+// Arg returns the value of the argument with the provided name, and
+// whether the argument exists. If the argument is passed multiple times,
+// the last value is returned.
+func (t *Template) Arg(name string) (string, bool) {
+	for i := len(t.Args) - 1; i >= 0; i-- {
+		if t.Args[i].Name == name {
+			return t.Args[i].Value, true
+		}
+	}
+	return "", false
+}
+
+// Magic words that take arguments after a colon, like parser functions.
+var colonFunctions = map[string]bool{
+	"lc":           true,
+	"uc":           true,
+	"lcfirst":      true,
+	"ucfirst":      true,
+	"urlencode":    true,
+	"anchorencode": true,
+	"fullurl":      true,
+	"localurl":     true,
+	"ns":           true,
+	"formatnum":    true,
+	"padleft":      true,
+	"padright":     true,
+	"plural":       true,
+	"grammar":      true,
+	"int":          true,
+	"displaytitle": true,
+	"defaultsort":  true,
+	"tag":          true,
+}
+
+// IsParserFunction returns true if fn, the text before the first colon
+// of an invocation, names a parser function or a magic word taking
+// arguments.
+func IsParserFunction(fn string) bool {
+	return strings.HasPrefix(fn, "#") || colonFunctions[strings.ToLower(fn)]
+}
+
+// ParseTemplates returns all template and parser function invocations in
+// the provided text, in the order in which they start. Invocations nested
+// inside the arguments of other templates are included. Template
+// parameters ({{{1}}}) are not returned, but templates inside their
+// default values are.
+func ParseTemplates(text string) []Template {
+	var res []Template
+	collectTemplates(text, 0, 0, &res)
+	return res
+}
+
+func collectTemplates(s string, offset int, depth int, res *[]Template) {
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "<!--"):
+			i = scan.MatchComment(s, i)
+		case strings.HasPrefix(s[i:], "{{"):
+			end := scan.MatchBraces(s, i)
+			if end == -1 {
+				i += 2
+				continue
+			}
+			if strings.HasPrefix(s[i:], "{{{") && strings.HasSuffix(s[:end], "}}}") {
+				collectTemplates(s[i+3:end-3], offset+i+3, depth, res)
+				i = end
+				continue
+			}
+			inner := s[i+2 : end-2]
+			t := newTemplate(inner)
+			t.Depth = depth
+			t.Start = offset + i
+			t.End = offset + end
+			if t.Name != "" {
+				*res = append(*res, t)
+			}
+			collectTemplates(inner, offset+i+2, depth+1, res)
+			i = end
+		default:
+			i++
+		}
+	}
+}
+
+// newTemplate parses the contents of a template invocation, i.e. the
+// text between the opening and closing braces.
+func newTemplate(inner string) Template {
+	var t Template
+	parts := scan.SplitPipes(inner)
+	name := parts[0]
+	args := parts[1:]
+
+	if idx := strings.IndexByte(name, ':'); idx != -1 {
+		fn := strings.TrimSpace(name[:idx])
+		if IsParserFunction(fn) {
+			t.ParserFunction = true
+			t.Name = strings.ToLower(fn)
+			args = append([]string{name[idx+1:]}, args...)
+		}
+	}
+	if !t.ParserFunction {
+		t.Name = NormalizeTemplateName(name)
+	}
+
+	pos := 0
+	for _, arg := range args {
+		if t.ParserFunction {
+			// Parser function arguments are positional, '=' is part of
+			// the value
+			pos++
+			t.Args = append(t.Args, Argument{
+				Name:  strconv.Itoa(pos),
+				Value: strings.TrimSpace(arg),
+			})
+			continue
+		}
+		if idx := scan.IndexTopLevel(arg, '='); idx != -1 {
+			t.Args = append(t.Args, Argument{
+				Name:  strings.TrimSpace(arg[:idx]),
+				Value: strings.TrimSpace(arg[idx+1:]),
+			})
+			continue
+		}
+		pos++
+		t.Args = append(t.Args, Argument{
+			Name:  strconv.Itoa(pos),
+			Value: arg,
+		})
+	}
+	return t
+}
+
+// NormalizeTemplateName normalizes a template name the way MediaWiki
+// resolves it: comments and the "Template:" prefix are removed,
+// underscores are replaced by spaces, whitespace is collapsed and the
+// first letter is upper-cased.
+func NormalizeTemplateName(name string) string {
+	name = normalizeTitle(stripComments(name))
+	if namespace(name) == "template" {
+		name = upperFirst(strings.TrimSpace(name[strings.IndexByte(name, ':')+1:]))
+	}
+	return name
+}
+
+// Canonical names of the namespaces of Wikipedia, by lower-case name or
+// alias.
+var canonicalNamespaces = map[string]string{
+	"media":          "Media",
+	"special":        "Special",
+	"talk":           "Talk",
+	"user":           "User",
+	"user talk":      "User talk",
+	"wikipedia":      "Wikipedia",
+	"project":        "Wikipedia",
+	"wp":             "Wikipedia",
+	"wikipedia talk": "Wikipedia talk",
+	"file":           "File",
+	"image":          "File",
+	"file talk":      "File talk",
+	"mediawiki":      "MediaWiki",
+	"template":       "Template",
+	"template talk":  "Template talk",
+	"help":           "Help",
+	"category":       "Category",
+	"category talk":  "Category talk",
+	"portal":         "Portal",
+	"draft":          "Draft",
+	"module":         "Module",
+}
+
+// NormalizeTitle returns the title of the page that a link target refers
+// to. The fragment and a leading colon are removed, underscores are
+// replaced by spaces, whitespace is collapsed and the first letters of the
+// title and of a known namespace are upper-cased, so that e.g.
+// "category:foo_bar#x" becomes "Category:Foo bar".
+func NormalizeTitle(target string) string {
+	if idx := strings.IndexByte(target, '#'); idx != -1 {
+		target = target[:idx]
+	}
+	target = normalizeTitle(strings.TrimPrefix(strings.TrimSpace(target), ":"))
+	if ns, ok := canonicalNamespaces[namespace(target)]; ok {
+		target = ns + ":" + upperFirst(strings.TrimSpace(target[strings.IndexByte(target, ':')+1:]))
+	}
+	return target
+}
+
+// SplitNamespace splits a normalized title into its namespace and name,
+// e.g. "Category" and "Cities" for "Category:Cities". The namespace is
+// empty for pages in the main namespace.
+func SplitNamespace(title string) (string, string) {
+	if ns, ok := canonicalNamespaces[namespace(title)]; ok {
+		return ns, strings.TrimSpace(title[strings.IndexByte(title, ':')+1:])
+	}
+	return "", title
+}
+
+// normalizeTitle replaces underscores by spaces, collapses whitespace and
+// upper-cases the first letter of a page title.
+func normalizeTitle(title string) string {
+	title = strings.ReplaceAll(title, "_", " ")
+	title = strings.Join(strings.Fields(title), " ")
+	return upperFirst(title)
+}
+
+// upperFirst upper-cases the first letter of s.
+func upperFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[n:]
+}
+
+// lowerFirst lower-cases the first letter of s.
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToLower(r)) + s[n:]
+}
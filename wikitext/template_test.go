@@ -0,0 +1,59 @@
+package wikitext_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/sebnyberg/wikipedia/wikitext"
+)
+
+func Test_ParseTemplates(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  []wikitext.Template
+	}{
+		{"empty", "", nil},
+		{"no templates", "text [[link]] {{{1}}}", nil},
+		{"arguments", "{{infobox_settlement|Stockholm| name = Sthlm |[[a|b]]}}", []wikitext.Template{
+			{Name: "Infobox settlement", Args: []wikitext.Argument{
+				{Name: "1", Value: "Stockholm"},
+				{Name: "name", Value: "Sthlm"},
+				{Name: "2", Value: "[[a|b]]"},
+			}, End: 55},
+		}},
+		{"template prefix", "{{Template:Cite web}}", []wikitext.Template{
+			{Name: "Cite web", End: 21},
+		}},
+		{"nested", "a{{T|x={{lang|fr|b=c}}}}", []wikitext.Template{
+			{Name: "T", Args: []wikitext.Argument{{Name: "x", Value: "{{lang|fr|b=c}}"}}, Start: 1, End: 24},
+			{Name: "Lang", Args: []wikitext.Argument{{Name: "1", Value: "fr"}, {Name: "b", Value: "c"}},
+				Depth: 1, Start: 7, End: 22},
+		}},
+		{"parser function", "{{#if: {{{1|}}} | a=b | {{c}} }}", []wikitext.Template{
+			{Name: "#if", ParserFunction: true, Args: []wikitext.Argument{
+				{Name: "1", Value: "{{{1|}}}"},
+				{Name: "2", Value: "a=b"},
+				{Name: "3", Value: "{{c}}"},
+			}, End: 32},
+			{Name: "C", Depth: 1, Start: 24, End: 29},
+		}},
+		{"template in parameter default", "{{{1|{{x}}}}}", []wikitext.Template{
+			{Name: "X", Start: 5, End: 10},
+		}},
+		{"commented out", "<!-- {{x}} -->{{y}}", []wikitext.Template{
+			{Name: "Y", Start: 14, End: 19},
+		}},
+		{"unbalanced", "{{x|{{y}}", []wikitext.Template{
+			{Name: "Y", Start: 4, End: 9},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := wikitext.ParseTemplates(tc.input)
+			if !cmp.Equal(tc.want, got, cmpopts.EquateEmpty()) {
+				t.Errorf("invalid templates\n%v", cmp.Diff(tc.want, got, cmpopts.EquateEmpty()))
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package wikitext
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sebnyberg/wikipedia"
+	wikiproto "github.com/sebnyberg/wikipedia/internal/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// Format is the file format of extracted records.
+type Format int
+
+const (
+	// FormatProto writes records in the same zstd-compressed
+	// length-delimited protobuf format as page datasets.
+	FormatProto Format = iota
+
+	// FormatJSONLines writes one JSON-encoded record per line.
+	FormatJSONLines
+)
+
+// ParseFormat parses a format name, either 'proto' or 'jsonl'.
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "proto":
+		return FormatProto, nil
+	case "jsonl":
+		return FormatJSONLines, nil
+	default:
+		return 0, fmt.Errorf("invalid format '%v', must be either 'proto' or 'jsonl'", s)
+	}
+}
+
+type plainTextWriter struct {
+	w    wikipedia.PageWriter
+	opts PlainTextOptions
+}
+
+// NewPlainTextWriter returns a writer that converts the text of each
+// revision to plain text before passing the page on to w.
+//
+// Revisions are converted in place, so the written page must not be
+// used by the caller after it has been written.
+func NewPlainTextWriter(w wikipedia.PageWriter, opts PlainTextOptions) wikipedia.PageWriter {
+	return &plainTextWriter{
+		w:    w,
+		opts: opts,
+	}
+}
+
+func (w *plainTextWriter) Write(p *wikipedia.Page) error {
+	for _, rev := range p.Revisions {
+		rev.Text = PlainText(rev.Text, w.opts)
+	}
+	return w.w.Write(p)
+}
+
+func (w *plainTextWriter) Close() error {
+	return w.w.Close()
+}
+
+type messageWriter interface {
+	Write(proto.Message) error
+	Close() error
+}
+
+// extractWriter is a page writer which extracts records from each page and
+// writes them to a file.
+type extractWriter struct {
+	w       messageWriter
+	extract func(p *wikipedia.Page) []proto.Message
+}
+
+func newExtractWriter(
+	path string,
+	format Format,
+	extract func(p *wikipedia.Page) []proto.Message,
+) (wikipedia.PageWriter, error) {
+	var w messageWriter
+	var err error
+	switch format {
+	case FormatProto:
+		w, err = wikiproto.NewMessageWriter(path)
+	case FormatJSONLines:
+		w, err = wikiproto.NewJSONLinesWriter(path)
+	default:
+		err = fmt.Errorf("invalid format: %v", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &extractWriter{
+		w:       w,
+		extract: extract,
+	}, nil
+}
+
+func (w *extractWriter) Write(p *wikipedia.Page) error {
+	for _, msg := range w.extract(p) {
+		if err := w.w.Write(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *extractWriter) Close() error {
+	return w.w.Close()
+}
+
+// NewTemplateWriter returns a writer that extracts the template invocations
+// of the latest revision of each page, and writes them as Template messages
+// to a file at the provided path.
+//
+// If the provided path already exists, an error is returned.
+func NewTemplateWriter(path string) (wikipedia.PageWriter, error) {
+	return newExtractWriter(path, FormatProto, templateMessages)
+}
+
+func templateMessages(p *wikipedia.Page) []proto.Message {
+	templates := ParseTemplates(latestText(p))
+	res := make([]proto.Message, len(templates))
+	for i, t := range templates {
+		res[i] = &wikipedia.Template{
+			PageId:         p.Id,
+			PageTitle:      p.Title,
+			Name:           t.Name,
+			Args:           templateArgs(t.Args),
+			ParserFunction: t.ParserFunction,
+			Depth:          int32(t.Depth),
+		}
+	}
+	return res
+}
+
+func templateArgs(args []Argument) []*wikipedia.TemplateArgument {
+	res := make([]*wikipedia.TemplateArgument, len(args))
+	for i, arg := range args {
+		res[i] = &wikipedia.TemplateArgument{
+			Name:  arg.Name,
+			Value: arg.Value,
+		}
+	}
+	return res
+}
+
+// NewInfoboxWriter returns a writer that extracts the infoboxes of the
+// latest revision of each page, and writes them as Infobox messages
+// to a file at the provided path.
+//
+// If the provided path already exists, an error is returned.
+func NewInfoboxWriter(path string, format Format) (wikipedia.PageWriter, error) {
+	return newExtractWriter(path, format, infoboxMessages)
+}
+
+func infoboxMessages(p *wikipedia.Page) []proto.Message {
+	infoboxes := ParseInfoboxes(latestText(p))
+	res := make([]proto.Message, len(infoboxes))
+	for i, box := range infoboxes {
+		msg := &wikipedia.Infobox{
+			PageId:    p.Id,
+			PageTitle: p.Title,
+			Type:      box.Type,
+			Fields:    make([]*wikipedia.InfoboxField, len(box.Fields)),
+		}
+		for j, f := range box.Fields {
+			msg.Fields[j] = &wikipedia.InfoboxField{
+				Key:   f.Key,
+				Raw:   f.Raw,
+				Value: f.Value,
+			}
+		}
+		res[i] = msg
+	}
+	return res
+}
+
+// NewSectionWriter returns a writer that splits the latest revision of each
+// page into sections, and writes them as Section messages to a file at the
+// provided path. If plain is not nil, section bodies are converted to plain
+// text using the provided options.
+//
+// If the provided path already exists, an error is returned.
+func NewSectionWriter(path string, format Format, plain *PlainTextOptions) (wikipedia.PageWriter, error) {
+	return newExtractWriter(path, format, func(p *wikipedia.Page) []proto.Message {
+		return sectionMessages(p, plain)
+	})
+}
+
+func sectionMessages(p *wikipedia.Page, plain *PlainTextOptions) []proto.Message {
+	sections := SplitSections(latestText(p))
+	res := make([]proto.Message, len(sections))
+	for i, s := range sections {
+		body := s.Body
+		if plain != nil {
+			body = PlainText(body, *plain)
+		}
+		res[i] = &wikipedia.Section{
+			PageId:    p.Id,
+			PageTitle: p.Title,
+			Index:     int32(i),
+			Heading:   s.Heading,
+			Level:     int32(s.Level),
+			Parent:    int32(s.Parent),
+			Start:     int32(s.Start),
+			End:       int32(s.End),
+			Body:      body,
+		}
+	}
+	return res
+}
+
+// NewExternalLinkWriter returns a writer that extracts the external links
+// of the latest revision of each page, and writes them as ExternalLink
+// messages to a file at the provided path.
+//
+// If the provided path already exists, an error is returned.
+func NewExternalLinkWriter(path string, format Format) (wikipedia.PageWriter, error) {
+	return newExtractWriter(path, format, externalLinkMessages)
+}
+
+func externalLinkMessages(p *wikipedia.Page) []proto.Message {
+	links := ParseExternalLinks(latestText(p))
+	res := make([]proto.Message, len(links))
+	for i, link := range links {
+		res[i] = &wikipedia.ExternalLink{
+			PageId:    p.Id,
+			PageTitle: p.Title,
+			Url:       link.URL,
+			Label:     link.Label,
+			Section:   link.Section,
+			Template:  link.Template,
+		}
+	}
+	return res
+}
+
+// NewCitationWriter returns a writer that extracts the citations of the
+// latest revision of each page, and writes them as Citation messages to a
+// file at the provided path.
+//
+// If the provided path already exists, an error is returned.
+func NewCitationWriter(path string, format Format) (wikipedia.PageWriter, error) {
+	return newExtractWriter(path, format, citationMessages)
+}
+
+func citationMessages(p *wikipedia.Page) []proto.Message {
+	citations := ParseCitations(latestText(p))
+	res := make([]proto.Message, len(citations))
+	for i, c := range citations {
+		res[i] = &wikipedia.Citation{
+			PageId:        p.Id,
+			PageTitle:     p.Title,
+			RefName:       c.RefName,
+			Reused:        c.Reused,
+			Template:      c.Template,
+			Title:         c.Title,
+			Authors:       c.Authors,
+			Date:          c.Date,
+			Url:           c.URL,
+			Doi:           c.DOI,
+			Isbn:          c.ISBN,
+			Publisher:     c.Publisher,
+			Work:          c.Work,
+			Text:          c.Text,
+			Offset:        int32(c.Offset),
+			SentenceStart: int32(c.SentenceStart),
+			SentenceEnd:   int32(c.SentenceEnd),
+		}
+	}
+	return res
+}
+
+// NewTableWriter returns a writer that extracts the tables of the latest
+// revision of each page, and writes them as Table messages to a file at the
+// provided path.
+//
+// If the provided path already exists, an error is returned.
+func NewTableWriter(path string, format Format) (wikipedia.PageWriter, error) {
+	return newExtractWriter(path, format, tableMessages)
+}
+
+func tableMessages(p *wikipedia.Page) []proto.Message {
+	tables := ParseTables(latestText(p))
+	res := make([]proto.Message, len(tables))
+	for i, t := range tables {
+		msg := &wikipedia.Table{
+			PageId:    p.Id,
+			PageTitle: p.Title,
+			Index:     int32(i),
+			Caption:   t.Caption,
+			Attrs:     t.Attrs,
+			Rows:      make([]*wikipedia.TableRow, len(t.Rows)),
+		}
+		for j, row := range t.Rows {
+			msg.Rows[j] = &wikipedia.TableRow{
+				Attrs: row.Attrs,
+				Cells: make([]*wikipedia.TableCell, len(row.Cells)),
+			}
+			for k, cell := range row.Cells {
+				msg.Rows[j].Cells[k] = &wikipedia.TableCell{
+					Text:    cell.Text,
+					Raw:     cell.Raw,
+					Header:  cell.Header,
+					RowSpan: int32(cell.RowSpan),
+					ColSpan: int32(cell.ColSpan),
+					Attrs:   cell.Attrs,
+				}
+			}
+		}
+		res[i] = msg
+	}
+	return res
+}
+
+type tableCSVWriter struct {
+	dir string
+}
+
+// NewTableCSVWriter returns a writer that extracts the tables of the latest
+// revision of each page, and writes each table to a separate CSV file in
+// the provided directory. Files are named by the page ID and the index of
+// the table within the page, e.g. "592_0.csv".
+func NewTableCSVWriter(dir string) (wikipedia.PageWriter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &tableCSVWriter{dir: dir}, nil
+}
+
+func (w *tableCSVWriter) Write(p *wikipedia.Page) error {
+	for i, t := range ParseTables(latestText(p)) {
+		name := filepath.Join(w.dir, fmt.Sprintf("%v_%v.csv", p.Id, i))
+		f, err := os.OpenFile(name, os.O_EXCL|os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		if err := t.WriteCSV(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *tableCSVWriter) Close() error {
+	return nil
+}
+
+// latestText returns the text of the latest revision of a page.
+func latestText(p *wikipedia.Page) string {
+	if len(p.Revisions) == 0 {
+		return ""
+	}
+	return p.Revisions[len(p.Revisions)-1].Text
+}